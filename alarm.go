@@ -0,0 +1,32 @@
+package godobot
+
+import "github.com/zdypro888/godobot/internal"
+
+// Alarm 报警定义，包含名称、类别、说明与恢复方法
+type Alarm = internal.Alarm
+
+// AlarmCategory 报警类别
+type AlarmCategory = internal.AlarmCategory
+
+const (
+	AlarmCategoryUnknown   = internal.AlarmCategoryUnknown
+	AlarmCategoryCommon    = internal.AlarmCategoryCommon
+	AlarmCategoryPlan      = internal.AlarmCategoryPlan
+	AlarmCategoryMove      = internal.AlarmCategoryMove
+	AlarmCategoryOverSpeed = internal.AlarmCategoryOverSpeed
+	AlarmCategoryLimit     = internal.AlarmCategoryLimit
+	AlarmCategoryLostStep  = internal.AlarmCategoryLostStep
+	AlarmCategoryDriver    = internal.AlarmCategoryDriver
+	AlarmCategoryMotor     = internal.AlarmCategoryMotor
+)
+
+// AlarmSet 报警位图，可解码为报警定义
+type AlarmSet = internal.AlarmSet
+
+// AlarmRecord 报警历史记录
+type AlarmRecord = internal.AlarmRecord
+
+// LookupAlarm 查询报警码的定义
+func LookupAlarm(code uint8) *Alarm {
+	return internal.LookupAlarm(code)
+}
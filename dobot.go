@@ -2,19 +2,27 @@ package godobot
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"time"
+	"sync/atomic"
 
 	"github.com/zdypro888/godobot/internal"
+	"github.com/zdypro888/godobot/kinematics"
+	"github.com/zdypro888/godobot/protocol"
 )
 
-var ErrLeftSpace = internal.ErrLeftSpace
-
 // Dobot 机械臂控制结构
 type Dobot struct {
-	conn *internal.Connector
+	conn      *internal.Connector
+	policy    *ReconnectPolicy
+	recorder  *internal.Recorder
+	watchdog  *WatchdogPolicy
+	workspace atomic.Pointer[workspace]
+	unchecked bool // 关闭参数检查
+
+	downloading atomic.Bool // 正在下载离线程序，队列指令存入控制器而不执行
 }
 
 // NewDobot 创建新的Dobot实例
@@ -22,69 +30,100 @@ func NewDobot() *Dobot {
 	return &Dobot{}
 }
 
-// ConnectDobot 连接到Dobot设备
-func (dobot *Dobot) Connect(portName string, baudrate uint32) error {
+// Connect 按地址连接到Dobot设备，地址形如 serial:///dev/ttyUSB0?baud=115200、udp://192.168.1.5:8899
+func (dobot *Dobot) Connect(address string) error {
 	dobot.conn = &internal.Connector{}
-	err := dobot.conn.Open(portName, baudrate)
+	dobot.conn.SetReconnectPolicy(dobot.policy)
+	dobot.conn.SetRecorder(dobot.recorder)
+	err := dobot.conn.Open(address)
 	if err != nil {
 		return err
 	}
+	dobot.conn.SetWatchdog(dobot.watchdog)
 	return nil
 }
 
+// ConnectTransport 使用已打开的传输层连接到Dobot设备
+func (dobot *Dobot) ConnectTransport(transport Transport) error {
+	if transport == nil {
+		return errors.New("invalid params: transport is nil")
+	}
+	dobot.conn = &internal.Connector{}
+	dobot.conn.SetReconnectPolicy(dobot.policy)
+	dobot.conn.SetRecorder(dobot.recorder)
+	dobot.conn.Attach(transport)
+	dobot.conn.SetWatchdog(dobot.watchdog)
+	return nil
+}
+
+// SetReconnectPolicy 设置断线自动重连策略，nil 关闭重连。
+// 重连后按写入顺序恢复运动参数、末端执行器与 IO 复用设置，并同步队列索引；
+// 通过 ConnectTransport 连接时需在策略中提供 Open
+func (dobot *Dobot) SetReconnectPolicy(policy *ReconnectPolicy) {
+	dobot.policy = policy
+	if dobot.conn != nil {
+		dobot.conn.SetReconnectPolicy(policy)
+	}
+}
+
 func (dobot *Dobot) Close() error {
 	return dobot.conn.Close()
 }
 
-type QueuedCommander func() (uint64, error)
+// QueuedCommand 已进入设备队列的指令句柄，nil 表示非队列指令
+type QueuedCommand = internal.QueuedCommand
 
-func (dobot *Dobot) QueuedSend(command QueuedCommander) (uint64, error) {
-	for {
-		cmdIndex, err := command()
-		if err != nil {
-			if err == ErrLeftSpace {
-				time.Sleep(time.Millisecond * 10)
-				continue
-			}
-			return 0, err
-		}
-		return cmdIndex, nil
+type QueuedCommander func(ctx context.Context) (*QueuedCommand, error)
+
+// track 跟踪队列指令的执行，非队列指令返回 nil
+func (dobot *Dobot) track(resp *protocol.Message, isQueued bool) *QueuedCommand {
+	if !isQueued {
+		return nil
+	}
+	if dobot.downloading.Load() {
+		return internal.Stored(resp.Uint64())
 	}
+	return dobot.conn.Track(resp.Uint64())
 }
 
-func (dobot *Dobot) QueuedComplete(command QueuedCommander) error {
-	var err error
-	var cmdIndex uint64
+// OnComplete 指令执行完成或无法完成时在独立协程中调用 callback
+func (dobot *Dobot) OnComplete(cmd *QueuedCommand, callback func(err error)) {
+	dobot.conn.OnComplete(cmd, callback)
+}
+
+// QueuedSend 发送队列指令，队列已满时等待设备腾出空间后重试
+func (dobot *Dobot) QueuedSend(ctx context.Context, command QueuedCommander) (*QueuedCommand, error) {
 	for {
-		if cmdIndex, err = command(); err != nil {
-			if err == ErrLeftSpace {
-				time.Sleep(time.Millisecond * 10)
+		cmd, err := command(ctx)
+		if err != nil {
+			if errors.Is(err, ErrLeftSpace) {
+				if err = dobot.conn.WaitAdvance(ctx); err != nil {
+					return nil, err
+				}
 				continue
 			}
-			return err
+			return nil, err
 		}
-		break
+		return cmd, nil
 	}
-	for {
-		nowIndex, err := dobot.GetQueuedCmdCurrentIndex()
-		if err != nil {
-			return err
-		}
-		if cmdIndex <= nowIndex {
-			break
-		}
-		time.Sleep(time.Millisecond * 10)
+}
+
+// QueuedComplete 发送队列指令并等待执行完成
+func (dobot *Dobot) QueuedComplete(ctx context.Context, command QueuedCommander) error {
+	cmd, err := dobot.QueuedSend(ctx, command)
+	if err != nil {
+		return err
 	}
-	return nil
+	return cmd.Wait(ctx)
 }
 
 // SetDeviceSN 设置设备序列号
-func (dobot *Dobot) SetDeviceSN(sn string) error {
+func (dobot *Dobot) SetDeviceSN(ctx context.Context, sn string) error {
 	if sn == "" {
 		return errors.New("invalid params: empty sn")
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolDeviceSN,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolDeviceSN,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -92,18 +131,18 @@ func (dobot *Dobot) SetDeviceSN(sn string) error {
 	writer.WriteString(sn)
 	writer.WriteByte(0)
 	message.Params = writer.Bytes()
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetDeviceSN 获取设备序列号
-func (dobot *Dobot) GetDeviceSN() (string, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolDeviceSN,
+func (dobot *Dobot) GetDeviceSN(ctx context.Context) (string, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolDeviceSN,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return "", err
 	}
@@ -111,29 +150,29 @@ func (dobot *Dobot) GetDeviceSN() (string, error) {
 }
 
 // SetDeviceName 设置设备名称
-func (dobot *Dobot) SetDeviceName(name string) error {
+func (dobot *Dobot) SetDeviceName(ctx context.Context, name string) error {
 	if name == "" {
 		return errors.New("invalid params: empty name")
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolDeviceName,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolDeviceName,
 		RW:       true,
 		IsQueued: false,
 	}
 	message.Params = []byte(name)
 	message.Params = append(message.Params, 0) // 添加一个字节 0x00 作为校验字节
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetDeviceName 获取设备名称
-func (dobot *Dobot) GetDeviceName() (string, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolDeviceName,
+func (dobot *Dobot) GetDeviceName(ctx context.Context) (string, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolDeviceName,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return "", err
 	}
@@ -141,23 +180,23 @@ func (dobot *Dobot) GetDeviceName() (string, error) {
 }
 
 // GetDeviceVersion 获取设备版本信息
-func (dobot *Dobot) GetDeviceVersion() (majorVersion, minorVersion, revision, hwVersion uint8, err error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolDeviceVersion,
+func (dobot *Dobot) GetDeviceVersion(ctx context.Context) (majorVersion, minorVersion, revision, hwVersion uint8, err error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolDeviceVersion,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, 0, 0, 0, err
 	}
-	return resp.Params[0], resp.Params[1], resp.Params[2], resp.Params[3], nil
+	return resp.Byte(0), resp.Byte(1), resp.Byte(2), resp.Byte(3), nil
 }
 
 // SetDeviceWithL 设置设备L轴
-func (dobot *Dobot) SetDeviceWithL(isWithL bool, version uint8) (uint64, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolDeviceWithL,
+func (dobot *Dobot) SetDeviceWithL(ctx context.Context, isWithL bool, version uint8) (*QueuedCommand, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolDeviceWithL,
 		RW:       true,
 		IsQueued: true,
 		Params:   make([]byte, 2),
@@ -166,21 +205,24 @@ func (dobot *Dobot) SetDeviceWithL(isWithL bool, version uint8) (uint64, error)
 		message.Params[0] = 1
 	}
 	message.Params[1] = version
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return resp.Uint64(), nil
+	dobot.amendModel(ctx, true, func(model *kinematics.Model) {
+		model.Rail = isWithL
+	})
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetDeviceWithL 获取设备L轴状态
-func (dobot *Dobot) GetDeviceWithL() (bool, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolDeviceWithL,
+func (dobot *Dobot) GetDeviceWithL(ctx context.Context) (bool, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolDeviceWithL,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return false, err
 	}
@@ -188,13 +230,13 @@ func (dobot *Dobot) GetDeviceWithL() (bool, error) {
 }
 
 // GetDeviceTime 获取设备运行时间
-func (dobot *Dobot) GetDeviceTime() (uint32, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolDeviceTime,
+func (dobot *Dobot) GetDeviceTime(ctx context.Context) (uint32, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolDeviceTime,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, err
 	}
@@ -202,13 +244,13 @@ func (dobot *Dobot) GetDeviceTime() (uint32, error) {
 }
 
 // GetDeviceInfo 获取设备信息
-func (dobot *Dobot) GetDeviceInfo() (*DeviceCountInfo, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolDeviceInfo,
+func (dobot *Dobot) GetDeviceInfo(ctx context.Context) (*DeviceCountInfo, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolDeviceInfo,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
@@ -220,13 +262,13 @@ func (dobot *Dobot) GetDeviceInfo() (*DeviceCountInfo, error) {
 }
 
 // GetPose 获取当前位姿信息
-func (dobot *Dobot) GetPose() (*Pose, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolGetPose,
+func (dobot *Dobot) GetPose(ctx context.Context) (*Pose, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolGetPose,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
@@ -238,9 +280,9 @@ func (dobot *Dobot) GetPose() (*Pose, error) {
 }
 
 // ResetPose 重置位姿到指定状态
-func (dobot *Dobot) ResetPose(manual bool, rearArmAngle, frontArmAngle float32) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolResetPose,
+func (dobot *Dobot) ResetPose(ctx context.Context, manual bool, rearArmAngle, frontArmAngle float32) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolResetPose,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -253,17 +295,20 @@ func (dobot *Dobot) ResetPose(manual bool, rearArmAngle, frontArmAngle float32)
 	binary.Write(writer, binary.LittleEndian, rearArmAngle)
 	binary.Write(writer, binary.LittleEndian, frontArmAngle)
 	message.Params = writer.Bytes()
-	_, err := dobot.conn.SendMessage(message)
-	return err
+	if _, err := dobot.conn.SendMessage(ctx, message); err != nil {
+		return err
+	}
+	dobot.workspace.Load().forget()
+	return nil
 }
 
 // GetKinematics 获取运动学参数
-func (dobot *Dobot) GetKinematics() (*Kinematics, error) {
-	message := &internal.Message{
-		Id: internal.ProtocolGetKinematics,
+func (dobot *Dobot) GetKinematics(ctx context.Context) (*Kinematics, error) {
+	message := &protocol.Message{
+		Id: protocol.ProtocolGetKinematics,
 		RW: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
@@ -275,12 +320,12 @@ func (dobot *Dobot) GetKinematics() (*Kinematics, error) {
 }
 
 // GetPoseL 获取L轴位置
-func (dobot *Dobot) GetPoseL() (float32, error) {
-	message := &internal.Message{
-		Id: internal.ProtocolGetPoseL,
+func (dobot *Dobot) GetPoseL(ctx context.Context) (float32, error) {
+	message := &protocol.Message{
+		Id: protocol.ProtocolGetPoseL,
 		RW: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, err
 	}
@@ -288,58 +333,68 @@ func (dobot *Dobot) GetPoseL() (float32, error) {
 }
 
 // GetAlarmsState 获取报警状态
-func (dobot *Dobot) GetAlarmsState() ([]uint8, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolAlarmsState,
+func (dobot *Dobot) GetAlarmsState(ctx context.Context) (AlarmSet, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolAlarmsState,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
-	return resp.Data(), nil
+	return internal.NewAlarmSet(resp.Data()), nil
+}
+
+// Alarms 最近一次后台轮询到的报警状态，不与设备交互
+func (dobot *Dobot) Alarms() AlarmSet {
+	return dobot.conn.Alarms()
+}
+
+// AlarmHistory 报警历史，包含每个报警的触发与清除时间
+func (dobot *Dobot) AlarmHistory() []AlarmRecord {
+	return dobot.conn.AlarmHistory()
 }
 
 // ClearAllAlarmsState 清除所有报警状态
-func (dobot *Dobot) ClearAllAlarmsState() error {
-	message := &internal.Message{
-		Id:       internal.ProtocolAlarmsState,
+func (dobot *Dobot) ClearAllAlarmsState(ctx context.Context) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolAlarmsState,
 		RW:       true,
 		IsQueued: false,
 	}
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // SetHOMEParams 设置HOME参数
-func (dobot *Dobot) SetHOMEParams(params *HOMEParams, isQueued bool) (uint64, error) {
+func (dobot *Dobot) SetHOMEParams(ctx context.Context, params *HOMEParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolHOMEParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolHOMEParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return resp.Uint64(), nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetHOMEParams 获取HOME参数
-func (dobot *Dobot) GetHOMEParams() (*HOMEParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolHOMEParams,
+func (dobot *Dobot) GetHOMEParams(ctx context.Context) (*HOMEParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolHOMEParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
@@ -351,32 +406,35 @@ func (dobot *Dobot) GetHOMEParams() (*HOMEParams, error) {
 }
 
 // SetHOMECmd 执行回零操作
-func (dobot *Dobot) SetHOMECmd(cmd *HOMECmd, isQueued bool) (uint64, error) {
+func (dobot *Dobot) SetHOMECmd(ctx context.Context, cmd *HOMECmd, isQueued bool) (*QueuedCommand, error) {
 	if cmd == nil {
-		return 0, errors.New("invalid params: cmd is nil")
+		return nil, errors.New("invalid params: cmd is nil")
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolHOMECmd,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolHOMECmd,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, cmd)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.sendMotion(ctx, message, dobot.routeHome)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return resp.Uint64(), nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetAutoLevelingCmd 执行自动调平
-func (dobot *Dobot) SetAutoLevelingCmd(cmd *AutoLevelingCmd, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetAutoLevelingCmd(ctx context.Context, cmd *AutoLevelingCmd, isQueued bool) (*QueuedCommand, error) {
 	if cmd == nil {
-		return 0, errors.New("invalid params: cmd is nil")
+		return nil, errors.New("invalid params: cmd is nil")
+	}
+	if err := dobot.validate(cmd); err != nil {
+		return nil, err
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolAutoLeveling,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolAutoLeveling,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -384,21 +442,21 @@ func (dobot *Dobot) SetAutoLevelingCmd(cmd *AutoLevelingCmd, isQueued bool) (que
 	binary.Write(writer, binary.LittleEndian, cmd)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return resp.Uint64(), nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetAutoLevelingResult 获取自动调平结果
-func (dobot *Dobot) GetAutoLevelingResult() (float32, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolAutoLeveling,
+func (dobot *Dobot) GetAutoLevelingResult(ctx context.Context) (float32, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolAutoLeveling,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, err
 	}
@@ -406,35 +464,38 @@ func (dobot *Dobot) GetAutoLevelingResult() (float32, error) {
 }
 
 // SetHHTTrigMode 设置手持示教触发模式
-func (dobot *Dobot) SetHHTTrigMode(mode HHTTrigMode) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolHHTTrigMode,
+func (dobot *Dobot) SetHHTTrigMode(ctx context.Context, mode HHTTrigMode) error {
+	if err := dobot.validate(mode); err != nil {
+		return err
+	}
+	message := &protocol.Message{
+		Id:       protocol.ProtocolHHTTrigMode,
 		RW:       true,
 		IsQueued: false,
 		Params:   []byte{uint8(mode)},
 	}
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetHHTTrigMode 获取手持示教触发模式
-func (dobot *Dobot) GetHHTTrigMode() (HHTTrigMode, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolHHTTrigMode,
+func (dobot *Dobot) GetHHTTrigMode(ctx context.Context) (HHTTrigMode, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolHHTTrigMode,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, err
 	}
-	return HHTTrigMode(resp.Params[0]), nil
+	return HHTTrigMode(resp.Byte(0)), nil
 }
 
 // SetHHTTrigOutputEnabled 设置手持示教触发输出使能
-func (dobot *Dobot) SetHHTTrigOutputEnabled(enabled bool) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolHHTTrigOutputEnabled,
+func (dobot *Dobot) SetHHTTrigOutputEnabled(ctx context.Context, enabled bool) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolHHTTrigOutputEnabled,
 		RW:       true,
 		IsQueued: false,
 		Params:   make([]byte, 1),
@@ -442,18 +503,18 @@ func (dobot *Dobot) SetHHTTrigOutputEnabled(enabled bool) error {
 	if enabled {
 		message.Params[0] = 1
 	}
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetHHTTrigOutputEnabled 获取手持示教触发输出使能状态
-func (dobot *Dobot) GetHHTTrigOutputEnabled() (bool, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolHHTTrigOutputEnabled,
+func (dobot *Dobot) GetHHTTrigOutputEnabled(ctx context.Context) (bool, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolHHTTrigOutputEnabled,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return false, err
 	}
@@ -461,13 +522,13 @@ func (dobot *Dobot) GetHHTTrigOutputEnabled() (bool, error) {
 }
 
 // GetHHTTrigOutput 获取手持示教触发输出状态
-func (dobot *Dobot) GetHHTTrigOutput() (bool, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolHHTTrigOutput,
+func (dobot *Dobot) GetHHTTrigOutput(ctx context.Context) (bool, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolHHTTrigOutput,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return false, err
 	}
@@ -475,37 +536,37 @@ func (dobot *Dobot) GetHHTTrigOutput() (bool, error) {
 }
 
 // SetEndEffectorParams 设置末端执行器参数
-func (dobot *Dobot) SetEndEffectorParams(params *EndEffectorParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetEndEffectorParams(ctx context.Context, params *EndEffectorParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolEndEffectorParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolEndEffectorParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
-	}
-	if isQueued {
-		return resp.Uint64(), nil
+		return nil, err
 	}
-	return 0, nil
+	dobot.amendModel(ctx, isQueued, func(model *kinematics.Model) {
+		model.Bias = [3]float64{float64(params.XBias), float64(params.YBias), float64(params.ZBias)}
+	})
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetEndEffectorParams 获取末端执行器参数
-func (dobot *Dobot) GetEndEffectorParams() (*EndEffectorParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolEndEffectorParams,
+func (dobot *Dobot) GetEndEffectorParams(ctx context.Context) (*EndEffectorParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolEndEffectorParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
@@ -517,9 +578,9 @@ func (dobot *Dobot) GetEndEffectorParams() (*EndEffectorParams, error) {
 }
 
 // SetEndEffectorLaser 设置末端激光状态
-func (dobot *Dobot) SetEndEffectorLaser(enableCtrl bool, on bool, isQueued bool) (queuedCmdIndex uint64, err error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolEndEffectorLaser,
+func (dobot *Dobot) SetEndEffectorLaser(ctx context.Context, enableCtrl bool, on bool, isQueued bool) (*QueuedCommand, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolEndEffectorLaser,
 		RW:       true,
 		IsQueued: isQueued,
 		Params:   make([]byte, 2),
@@ -530,35 +591,32 @@ func (dobot *Dobot) SetEndEffectorLaser(enableCtrl bool, on bool, isQueued bool)
 	if on {
 		message.Params[1] = 1
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetEndEffectorLaser 获取末端激光状态
-func (dobot *Dobot) GetEndEffectorLaser() (isCtrlEnabled bool, isOn bool, err error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolEndEffectorLaser,
+func (dobot *Dobot) GetEndEffectorLaser(ctx context.Context) (isCtrlEnabled bool, isOn bool, err error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolEndEffectorLaser,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return false, false, err
 	}
-	return resp.Params[0] != 0, resp.Params[1] != 0, nil
+	return resp.Byte(0) != 0, resp.Byte(1) != 0, nil
 }
 
 // SetEndEffectorSuctionCup 设置末端吸盘状态
-func (dobot *Dobot) SetEndEffectorSuctionCup(enableCtrl bool, suck bool, isQueued bool) (queuedCmdIndex uint64, err error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolEndEffectorSuctionCup,
+func (dobot *Dobot) SetEndEffectorSuctionCup(ctx context.Context, enableCtrl bool, suck bool, isQueued bool) (*QueuedCommand, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolEndEffectorSuctionCup,
 		RW:       true,
 		IsQueued: isQueued,
 		Params:   make([]byte, 2),
@@ -569,35 +627,32 @@ func (dobot *Dobot) SetEndEffectorSuctionCup(enableCtrl bool, suck bool, isQueue
 	if suck {
 		message.Params[1] = 1
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetEndEffectorSuctionCup 获取末端执行器吸盘状态
-func (dobot *Dobot) GetEndEffectorSuctionCup() (isCtrlEnabled bool, isSucked bool, err error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolEndEffectorSuctionCup,
+func (dobot *Dobot) GetEndEffectorSuctionCup(ctx context.Context) (isCtrlEnabled bool, isSucked bool, err error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolEndEffectorSuctionCup,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return false, false, err
 	}
-	return resp.Params[0] != 0, resp.Params[1] != 0, nil
+	return resp.Byte(0) != 0, resp.Byte(1) != 0, nil
 }
 
 // SetEndEffectorGripper 设置末端夹爪状态
-func (dobot *Dobot) SetEndEffectorGripper(enableCtrl bool, grip bool, isQueued bool) (queuedCmdIndex uint64, err error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolEndEffectorGripper,
+func (dobot *Dobot) SetEndEffectorGripper(ctx context.Context, enableCtrl bool, grip bool, isQueued bool) (*QueuedCommand, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolEndEffectorGripper,
 		RW:       true,
 		IsQueued: isQueued,
 		Params:   make([]byte, 2),
@@ -608,114 +663,117 @@ func (dobot *Dobot) SetEndEffectorGripper(enableCtrl bool, grip bool, isQueued b
 	if grip {
 		message.Params[1] = 1
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetEndEffectorGripper 获取末端夹爪状态
-func (dobot *Dobot) GetEndEffectorGripper() (isCtrlEnabled bool, isGripped bool, err error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolEndEffectorGripper,
+func (dobot *Dobot) GetEndEffectorGripper(ctx context.Context) (isCtrlEnabled bool, isGripped bool, err error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolEndEffectorGripper,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return false, false, err
 	}
-	return resp.Params[0] != 0, resp.Params[1] != 0, nil
+	return resp.Byte(0) != 0, resp.Byte(1) != 0, nil
 }
 
 // SetArmOrientation 设置机械臂方向
-func (dobot *Dobot) SetArmOrientation(armOrientation ArmOrientation, isQueued bool) (queuedCmdIndex uint64, err error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolArmOrientation,
+func (dobot *Dobot) SetArmOrientation(ctx context.Context, armOrientation ArmOrientation, isQueued bool) (*QueuedCommand, error) {
+	if err := dobot.validate(armOrientation); err != nil {
+		return nil, err
+	}
+	message := &protocol.Message{
+		Id:       protocol.ProtocolArmOrientation,
 		RW:       true,
 		IsQueued: isQueued,
 		Params:   []byte{uint8(armOrientation)},
 	}
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	dobot.amendModel(ctx, isQueued, func(model *kinematics.Model) {
+		model.Orientation = kinematics.Orientation(armOrientation)
+	})
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetArmOrientation 获取机械臂方向
-func (dobot *Dobot) GetArmOrientation() (ArmOrientation, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolArmOrientation,
+func (dobot *Dobot) GetArmOrientation(ctx context.Context) (ArmOrientation, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolArmOrientation,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, err
 	}
-	return ArmOrientation(resp.Params[0]), nil
+	return ArmOrientation(resp.Byte(0)), nil
 }
 
 // SetJOGJointParams 设置关节点动参数
-func (dobot *Dobot) SetJOGJointParams(params *JOGJointParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetJOGJointParams(ctx context.Context, params *JOGJointParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolJOGJointParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolJOGJointParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
-	}
-	if isQueued {
-		return resp.Uint64(), nil
+		return nil, err
 	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetJOGJointParams 获取关节点动参数
-func (dobot *Dobot) GetJOGJointParams() (*JOGJointParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolJOGJointParams,
+func (dobot *Dobot) GetJOGJointParams(ctx context.Context) (*JOGJointParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolJOGJointParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 	params := &JOGJointParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read JOG joint params: %v", err)
+		return nil, fmt.Errorf("failed to read JOG joint params: %w", err)
 	}
 	return params, nil
 }
 
 // SetJOGCoordinateParams 设置坐标点动参数
-func (dobot *Dobot) SetJOGCoordinateParams(params *JOGCoordinateParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetJOGCoordinateParams(ctx context.Context, params *JOGCoordinateParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolJOGCoordinateParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolJOGCoordinateParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -724,44 +782,44 @@ func (dobot *Dobot) SetJOGCoordinateParams(params *JOGCoordinateParams, isQueued
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetJOGCoordinateParams 获取坐标点动参数
-func (dobot *Dobot) GetJOGCoordinateParams() (*JOGCoordinateParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolJOGCoordinateParams,
+func (dobot *Dobot) GetJOGCoordinateParams(ctx context.Context) (*JOGCoordinateParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolJOGCoordinateParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &JOGCoordinateParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read JOG coordinate params: %v", err)
+		return nil, fmt.Errorf("failed to read JOG coordinate params: %w", err)
 	}
 	return params, nil
 }
 
 // SetJOGLParams 设置JOGL参数
-func (dobot *Dobot) SetJOGLParams(params *JOGLParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetJOGLParams(ctx context.Context, params *JOGLParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolJOGLParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolJOGLParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -770,87 +828,87 @@ func (dobot *Dobot) SetJOGLParams(params *JOGLParams, isQueued bool) (queuedCmdI
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, err
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetJOGLParams 获取JOGL参数
-func (dobot *Dobot) GetJOGLParams() (*JOGLParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolJOGLParams,
+func (dobot *Dobot) GetJOGLParams(ctx context.Context) (*JOGLParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolJOGLParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &JOGLParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read JOG L params: %v", err)
+		return nil, fmt.Errorf("failed to read JOG L params: %w", err)
 	}
 	return params, nil
 }
 
 // SetJOGCommonParams 设置JOG通用参数
-func (dobot *Dobot) SetJOGCommonParams(params *JOGCommonParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetJOGCommonParams(ctx context.Context, params *JOGCommonParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolJOGCommonParams,
+	if err := dobot.validate(params); err != nil {
+		return nil, err
+	}
+	message := &protocol.Message{
+		Id:       protocol.ProtocolJOGCommonParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetJOGCommonParams 获取JOG通用参数
-func (dobot *Dobot) GetJOGCommonParams() (*JOGCommonParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolJOGCommonParams,
+func (dobot *Dobot) GetJOGCommonParams(ctx context.Context) (*JOGCommonParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolJOGCommonParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &JOGCommonParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read JOG common params: %v", err)
+		return nil, fmt.Errorf("failed to read JOG common params: %w", err)
 	}
 	return params, nil
 }
 
 // SetJOGCmd 设置JOG运动指令
-func (dobot *Dobot) SetJOGCmd(cmd *JOGCmd, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetJOGCmd(ctx context.Context, cmd *JOGCmd, isQueued bool) (*QueuedCommand, error) {
 	if cmd == nil {
-		return 0, errors.New("invalid params: cmd is nil")
+		return nil, errors.New("invalid params: cmd is nil")
+	}
+	if err := dobot.validate(cmd); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolJOGCmd,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolJOGCmd,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -858,67 +916,81 @@ func (dobot *Dobot) SetJOGCmd(cmd *JOGCmd, isQueued bool) (queuedCmdIndex uint64
 	binary.Write(writer, binary.LittleEndian, cmd)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	// 新的点动指令取代之前的点动，先取消之前的守护
+	space := dobot.workspace.Load()
+	space.stopJOG()
+	guarded := space != nil && cmd.Cmd != JogIdle
+	var distance, angle float64
+	if guarded {
+		var err error
+		if distance, angle, err = dobot.jogReach(ctx, cmd); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := dobot.sendMotion(ctx, message, routeJOG(cmd, distance, angle))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-
-	if isQueued {
-		return resp.Uint64(), nil
+	if guarded {
+		dobot.guardJOG(space, *cmd, distance, angle)
 	}
-	return 0, nil
+
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetPTPJointParams 设置PTP关节参数
-func (dobot *Dobot) SetPTPJointParams(params *PTPJointParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetPTPJointParams(ctx context.Context, params *PTPJointParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPJointParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPJointParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
-	}
-	if isQueued {
-		return resp.Uint64(), nil
+		return nil, err
 	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetPTPJointParams 获取PTP关节参数
-func (dobot *Dobot) GetPTPJointParams() (*PTPJointParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPJointParams,
+func (dobot *Dobot) GetPTPJointParams(ctx context.Context) (*PTPJointParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPJointParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &PTPJointParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read PTP joint params: %v", err)
+		return nil, fmt.Errorf("failed to read PTP joint params: %w", err)
 	}
 	return params, nil
 }
 
 // SetPTPCoordinateParams 设置PTP坐标运动参数
-func (dobot *Dobot) SetPTPCoordinateParams(params *PTPCoordinateParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetPTPCoordinateParams(ctx context.Context, params *PTPCoordinateParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPCoordinateParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPCoordinateParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -927,44 +999,44 @@ func (dobot *Dobot) SetPTPCoordinateParams(params *PTPCoordinateParams, isQueued
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetPTPCoordinateParams 获取PTP坐标运动参数
-func (dobot *Dobot) GetPTPCoordinateParams() (*PTPCoordinateParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPCoordinateParams,
+func (dobot *Dobot) GetPTPCoordinateParams(ctx context.Context) (*PTPCoordinateParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPCoordinateParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &PTPCoordinateParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read PTP coordinate params: %v", err)
+		return nil, fmt.Errorf("failed to read PTP coordinate params: %w", err)
 	}
 	return params, nil
 }
 
 // SetPTPLParams 设置PTPL运动参数
-func (dobot *Dobot) SetPTPLParams(params *PTPLParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetPTPLParams(ctx context.Context, params *PTPLParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPLParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPLParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -973,44 +1045,44 @@ func (dobot *Dobot) SetPTPLParams(params *PTPLParams, isQueued bool) (queuedCmdI
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetPTPLParams 获取PTPL运动参数
-func (dobot *Dobot) GetPTPLParams() (*PTPLParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPLParams,
+func (dobot *Dobot) GetPTPLParams(ctx context.Context) (*PTPLParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPLParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &PTPLParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read PTP L params: %v", err)
+		return nil, fmt.Errorf("failed to read PTP L params: %w", err)
 	}
 	return params, nil
 }
 
 // SetPTPJumpParams 设置PTP跳跃参数
-func (dobot *Dobot) SetPTPJumpParams(params *PTPJumpParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetPTPJumpParams(ctx context.Context, params *PTPJumpParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid para dms: params is nil")
+		return nil, errors.New("invalid para dms: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPJumpParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPJumpParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1019,44 +1091,44 @@ func (dobot *Dobot) SetPTPJumpParams(params *PTPJumpParams, isQueued bool) (queu
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetPTPJumpParams 获取PTP跳跃参数
-func (dobot *Dobot) GetPTPJumpParams() (*PTPJumpParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPJumpParams,
+func (dobot *Dobot) GetPTPJumpParams(ctx context.Context) (*PTPJumpParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPJumpParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &PTPJumpParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read PTP jump params: %v", err)
+		return nil, fmt.Errorf("failed to read PTP jump params: %w", err)
 	}
 	return params, nil
 }
 
 // SetPTPJump2Params 设置PTP跳跃2参数
-func (dobot *Dobot) SetPTPJump2Params(params *PTPJump2Params, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetPTPJump2Params(ctx context.Context, params *PTPJump2Params, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPJump2Params,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPJump2Params,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1065,44 +1137,44 @@ func (dobot *Dobot) SetPTPJump2Params(params *PTPJump2Params, isQueued bool) (qu
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetPTPJump2Params 获取PTP跳跃2参数
-func (dobot *Dobot) GetPTPJump2Params() (*PTPJump2Params, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPJump2Params,
+func (dobot *Dobot) GetPTPJump2Params(ctx context.Context) (*PTPJump2Params, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPJump2Params,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &PTPJump2Params{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read PTP jump2 params: %v", err)
+		return nil, fmt.Errorf("failed to read PTP jump2 params: %w", err)
 	}
 	return params, nil
 }
 
 // SetPTPCommonParams 设置PTP通用参数
-func (dobot *Dobot) SetPTPCommonParams(params *PTPCommonParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetPTPCommonParams(ctx context.Context, params *PTPCommonParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPCommonParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPCommonParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1111,66 +1183,66 @@ func (dobot *Dobot) SetPTPCommonParams(params *PTPCommonParams, isQueued bool) (
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
-func (dobot *Dobot) GetPTPCommonParams() (*PTPCommonParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPCommonParams,
+func (dobot *Dobot) GetPTPCommonParams(ctx context.Context) (*PTPCommonParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPCommonParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &PTPCommonParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read PTP common params: %v", err)
+		return nil, fmt.Errorf("failed to read PTP common params: %w", err)
 	}
 	return params, nil
 }
 
 // SetPTPCmd 设置PTP命令
-func (dobot *Dobot) SetPTPCmd(cmd *PTPCmd, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetPTPCmd(ctx context.Context, cmd *PTPCmd, isQueued bool) (*QueuedCommand, error) {
 	if cmd == nil {
-		return 0, errors.New("invalid params: cmd is nil")
+		return nil, errors.New("invalid params: cmd is nil")
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPCmd,
+	if err := dobot.validate(cmd); err != nil {
+		return nil, err
+	}
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPCmd,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, cmd)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.sendMotion(ctx, message, dobot.routePTP(cmd.PTPMode, cmd.X, cmd.Y, cmd.Z, cmd.R, nil))
 	if err != nil {
-		return 0, err
-	}
-	if isQueued {
-		return resp.Uint64(), nil
+		return nil, err
 	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetPTPWithLCmd 设置带L轴的PTP运动指令
-func (dobot *Dobot) SetPTPWithLCmd(cmd *PTPWithLCmd, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetPTPWithLCmd(ctx context.Context, cmd *PTPWithLCmd, isQueued bool) (*QueuedCommand, error) {
 	if cmd == nil {
-		return 0, errors.New("invalid params: cmd is nil")
+		return nil, errors.New("invalid params: cmd is nil")
+	}
+	if err := dobot.validate(cmd); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPWithLCmd,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPWithLCmd,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1178,48 +1250,48 @@ func (dobot *Dobot) SetPTPWithLCmd(cmd *PTPWithLCmd, isQueued bool) (queuedCmdIn
 	binary.Write(writer, binary.LittleEndian, cmd)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.sendMotion(ctx, message, dobot.routePTP(cmd.PTPMode, cmd.X, cmd.Y, cmd.Z, cmd.R, &cmd.L))
 	if err != nil {
-		return 0, err
-	}
-	if isQueued {
-		return resp.Uint64(), nil
+		return nil, err
 	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetCPParams 设置CP参数
-func (dobot *Dobot) SetCPParams(params *CPParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetCPParams(ctx context.Context, params *CPParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolCPParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolCPParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetCPCmd 设置连续运动命令
-func (dobot *Dobot) SetCPCmd(cmd *CPCmd, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetCPCmd(ctx context.Context, cmd *CPCmd, isQueued bool) (*QueuedCommand, error) {
 	if cmd == nil {
-		return 0, errors.New("invalid params: cmd is nil")
+		return nil, errors.New("invalid params: cmd is nil")
+	}
+	if err := dobot.validate(cmd); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolCPCmd,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolCPCmd,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1227,21 +1299,25 @@ func (dobot *Dobot) SetCPCmd(cmd *CPCmd, isQueued bool) (queuedCmdIndex uint64,
 	binary.Write(writer, binary.LittleEndian, cmd)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.sendMotion(ctx, message, routeCP(cmd.CPMode == CPRelativeMode, cmd.X, cmd.Y, cmd.Z))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetCPLECmd 设置连续运动扩展命令
-func (dobot *Dobot) SetCPLECmd(cpMode uint8, x, y, z, power float32, isQueued bool) (queuedCmdIndex uint64, err error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolCPLECmd,
+func (dobot *Dobot) SetCPLECmd(ctx context.Context, cpMode uint8, x, y, z, power float32, isQueued bool) (*QueuedCommand, error) {
+	if err := dobot.validate(CPMode(cpMode)); err != nil {
+		return nil, err
+	}
+	// power 为激光功率百分比
+	if err := dobot.validateArg("CPLECmd.Power", power, "0,100"); err != nil {
+		return nil, err
+	}
+	message := &protocol.Message{
+		Id:       protocol.ProtocolCPLECmd,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1252,21 +1328,18 @@ func (dobot *Dobot) SetCPLECmd(cpMode uint8, x, y, z, power float32, isQueued bo
 	binary.Write(writer, binary.LittleEndian, z)
 	binary.Write(writer, binary.LittleEndian, power)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.sendMotion(ctx, message, routeCP(CPMode(cpMode) == CPRelativeMode, x, y, z))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetCPRHoldEnable 设置CPR保持使能
-func (dobot *Dobot) SetCPRHoldEnable(isEnable bool) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolCPRHoldEnable,
+func (dobot *Dobot) SetCPRHoldEnable(ctx context.Context, isEnable bool) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolCPRHoldEnable,
 		RW:       true,
 		IsQueued: false,
 		Params:   make([]byte, 1),
@@ -1274,118 +1347,121 @@ func (dobot *Dobot) SetCPRHoldEnable(isEnable bool) error {
 	if isEnable {
 		message.Params[0] = 1
 	}
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetCPRHoldEnable 获取CP运动保持使能状态
-func (dobot *Dobot) GetCPRHoldEnable() (bool, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolCPRHoldEnable,
+func (dobot *Dobot) GetCPRHoldEnable(ctx context.Context) (bool, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolCPRHoldEnable,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return false, err
 	}
-	return resp.Params[0] != 0, nil
+	return resp.Byte(0) != 0, nil
 }
 
 // SetCPCommonParams 设置CP通用参数
-func (dobot *Dobot) SetCPCommonParams(params *CPCommonParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetCPCommonParams(ctx context.Context, params *CPCommonParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolCPCommonParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolCPCommonParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetCPCommonParams 获取CP通用参数
-func (dobot *Dobot) GetCPCommonParams() (*CPCommonParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolCPCommonParams,
+func (dobot *Dobot) GetCPCommonParams(ctx context.Context) (*CPCommonParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolCPCommonParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &CPCommonParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read CP common params: %v", err)
+		return nil, fmt.Errorf("failed to read CP common params: %w", err)
 	}
 	return params, nil
 }
 
 // SetARCParams 设置ARC参数
-func (dobot *Dobot) SetARCParams(params *ARCParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetARCParams(ctx context.Context, params *ARCParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolARCParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolARCParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
-	}
-	if isQueued {
-		return resp.Uint64(), nil
+		return nil, err
 	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetARCParams 获取ARC参数
-func (dobot *Dobot) GetARCParams() (*ARCParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolARCParams,
+func (dobot *Dobot) GetARCParams(ctx context.Context) (*ARCParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolARCParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &ARCParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read ARC params: %v", err)
+		return nil, fmt.Errorf("failed to read ARC params: %w", err)
 	}
 	return params, nil
 }
 
 // SetARCCmd 设置ARC命令
-func (dobot *Dobot) SetARCCmd(cmd *ARCCmd, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetARCCmd(ctx context.Context, cmd *ARCCmd, isQueued bool) (*QueuedCommand, error) {
 	if cmd == nil {
-		return 0, errors.New("invalid params: cmd is nil")
+		return nil, errors.New("invalid params: cmd is nil")
+	}
+	if err := dobot.validate(cmd); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolARCCmd,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolARCCmd,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1393,25 +1469,25 @@ func (dobot *Dobot) SetARCCmd(cmd *ARCCmd, isQueued bool) (queuedCmdIndex uint64
 	binary.Write(writer, binary.LittleEndian, cmd)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.sendMotion(ctx, message, routeArc(arcPoint(cmd.CirPoint), arcPoint(cmd.ToPoint), false))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetCircleCmd 设置圆周运动命令
-func (dobot *Dobot) SetCircleCmd(cmd *CircleCmd, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetCircleCmd(ctx context.Context, cmd *CircleCmd, isQueued bool) (*QueuedCommand, error) {
 	if cmd == nil {
-		return 0, errors.New("invalid params: cmd is nil")
+		return nil, errors.New("invalid params: cmd is nil")
+	}
+	if err := dobot.validate(cmd); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolCircleCmd,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolCircleCmd,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1419,94 +1495,91 @@ func (dobot *Dobot) SetCircleCmd(cmd *CircleCmd, isQueued bool) (queuedCmdIndex
 	binary.Write(writer, binary.LittleEndian, cmd)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.sendMotion(ctx, message, routeArc(arcPoint(cmd.CirPoint), arcPoint(cmd.ToPoint), true))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetARCCommonParams 设置ARC通用参数
-func (dobot *Dobot) SetARCCommonParams(params *ARCCommonParams, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetARCCommonParams(ctx context.Context, params *ARCCommonParams, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolARCCommonParams,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolARCCommonParams,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetARCCommonParams 获取ARC通用参数
-func (dobot *Dobot) GetARCCommonParams() (*ARCCommonParams, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolARCCommonParams,
+func (dobot *Dobot) GetARCCommonParams(ctx context.Context) (*ARCCommonParams, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolARCCommonParams,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &ARCCommonParams{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read ARC common params: %v", err)
+		return nil, fmt.Errorf("failed to read ARC common params: %w", err)
 	}
 	return params, nil
 }
 
 // SetWAITCmd 设置等待指令
-func (dobot *Dobot) SetWAITCmd(cmd *WAITCmd, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetWAITCmd(ctx context.Context, cmd *WAITCmd, isQueued bool) (*QueuedCommand, error) {
 	if cmd == nil {
-		return 0, errors.New("invalid params: cmd is nil")
+		return nil, errors.New("invalid params: cmd is nil")
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolWAITCmd,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWAITCmd,
 		RW:       true,
 		IsQueued: true,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, cmd)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetTRIGCmd 设置触发指令
-func (dobot *Dobot) SetTRIGCmd(cmd *TRIGCmd, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetTRIGCmd(ctx context.Context, cmd *TRIGCmd, isQueued bool) (*QueuedCommand, error) {
 	if cmd == nil {
-		return 0, errors.New("invalid params: cmd is nil")
+		return nil, errors.New("invalid params: cmd is nil")
+	}
+	if err := dobot.validate(cmd); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolTRIGCmd,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolTRIGCmd,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1514,25 +1587,25 @@ func (dobot *Dobot) SetTRIGCmd(cmd *TRIGCmd, isQueued bool) (queuedCmdIndex uint
 	binary.Write(writer, binary.LittleEndian, cmd)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetIOMultiplexing 设置IO复用功能
-func (dobot *Dobot) SetIOMultiplexing(params *IOMultiplexing, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetIOMultiplexing(ctx context.Context, params *IOMultiplexing, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolIOMultiplexing,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolIOMultiplexing,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1540,25 +1613,25 @@ func (dobot *Dobot) SetIOMultiplexing(params *IOMultiplexing, isQueued bool) (qu
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetIODO 设置IO数字输出
-func (dobot *Dobot) SetIODO(params *IODO, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetIODO(ctx context.Context, params *IODO, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolIODO,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolIODO,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1566,25 +1639,25 @@ func (dobot *Dobot) SetIODO(params *IODO, isQueued bool) (queuedCmdIndex uint64,
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetIOPWM 设置IO PWM输出
-func (dobot *Dobot) SetIOPWM(params *IOPWM, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetIOPWM(ctx context.Context, params *IOPWM, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolIOPWM,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolIOPWM,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1592,68 +1665,68 @@ func (dobot *Dobot) SetIOPWM(params *IOPWM, isQueued bool) (queuedCmdIndex uint6
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if isQueued {
-		return resp.Uint64(), nil
-	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetIODI 获取IO数字输入
-func (dobot *Dobot) GetIODI(ioDI *IODI) (*IODI, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolIODI,
+func (dobot *Dobot) GetIODI(ctx context.Context, ioDI *IODI) (*IODI, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolIODI,
 		RW:       false,
 		IsQueued: false,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, ioDI)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	params := &IODI{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read IO DI: %v", err)
+		return nil, fmt.Errorf("failed to read IO DI: %w", err)
 	}
 	return params, nil
 }
 
 // GetIOADC 获取IO模拟输入
-func (dobot *Dobot) GetIOADC(ioDI *IODI) (*IOADC, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolIOADC,
+func (dobot *Dobot) GetIOADC(ctx context.Context, ioDI *IODI) (*IOADC, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolIOADC,
 		RW:       false,
 		IsQueued: false,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, ioDI)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 	params := &IOADC{}
 	if err := resp.Read(params); err != nil {
-		return nil, fmt.Errorf("failed to read IO ADC: %v", err)
+		return nil, fmt.Errorf("failed to read IO ADC: %w", err)
 	}
 	return params, nil
 }
 
 // SetEMotor 设置扩展电机参数
-func (dobot *Dobot) SetEMotor(params *EMotor, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetEMotor(ctx context.Context, params *EMotor, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
 
-	message := &internal.Message{
-		Id:       internal.ProtocolEMotor,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolEMotor,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1662,23 +1735,23 @@ func (dobot *Dobot) SetEMotor(params *EMotor, isQueued bool) (queuedCmdIndex uin
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
-	}
-	if isQueued {
-		return resp.Uint64(), nil
+		return nil, err
 	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetEMotorS 设置扩展步进电机参数
-func (dobot *Dobot) SetEMotorS(params *EMotorS, isQueued bool) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetEMotorS(ctx context.Context, params *EMotorS, isQueued bool) (*QueuedCommand, error) {
 	if params == nil {
-		return 0, errors.New("invalid params: params is nil")
+		return nil, errors.New("invalid params: params is nil")
+	}
+	if err := dobot.validate(params); err != nil {
+		return nil, err
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolEMotorS,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolEMotorS,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -1686,20 +1759,20 @@ func (dobot *Dobot) SetEMotorS(params *EMotorS, isQueued bool) (queuedCmdIndex u
 	binary.Write(writer, binary.LittleEndian, params)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
-	}
-	if isQueued {
-		return resp.Uint64(), nil
+		return nil, err
 	}
-	return 0, nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetColorSensor 设置颜色传感器
-func (dobot *Dobot) SetColorSensor(enable bool, colorPort ColorPort, version uint8) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolColorSensor,
+func (dobot *Dobot) SetColorSensor(ctx context.Context, enable bool, colorPort ColorPort, version uint8) error {
+	if err := dobot.validate(colorPort); err != nil {
+		return err
+	}
+	message := &protocol.Message{
+		Id:       protocol.ProtocolColorSensor,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -1709,28 +1782,28 @@ func (dobot *Dobot) SetColorSensor(enable bool, colorPort ColorPort, version uin
 	binary.Write(writer, binary.LittleEndian, version)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetColorSensor 获取颜色传感器数据
-func (dobot *Dobot) GetColorSensor() (r, g, b uint8, err error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolColorSensor,
+func (dobot *Dobot) GetColorSensor(ctx context.Context) (r, g, b uint8, err error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolColorSensor,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, 0, 0, err
 	}
-	return resp.Params[0], resp.Params[1], resp.Params[2], nil
+	return resp.Byte(0), resp.Byte(1), resp.Byte(2), nil
 }
 
 // SetAngleSensorStaticError 设置角度传感器静态误差
-func (dobot *Dobot) SetAngleSensorStaticError(rearArmAngleError, frontArmAngleError float32) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolAngleSensorStaticError,
+func (dobot *Dobot) SetAngleSensorStaticError(ctx context.Context, rearArmAngleError, frontArmAngleError float32) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolAngleSensorStaticError,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -1739,18 +1812,18 @@ func (dobot *Dobot) SetAngleSensorStaticError(rearArmAngleError, frontArmAngleEr
 	binary.Write(writer, binary.LittleEndian, frontArmAngleError)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetAngleSensorStaticError 获取角度传感器静态误差
-func (dobot *Dobot) GetAngleSensorStaticError() (float32, float32, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolAngleSensorStaticError,
+func (dobot *Dobot) GetAngleSensorStaticError(ctx context.Context) (float32, float32, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolAngleSensorStaticError,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -1762,9 +1835,9 @@ func (dobot *Dobot) GetAngleSensorStaticError() (float32, float32, error) {
 }
 
 // SetAngleSensorCoef 设置角度传感器系数
-func (dobot *Dobot) SetAngleSensorCoef(rearArmAngleCoef, frontArmAngleCoef float32) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolAngleSensorCoef,
+func (dobot *Dobot) SetAngleSensorCoef(ctx context.Context, rearArmAngleCoef, frontArmAngleCoef float32) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolAngleSensorCoef,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -1773,18 +1846,18 @@ func (dobot *Dobot) SetAngleSensorCoef(rearArmAngleCoef, frontArmAngleCoef float
 	binary.Write(writer, binary.LittleEndian, frontArmAngleCoef)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetAngleSensorCoef 获取角度传感器系数
-func (dobot *Dobot) GetAngleSensorCoef() (float32, float32, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolAngleSensorCoef,
+func (dobot *Dobot) GetAngleSensorCoef(ctx context.Context) (float32, float32, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolAngleSensorCoef,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -1796,9 +1869,9 @@ func (dobot *Dobot) GetAngleSensorCoef() (float32, float32, error) {
 }
 
 // SetBaseDecoderStaticError 设置底座解码器静态误差
-func (dobot *Dobot) SetBaseDecoderStaticError(baseDecoderError float32) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolBaseDecoderStaticError,
+func (dobot *Dobot) SetBaseDecoderStaticError(ctx context.Context, baseDecoderError float32) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolBaseDecoderStaticError,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -1806,18 +1879,18 @@ func (dobot *Dobot) SetBaseDecoderStaticError(baseDecoderError float32) error {
 	binary.Write(writer, binary.LittleEndian, baseDecoderError)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetBaseDecoderStaticError 获取底座解码器静态误差
-func (dobot *Dobot) GetBaseDecoderStaticError() (float32, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolBaseDecoderStaticError,
+func (dobot *Dobot) GetBaseDecoderStaticError(ctx context.Context) (float32, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolBaseDecoderStaticError,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, err
 	}
@@ -1825,9 +1898,9 @@ func (dobot *Dobot) GetBaseDecoderStaticError() (float32, error) {
 }
 
 // SetLRHandCalibrateValue 设置左右手校准值
-func (dobot *Dobot) SetLRHandCalibrateValue(lrHandCalibrateValue float32) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolLRHandCalibrateValue,
+func (dobot *Dobot) SetLRHandCalibrateValue(ctx context.Context, lrHandCalibrateValue float32) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolLRHandCalibrateValue,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -1835,18 +1908,18 @@ func (dobot *Dobot) SetLRHandCalibrateValue(lrHandCalibrateValue float32) error
 	binary.Write(writer, binary.LittleEndian, lrHandCalibrateValue)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetLRHandCalibrateValue 获取左右手校准值
-func (dobot *Dobot) GetLRHandCalibrateValue() (float32, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolLRHandCalibrateValue,
+func (dobot *Dobot) GetLRHandCalibrateValue(ctx context.Context) (float32, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolLRHandCalibrateValue,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, err
 	}
@@ -1854,42 +1927,45 @@ func (dobot *Dobot) GetLRHandCalibrateValue() (float32, error) {
 }
 
 // SetQueuedCmdStartExec 开始执行指令队列
-func (dobot *Dobot) SetQueuedCmdStartExec() error {
-	message := &internal.Message{
-		Id:       internal.ProtocolQueuedCmdStartExec,
+func (dobot *Dobot) SetQueuedCmdStartExec(ctx context.Context) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolQueuedCmdStartExec,
 		RW:       true,
 		IsQueued: false,
 	}
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // SetQueuedCmdStopExec 停止执行队列命令
-func (dobot *Dobot) SetQueuedCmdStopExec() error {
-	message := &internal.Message{
-		Id:       internal.ProtocolQueuedCmdStopExec,
+func (dobot *Dobot) SetQueuedCmdStopExec(ctx context.Context) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolQueuedCmdStopExec,
 		RW:       true,
 		IsQueued: false,
 	}
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // SetQueuedCmdForceStopExec 强制停止执行队列命令
-func (dobot *Dobot) SetQueuedCmdForceStopExec() error {
-	message := &internal.Message{
-		Id:       internal.ProtocolQueuedCmdForceStopExec,
+func (dobot *Dobot) SetQueuedCmdForceStopExec(ctx context.Context) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolQueuedCmdForceStopExec,
 		RW:       true,
 		IsQueued: false,
 	}
-	_, err := dobot.conn.SendMessage(message)
-	return err
+	if _, err := dobot.conn.SendMessage(ctx, message); err != nil {
+		return err
+	}
+	dobot.workspace.Load().forget()
+	return nil
 }
 
 // SetQueuedCmdStartDownload 开始下载队列命令
-func (dobot *Dobot) SetQueuedCmdStartDownload(totalLoop uint32, linePerLoop uint32) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolQueuedCmdStartDownload,
+func (dobot *Dobot) SetQueuedCmdStartDownload(ctx context.Context, totalLoop uint32, linePerLoop uint32) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolQueuedCmdStartDownload,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -1898,40 +1974,43 @@ func (dobot *Dobot) SetQueuedCmdStartDownload(totalLoop uint32, linePerLoop uint
 	binary.Write(writer, binary.LittleEndian, linePerLoop)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // SetQueuedCmdStopDownload 停止下载队列命令
-func (dobot *Dobot) SetQueuedCmdStopDownload() error {
-	message := &internal.Message{
-		Id:       internal.ProtocolQueuedCmdStopDownload,
+func (dobot *Dobot) SetQueuedCmdStopDownload(ctx context.Context) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolQueuedCmdStopDownload,
 		RW:       true,
 		IsQueued: false,
 	}
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // SetQueuedCmdClear 清除队列命令
-func (dobot *Dobot) SetQueuedCmdClear() error {
-	message := &internal.Message{
-		Id:       internal.ProtocolQueuedCmdClear,
+func (dobot *Dobot) SetQueuedCmdClear(ctx context.Context) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolQueuedCmdClear,
 		RW:       true,
 		IsQueued: false,
 	}
-	_, err := dobot.conn.SendMessage(message)
-	return err
+	if _, err := dobot.conn.SendMessage(ctx, message); err != nil {
+		return err
+	}
+	dobot.workspace.Load().forget()
+	return nil
 }
 
 // GetQueuedCmdCurrentIndex 获取当前队列命令索引
-func (dobot *Dobot) GetQueuedCmdCurrentIndex() (uint64, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolQueuedCmdCurrentIndex,
+func (dobot *Dobot) GetQueuedCmdCurrentIndex(ctx context.Context) (uint64, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolQueuedCmdCurrentIndex,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, err
 	}
@@ -1939,26 +2018,46 @@ func (dobot *Dobot) GetQueuedCmdCurrentIndex() (uint64, error) {
 }
 
 // GetQueuedCmdMotionFinish 获取队列命令运动是否完成
-func (dobot *Dobot) GetQueuedCmdMotionFinish() (bool, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolQueuedCmdMotionFinish,
+func (dobot *Dobot) GetQueuedCmdMotionFinish(ctx context.Context) (bool, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolQueuedCmdMotionFinish,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return false, err
 	}
 	return resp.Bool(), nil
 }
 
+// GetQueuedCmdLeftSpace 获取设备队列剩余空间
+func (dobot *Dobot) GetQueuedCmdLeftSpace(ctx context.Context) (uint32, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolQueuedCmdLeftSpace,
+		RW:       false,
+		IsQueued: false,
+	}
+	resp, err := dobot.conn.SendMessage(ctx, message)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Uint32(), nil
+}
+
 // SetPTPPOCmd 设置PTP并行输出命令
-func (dobot *Dobot) SetPTPPOCmd(ptpCmd *PTPCmd, parallelCmd []ParallelOutputCmd) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetPTPPOCmd(ctx context.Context, ptpCmd *PTPCmd, parallelCmd []ParallelOutputCmd) (*QueuedCommand, error) {
 	if ptpCmd == nil {
-		return 0, errors.New("invalid params: ptpCmd is nil")
+		return nil, errors.New("invalid params: ptpCmd is nil")
+	}
+	if err := dobot.validate(ptpCmd); err != nil {
+		return nil, err
+	}
+	if err := dobot.validate(parallelCmd); err != nil {
+		return nil, err
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPPOCmd,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPPOCmd,
 		RW:       true,
 		IsQueued: true,
 	}
@@ -1968,21 +2067,27 @@ func (dobot *Dobot) SetPTPPOCmd(ptpCmd *PTPCmd, parallelCmd []ParallelOutputCmd)
 	binary.Write(writer, binary.LittleEndian, parallelCmd)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.sendMotion(ctx, message, dobot.routePTP(ptpCmd.PTPMode, ptpCmd.X, ptpCmd.Y, ptpCmd.Z, ptpCmd.R, nil))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return resp.Uint64(), nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetPTPPOWithLCmd 设置带并行输出和L轴的PTP运动指令
-func (dobot *Dobot) SetPTPPOWithLCmd(ptpWithLCmd *PTPWithLCmd, parallelCmd []ParallelOutputCmd) (queuedCmdIndex uint64, err error) {
+func (dobot *Dobot) SetPTPPOWithLCmd(ctx context.Context, ptpWithLCmd *PTPWithLCmd, parallelCmd []ParallelOutputCmd) (*QueuedCommand, error) {
 	if ptpWithLCmd == nil {
-		return 0, errors.New("invalid params: ptpWithLCmd is nil")
+		return nil, errors.New("invalid params: ptpWithLCmd is nil")
+	}
+	if err := dobot.validate(ptpWithLCmd); err != nil {
+		return nil, err
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPPOWithLCmd,
+	if err := dobot.validate(parallelCmd); err != nil {
+		return nil, err
+	}
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPPOWithLCmd,
 		RW:       true,
 		IsQueued: true,
 	}
@@ -1992,18 +2097,18 @@ func (dobot *Dobot) SetPTPPOWithLCmd(ptpWithLCmd *PTPWithLCmd, parallelCmd []Par
 	binary.Write(writer, binary.LittleEndian, parallelCmd)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.sendMotion(ctx, message, dobot.routePTP(ptpWithLCmd.PTPMode, ptpWithLCmd.X, ptpWithLCmd.Y, ptpWithLCmd.Z, ptpWithLCmd.R, &ptpWithLCmd.L))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return resp.Uint64(), nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetWIFIConfigMode 设置WIFI配置模式
-func (dobot *Dobot) SetWIFIConfigMode(enable bool) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFIConfigMode,
+func (dobot *Dobot) SetWIFIConfigMode(ctx context.Context, enable bool) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFIConfigMode,
 		RW:       true,
 		IsQueued: false,
 		Params:   make([]byte, 1),
@@ -2013,18 +2118,18 @@ func (dobot *Dobot) SetWIFIConfigMode(enable bool) error {
 		message.Params[0] = 1
 	}
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetWIFIConfigMode 获取WIFI配置模式状态
-func (dobot *Dobot) GetWIFIConfigMode() (bool, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFIConfigMode,
+func (dobot *Dobot) GetWIFIConfigMode(ctx context.Context) (bool, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFIConfigMode,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return false, err
 	}
@@ -2032,12 +2137,12 @@ func (dobot *Dobot) GetWIFIConfigMode() (bool, error) {
 }
 
 // SetWIFISSID 设置WIFI SSID
-func (dobot *Dobot) SetWIFISSID(ssid string) error {
+func (dobot *Dobot) SetWIFISSID(ctx context.Context, ssid string) error {
 	if ssid == "" {
 		return errors.New("invalid params: empty ssid")
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFISSID,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFISSID,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -2047,18 +2152,18 @@ func (dobot *Dobot) SetWIFISSID(ssid string) error {
 	writer.WriteByte(0) // 添加一个字节 0x00 作为校验字节
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetWIFISSID 获取WIFI SSID
-func (dobot *Dobot) GetWIFISSID() (string, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFISSID,
+func (dobot *Dobot) GetWIFISSID(ctx context.Context) (string, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFISSID,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return "", err
 	}
@@ -2066,12 +2171,12 @@ func (dobot *Dobot) GetWIFISSID() (string, error) {
 }
 
 // SetWIFIPassword 设置WIFI密码
-func (dobot *Dobot) SetWIFIPassword(password string) error {
+func (dobot *Dobot) SetWIFIPassword(ctx context.Context, password string) error {
 	if password == "" {
 		return errors.New("invalid params: empty password")
 	}
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFIPassword,
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFIPassword,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -2081,18 +2186,18 @@ func (dobot *Dobot) SetWIFIPassword(password string) error {
 	writer.WriteByte(0)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetWIFIPassword 获取WIFI密码
-func (dobot *Dobot) GetWIFIPassword() (string, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFIPassword,
+func (dobot *Dobot) GetWIFIPassword(ctx context.Context) (string, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFIPassword,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return "", err
 	}
@@ -2100,13 +2205,13 @@ func (dobot *Dobot) GetWIFIPassword() (string, error) {
 }
 
 // GetPTPTime 获取PTP运动时间
-func (dobot *Dobot) GetPTPTime() (float32, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolPTPTime,
+func (dobot *Dobot) GetPTPTime(ctx context.Context) (float32, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolPTPTime,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, err
 	}
@@ -2123,23 +2228,26 @@ const (
 )
 
 // GetFirmwareMode 获取固件模式
-func (dobot *Dobot) GetFirmwareMode() (FirmwareMode, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolFirmwareMode,
+func (dobot *Dobot) GetFirmwareMode(ctx context.Context) (FirmwareMode, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolFirmwareMode,
 		RW:       false,
 		IsQueued: false,
 	}
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, err
 	}
-	return FirmwareMode(resp.Params[0]), nil
+	return FirmwareMode(resp.Byte(0)), nil
 }
 
 // SetLostStepParams 设置丢步参数
-func (dobot *Dobot) SetLostStepParams(threshold float32, isQueued bool) (uint64, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolLostStepSet,
+func (dobot *Dobot) SetLostStepParams(ctx context.Context, threshold float32, isQueued bool) (*QueuedCommand, error) {
+	if err := dobot.validateArg("LostStepParams.Threshold", threshold, "0,"); err != nil {
+		return nil, err
+	}
+	message := &protocol.Message{
+		Id:       protocol.ProtocolLostStepSet,
 		RW:       true,
 		IsQueued: isQueued,
 	}
@@ -2148,50 +2256,50 @@ func (dobot *Dobot) SetLostStepParams(threshold float32, isQueued bool) (uint64,
 	binary.Write(writer, binary.LittleEndian, threshold)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return resp.Uint64(), nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // SetLostStepCmd 设置丢步命令
-func (dobot *Dobot) SetLostStepCmd(isQueued bool) (uint64, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolLostStepDetect,
+func (dobot *Dobot) SetLostStepCmd(ctx context.Context, isQueued bool) (*QueuedCommand, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolLostStepDetect,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return resp.Uint64(), nil
+	return dobot.track(resp, message.IsQueued), nil
 }
 
 // GetUART4PeripheralsType 获取UART4外设类型
-func (dobot *Dobot) GetUART4PeripheralsType() (uint8, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolCheckUART4PeripheralsModel,
+func (dobot *Dobot) GetUART4PeripheralsType(ctx context.Context) (uint8, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolCheckUART4PeripheralsModel,
 		RW:       false,
 		IsQueued: false,
 	}
 
-	response, err := dobot.conn.SendMessage(message)
+	response, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return 0, err
 	}
 
-	return response.Params[0], nil
+	return response.Byte(0), nil
 }
 
 // SetUART4PeripheralsEnable 设置UART4外设使能状态
-func (dobot *Dobot) SetUART4PeripheralsEnable(isEnable bool) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolUART4PeripheralsEnabled,
+func (dobot *Dobot) SetUART4PeripheralsEnable(ctx context.Context, isEnable bool) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolUART4PeripheralsEnabled,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -2199,20 +2307,20 @@ func (dobot *Dobot) SetUART4PeripheralsEnable(isEnable bool) error {
 	binary.Write(writer, binary.LittleEndian, isEnable)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetUART4PeripheralsEnable 获取UART4外设使能状态
-func (dobot *Dobot) GetUART4PeripheralsEnable() (bool, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolUART4PeripheralsEnabled,
+func (dobot *Dobot) GetUART4PeripheralsEnable(ctx context.Context) (bool, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolUART4PeripheralsEnabled,
 		RW:       false,
 		IsQueued: false,
 		Params:   []byte{},
 	}
 
-	response, err := dobot.conn.SendMessage(message)
+	response, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return false, err
 	}
@@ -2229,23 +2337,20 @@ type PluseCmd struct {
 }
 
 // SendPluse 发送脉冲控制命令
-func (dobot *Dobot) SendPluse(pluseCmd *PluseCmd, isQueued bool) (uint64, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolFunctionPulseMode,
+func (dobot *Dobot) SendPluse(ctx context.Context, pluseCmd *PluseCmd, isQueued bool) (*QueuedCommand, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolFunctionPulseMode,
 		RW:       true,
 		IsQueued: isQueued,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, pluseCmd)
 	message.Params = writer.Bytes()
-	response, err := dobot.conn.SendMessage(message)
+	response, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
-		return 0, err
-	}
-	if isQueued {
-		return response.Uint64(), nil
+		return nil, err
 	}
-	return 0, nil
+	return dobot.track(response, message.IsQueued), nil
 }
 
 // WIFIIPAddress WIFI IP地址结构
@@ -2270,9 +2375,9 @@ type WIFIDNS struct {
 }
 
 // SetWIFIIPAddress 设置WIFI IP地址
-func (dobot *Dobot) SetWIFIIPAddress(wifiIPAddress *WIFIIPAddress) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFIIPAddress,
+func (dobot *Dobot) SetWIFIIPAddress(ctx context.Context, wifiIPAddress *WIFIIPAddress) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFIIPAddress,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -2280,32 +2385,32 @@ func (dobot *Dobot) SetWIFIIPAddress(wifiIPAddress *WIFIIPAddress) error {
 	binary.Write(writer, binary.LittleEndian, wifiIPAddress)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetWIFIIPAddress 获取WIFI IP地址
-func (dobot *Dobot) GetWIFIIPAddress() (*WIFIIPAddress, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFIIPAddress,
+func (dobot *Dobot) GetWIFIIPAddress(ctx context.Context) (*WIFIIPAddress, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFIIPAddress,
 		RW:       false,
 		IsQueued: false,
 	}
-	response, err := dobot.conn.SendMessage(message)
+	response, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 	result := &WIFIIPAddress{}
 	if err := response.Read(result); err != nil {
-		return nil, fmt.Errorf("failed to read WIFI IP address: %v", err)
+		return nil, fmt.Errorf("failed to read WIFI IP address: %w", err)
 	}
 	return result, nil
 }
 
 // SetWIFINetmask 设置WIFI子网掩码
-func (dobot *Dobot) SetWIFINetmask(wifiNetmask *WIFINetmask) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFINetmask,
+func (dobot *Dobot) SetWIFINetmask(ctx context.Context, wifiNetmask *WIFINetmask) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFINetmask,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -2313,34 +2418,34 @@ func (dobot *Dobot) SetWIFINetmask(wifiNetmask *WIFINetmask) error {
 	binary.Write(writer, binary.LittleEndian, wifiNetmask)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetWIFINetmask 获取WIFI子网掩码
-func (dobot *Dobot) GetWIFINetmask() (*WIFINetmask, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFINetmask,
+func (dobot *Dobot) GetWIFINetmask(ctx context.Context) (*WIFINetmask, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFINetmask,
 		RW:       false,
 		IsQueued: false,
 	}
 
-	response, err := dobot.conn.SendMessage(message)
+	response, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	result := &WIFINetmask{}
 	if err := response.Read(result); err != nil {
-		return nil, fmt.Errorf("failed to read WIFI netmask: %v", err)
+		return nil, fmt.Errorf("failed to read WIFI netmask: %w", err)
 	}
 	return result, nil
 }
 
 // SetWIFIGateway 设置WIFI网关
-func (dobot *Dobot) SetWIFIGateway(wifiGateway *WIFIGateway) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFIGateway,
+func (dobot *Dobot) SetWIFIGateway(ctx context.Context, wifiGateway *WIFIGateway) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFIGateway,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -2348,34 +2453,34 @@ func (dobot *Dobot) SetWIFIGateway(wifiGateway *WIFIGateway) error {
 	binary.Write(writer, binary.LittleEndian, wifiGateway)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetWIFIGateway 获取WIFI网关
-func (dobot *Dobot) GetWIFIGateway() (*WIFIGateway, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFIGateway,
+func (dobot *Dobot) GetWIFIGateway(ctx context.Context) (*WIFIGateway, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFIGateway,
 		RW:       false,
 		IsQueued: false,
 	}
 
-	response, err := dobot.conn.SendMessage(message)
+	response, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	result := &WIFIGateway{}
 	if err := response.Read(result); err != nil {
-		return nil, fmt.Errorf("failed to read WIFI gateway: %v", err)
+		return nil, fmt.Errorf("failed to read WIFI gateway: %w", err)
 	}
 	return result, nil
 }
 
 // SetWIFIDNS 设置WIFI DNS
-func (dobot *Dobot) SetWIFIDNS(wifiDNS *WIFIDNS) error {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFIDNS,
+func (dobot *Dobot) SetWIFIDNS(ctx context.Context, wifiDNS *WIFIDNS) error {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFIDNS,
 		RW:       true,
 		IsQueued: false,
 	}
@@ -2383,39 +2488,39 @@ func (dobot *Dobot) SetWIFIDNS(wifiDNS *WIFIDNS) error {
 	binary.Write(writer, binary.LittleEndian, wifiDNS)
 	message.Params = writer.Bytes()
 
-	_, err := dobot.conn.SendMessage(message)
+	_, err := dobot.conn.SendMessage(ctx, message)
 	return err
 }
 
 // GetWIFIDNS 获取WIFI DNS
-func (dobot *Dobot) GetWIFIDNS() (*WIFIDNS, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFIDNS,
+func (dobot *Dobot) GetWIFIDNS(ctx context.Context) (*WIFIDNS, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFIDNS,
 		RW:       false,
 		IsQueued: false,
 	}
 
-	response, err := dobot.conn.SendMessage(message)
+	response, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 
 	result := &WIFIDNS{}
 	if err := response.Read(result); err != nil {
-		return nil, fmt.Errorf("failed to read WIFI DNS: %v", err)
+		return nil, fmt.Errorf("failed to read WIFI DNS: %w", err)
 	}
 	return result, nil
 }
 
 // GetWIFIConnectStatus 获取WIFI连接状态
-func (dobot *Dobot) GetWIFIConnectStatus() (bool, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolWIFIConnectStatus,
+func (dobot *Dobot) GetWIFIConnectStatus(ctx context.Context) (bool, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolWIFIConnectStatus,
 		RW:       false,
 		IsQueued: false,
 	}
 
-	response, err := dobot.conn.SendMessage(message)
+	response, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return false, err
 	}
@@ -2423,30 +2528,30 @@ func (dobot *Dobot) GetWIFIConnectStatus() (bool, error) {
 }
 
 // GetIOMultiplexing 获取IO复用状态
-func (dobot *Dobot) GetIOMultiplexing(ioMultiplexing *IOMultiplexing) (*IOMultiplexing, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolIOMultiplexing,
+func (dobot *Dobot) GetIOMultiplexing(ctx context.Context, ioMultiplexing *IOMultiplexing) (*IOMultiplexing, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolIOMultiplexing,
 		RW:       false,
 		IsQueued: false,
 	}
 	writer := &bytes.Buffer{}
 	binary.Write(writer, binary.LittleEndian, ioMultiplexing)
 	message.Params = writer.Bytes()
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 	var multiplexing IOMultiplexing
 	if err := resp.Read(&multiplexing); err != nil {
-		return nil, fmt.Errorf("failed to read IOMultiplexing: %v", err)
+		return nil, fmt.Errorf("failed to read IOMultiplexing: %w", err)
 	}
 	return &multiplexing, nil
 }
 
 // GetIODO 获取IO数字输出状态
-func (dobot *Dobot) GetIODO(ioDO *IODO) (*IODO, error) {
-	message := &internal.Message{
-		Id:       internal.ProtocolIODO,
+func (dobot *Dobot) GetIODO(ctx context.Context, ioDO *IODO) (*IODO, error) {
+	message := &protocol.Message{
+		Id:       protocol.ProtocolIODO,
 		RW:       false,
 		IsQueued: false,
 	}
@@ -2454,14 +2559,14 @@ func (dobot *Dobot) GetIODO(ioDO *IODO) (*IODO, error) {
 	binary.Write(writer, binary.LittleEndian, ioDO)
 	message.Params = writer.Bytes()
 
-	resp, err := dobot.conn.SendMessage(message)
+	resp, err := dobot.conn.SendMessage(ctx, message)
 	if err != nil {
 		return nil, err
 	}
 	reader := resp.Reader()
 	var iodo IODO
 	if err := binary.Read(reader, binary.LittleEndian, &iodo); err != nil {
-		return nil, fmt.Errorf("failed to read IODO: %v", err)
+		return nil, fmt.Errorf("failed to read IODO: %w", err)
 	}
 	return &iodo, nil
 }
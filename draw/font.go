@@ -0,0 +1,280 @@
+package draw
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrBadFont = errors.New("bad font")
+
+// Glyph 单线字形，坐标 y 轴向下，基线为 Font.Baseline
+type Glyph struct {
+	Left, Right float64 // 左右边界，二者之差为字宽
+	Strokes     []*Stroke
+}
+
+// Font 单线字体（Hershey 风格），坐标单位由字体自定
+type Font struct {
+	Glyphs     map[rune]*Glyph
+	CapHeight  float64 // 大写字母高度，字号按此缩放
+	Baseline   float64 // 基线的 y 坐标
+	LineHeight float64 // 默认行距
+	Fallback   *Font   // 缺字时继续查找的字体，如中文字体缺字时用西文字体写数字
+}
+
+// Glyph 在本字体及 Fallback 中查找字形，返回字形与所属字体；缺失时依次以 '?'、空格代替
+func (font *Font) Glyph(char rune) (*Glyph, *Font) {
+	for _, c := range []rune{char, '?', ' '} {
+		for f := font; f != nil; f = f.Fallback {
+			if glyph, ok := f.Glyphs[c]; ok {
+				return glyph, f
+			}
+		}
+	}
+	return &Glyph{}, font
+}
+
+// Missing 文本中本字体及 Fallback 均没有的字符（不含换行），按首次出现排列
+func (font *Font) Missing(text string) []rune {
+	var missing []rune
+	seen := map[rune]bool{'\n': true}
+	for _, char := range text {
+		if seen[char] {
+			continue
+		}
+		seen[char] = true
+		if _, f := font.Glyph(char); f.Glyphs[char] == nil {
+			missing = append(missing, char)
+		}
+	}
+	return missing
+}
+
+// LoadJHF 读取 Hershey 字体文件，见 ParseJHF
+func LoadJHF(path string) (*Font, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseJHF(file)
+}
+
+// ParseJHF 解析 Hershey 字体的 JHF 格式（如 futural.jhf、rowmans.jhf），
+// 第 i 个字形对应字符 ' '+i；字高与基线取自字母 H
+func ParseJHF(r io.Reader) (*Font, error) {
+	font := &Font{Glyphs: map[rune]*Glyph{}, CapHeight: 21, Baseline: 9}
+	scanner := bufio.NewScanner(r)
+	var record string
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if record == "" && strings.TrimSpace(text) == "" {
+			continue
+		}
+		// 较长的字形会折成多行，按顶点数拼接
+		record += text
+		if len(record) < 8 {
+			return nil, fmt.Errorf("%w: line %d: short record", ErrBadFont, line)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(record[5:8]))
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("%w: line %d: vertex count %q", ErrBadFont, line, record[5:8])
+		}
+		if len(record) < 8+2*count {
+			continue
+		}
+		data := record[8 : 8+2*count]
+		record = ""
+		glyph := &Glyph{Left: float64(data[0]) - 'R', Right: float64(data[1]) - 'R'}
+		var points []*Point
+		for i := 2; i+1 < len(data); i += 2 {
+			if data[i:i+2] == " R" {
+				if len(points) > 0 {
+					glyph.Strokes = append(glyph.Strokes, &Stroke{Points: points})
+				}
+				points = nil
+				continue
+			}
+			points = append(points, &Point{X: float32(data[i]) - 'R', Y: float32(data[i+1]) - 'R'})
+		}
+		if len(points) > 0 {
+			glyph.Strokes = append(glyph.Strokes, &Stroke{Points: points})
+		}
+		font.Glyphs[' '+rune(len(font.Glyphs))] = glyph
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if record != "" {
+		return nil, fmt.Errorf("%w: truncated glyph", ErrBadFont)
+	}
+	if len(font.Glyphs) == 0 {
+		return nil, fmt.Errorf("%w: no glyphs", ErrBadFont)
+	}
+	if h, ok := font.Glyphs['H']; ok {
+		top, bottom := math.Inf(1), math.Inf(-1)
+		for _, stroke := range h.Strokes {
+			for _, p := range stroke.Points {
+				top, bottom = min(top, float64(p.Y)), max(bottom, float64(p.Y))
+			}
+		}
+		if bottom > top {
+			font.CapHeight, font.Baseline = bottom-top, bottom
+		}
+	}
+	font.LineHeight = font.CapHeight * 1.6
+	return font, nil
+}
+
+// DefaultFont 内置的 ASCII 单线字体，大写字母高 10 个单位
+var DefaultFont = buildFont(defaultGlyphs)
+
+// glyphSource 字宽与笔画描述：笔画以 ; 分隔，笔画内的项以空格分隔，
+// 项为 x,y 点或 (cx,cy,rx,ry,a0,a1) 椭圆弧（角度制，逆时针为正），坐标 y 轴向上、基线为 0
+type glyphSource struct {
+	width   float64
+	strokes string
+}
+
+// buildFont 由字形描述构造字体，解析失败属于编码错误
+func buildFont(sources map[rune]glyphSource) *Font {
+	font := &Font{Glyphs: map[rune]*Glyph{}, CapHeight: 10, LineHeight: 16}
+	for char, source := range sources {
+		glyph := &Glyph{Left: -1, Right: source.width + 1}
+		for _, text := range strings.Split(source.strokes, ";") {
+			var points []*Point
+			for _, item := range strings.Fields(text) {
+				var values []float64
+				for _, field := range strings.Split(strings.Trim(item, "()"), ",") {
+					value, err := strconv.ParseFloat(field, 64)
+					if err != nil {
+						panic(fmt.Sprintf("draw: glyph %q: %v", char, err))
+					}
+					values = append(values, value)
+				}
+				switch len(values) {
+				case 2:
+					points = append(points, &Point{X: float32(values[0]), Y: float32(-values[1])})
+				case 6:
+					cx, cy, rx, ry, a0, a1 := values[0], values[1], values[2], values[3], values[4], values[5]
+					n := max(1, int(math.Ceil(math.Abs(a1-a0)/10)))
+					for i := 0; i <= n; i++ {
+						a := (a0 + (a1-a0)*float64(i)/float64(n)) * math.Pi / 180
+						points = append(points, &Point{X: float32(cx + rx*math.Cos(a)), Y: float32(-(cy + ry*math.Sin(a)))})
+					}
+				default:
+					panic(fmt.Sprintf("draw: glyph %q: bad item %q", char, item))
+				}
+			}
+			if len(points) > 0 {
+				glyph.Strokes = append(glyph.Strokes, &Stroke{Points: points})
+			}
+		}
+		font.Glyphs[char] = glyph
+	}
+	return font
+}
+
+var defaultGlyphs = map[rune]glyphSource{
+	' ':  {4, ""},
+	'!':  {0, "0,10 0,3; 0,0.2 0,0"},
+	'"':  {2, "0,10 0,7; 2,10 2,7"},
+	'#':  {7, "2,0 3,10; 5,0 6,10; 0,3.5 7,3.5; 0,6.5 7,6.5"},
+	'$':  {6, "(3,7,3,2,20,270) (3,3,3,2,90,-160); 3,11 3,-1"},
+	'%':  {8, "0,0 8,10; (1.5,8.5,1.5,1.5,0,360); (6.5,1.5,1.5,1.5,0,360)"},
+	'&':  {7, "7,0 1.4,7.4 (2.5,8.5,1.5,1.5,225,-45) 0.6,4 (3,2.2,3,2.2,145,340) 7,4"},
+	'\'': {0, "0,10 0,7"},
+	'(':  {3, "(4.5,4,4.5,7,120,240)"},
+	')':  {3, "(-1.5,4,4.5,7,60,-60)"},
+	'*':  {6, "3,10 3,4; 0.4,8.5 5.6,5.5; 5.6,8.5 0.4,5.5"},
+	'+':  {6, "3,1 3,7; 0,4 6,4"},
+	',':  {1, "1,0.3 1,0 0,-2"},
+	'-':  {6, "0,4 6,4"},
+	'.':  {0, "0,0.2 0,0"},
+	'/':  {6, "0,-1 6,11"},
+	'0':  {6, "(3,5,3,5,0,360)"},
+	'1':  {6, "1,8 3,10 3,0"},
+	'2':  {6, "0,7.5 (3,7,3,3,170,-40) 0,0 6,0"},
+	'3':  {6, "0,10 6,10 3,6 (3,3,3,3,90,-150)"},
+	'4':  {6, "4.5,0 4.5,10 0,3 6,3"},
+	'5':  {6, "5.5,10 0.5,10 0,5.5 (3,3.2,3,3.2,130,-150)"},
+	'6':  {6, "5,10 (3,3,3,3,150,510)"},
+	'7':  {6, "0,10 6,10 2,0"},
+	'8':  {6, "(3,7.5,2.5,2.5,0,360); (3,2.5,3,2.5,0,360)"},
+	'9':  {6, "1,0 (3,7,3,3,-30,330)"},
+	':':  {0, "0,7 0,6.8; 0,0.2 0,0"},
+	';':  {1, "1,7 1,6.8; 1,0.3 1,0 0,-2"},
+	'<':  {6, "6,8 0,4 6,0"},
+	'=':  {6, "0,5.5 6,5.5; 0,2.5 6,2.5"},
+	'>':  {6, "0,8 6,4 0,0"},
+	'?':  {6, "0,7.5 (3,7.5,3,2.5,180,-90) 3,2.5; 3,0.2 3,0"},
+	'@':  {10, "(5,5,2,2,0,360); 7,7 7,3.5 (8,3.5,1,1,180,360) (5,5,4,5,10,330)"},
+	'A':  {8, "0,0 4,10 8,0; 1.5,3.6 6.5,3.6"},
+	'B':  {7, "0,0 0,10 4.5,10 (4.5,7.5,2.5,2.5,90,-90) 0,5; 0,5 (4.5,2.5,2.5,2.5,90,-90) 0,0"},
+	'C':  {8, "(4,5,4,5,40,320)"},
+	'D':  {7, "0,0 0,10 2,10 (2,5,5,5,90,-90) 0,0"},
+	'E':  {6, "6,10 0,10 0,0 6,0; 0,5 4.5,5"},
+	'F':  {6, "6,10 0,10 0,0; 0,5 4.5,5"},
+	'G':  {8, "(4,5,4,5,40,360) 4.5,5"},
+	'H':  {7, "0,0 0,10; 7,0 7,10; 0,5 7,5"},
+	'I':  {0, "0,0 0,10"},
+	'J':  {5, "5,10 5,2.5 (2.5,2.5,2.5,2.5,0,-180)"},
+	'K':  {7, "0,0 0,10; 7,10 0,3; 2.2,5 7,0"},
+	'L':  {6, "0,10 0,0 6,0"},
+	'M':  {8, "0,0 0,10 4,0 8,10 8,0"},
+	'N':  {7, "0,0 0,10 7,0 7,10"},
+	'O':  {8, "(4,5,4,5,0,360)"},
+	'P':  {7, "0,0 0,10 4.5,10 (4.5,7.5,2.5,2.5,90,-90) 0,5"},
+	'Q':  {8, "(4,5,4,5,0,360); 5,2.5 8,-0.5"},
+	'R':  {7, "0,0 0,10 4.5,10 (4.5,7.5,2.5,2.5,90,-90) 0,5; 3.5,5 7,0"},
+	'S':  {7, "(3.5,7.5,3.5,2.5,20,270) (3.5,2.5,3.5,2.5,90,-160)"},
+	'T':  {8, "0,10 8,10; 4,10 4,0"},
+	'U':  {7, "0,10 0,3.5 (3.5,3.5,3.5,3.5,180,360) 7,10"},
+	'V':  {8, "0,10 4,0 8,10"},
+	'W':  {10, "0,10 2.5,0 5,8 7.5,0 10,10"},
+	'X':  {7, "0,10 7,0; 7,10 0,0"},
+	'Y':  {8, "0,10 4,5 8,10; 4,5 4,0"},
+	'Z':  {7, "0,10 7,10 0,0 7,0"},
+	'[':  {3, "3,11 0,11 0,-1 3,-1"},
+	'\\': {6, "0,11 6,-1"},
+	']':  {3, "0,11 3,11 3,-1 0,-1"},
+	'^':  {6, "0,7 3,10 6,7"},
+	'_':  {8, "0,-2 8,-2"},
+	'`':  {2, "0,10 2,8"},
+	'a':  {6, "(3,3.5,3,3.5,0,360); 6,7 6,0"},
+	'b':  {6, "0,10 0,0; (3,3.5,3,3.5,0,360)"},
+	'c':  {6, "(3,3.5,3,3.5,40,320)"},
+	'd':  {6, "6,10 6,0; (3,3.5,3,3.5,0,360)"},
+	'e':  {6, "0,3.5 6,3.5 (3,3.5,3,3.5,0,320)"},
+	'f':  {4, "4,10 (2.5,8.5,1.5,1.5,90,180) 1,0; 0,7 4,7"},
+	'g':  {6, "(3,3.5,3,3.5,0,360); 6,7 6,-1 (3,-1,3,2,0,-160)"},
+	'h':  {6, "0,10 0,0; 0,4 (3,4,3,3,180,0) 6,0"},
+	'i':  {0, "0,7 0,0; 0,9.6 0,9.4"},
+	'j':  {3, "3,7 3,-1 (1,-1,2,2,0,-150); 3,9.6 3,9.4"},
+	'k':  {6, "0,10 0,0; 6,7 0,2.5; 2,3.8 6,0"},
+	'l':  {0, "0,10 0,0"},
+	'm':  {10, "0,7 0,0; 0,4.5 (2.5,4.5,2.5,2.5,180,0) 5,0; 5,4.5 (7.5,4.5,2.5,2.5,180,0) 10,0"},
+	'n':  {6, "0,7 0,0; 0,4 (3,4,3,3,180,0) 6,0"},
+	'o':  {6, "(3,3.5,3,3.5,0,360)"},
+	'p':  {6, "0,7 0,-3; (3,3.5,3,3.5,0,360)"},
+	'q':  {6, "6,7 6,-3; (3,3.5,3,3.5,0,360)"},
+	'r':  {4, "0,7 0,0; 0,4 (3,4,3,3,180,70)"},
+	's':  {5, "(2.5,5.25,2.5,1.75,20,270) (2.5,1.75,2.5,1.75,90,-160)"},
+	't':  {4, "1,10 1,1 (2.5,1,1.5,1,180,270) 4,0; 0,7 4,7"},
+	'u':  {6, "0,7 0,3 (3,3,3,3,180,360); 6,7 6,0"},
+	'v':  {6, "0,7 3,0 6,7"},
+	'w':  {9, "0,7 2,0 4.5,6 7,0 9,7"},
+	'x':  {6, "0,7 6,0; 6,7 0,0"},
+	'y':  {6, "0,7 3,0; 6,7 2,-3"},
+	'z':  {6, "0,7 6,7 0,0 6,0"},
+	'{':  {4, "4,11 (4,9.5,1.5,1.5,90,180) 2.5,6.5 1,5 2.5,3.5 2.5,0.5 (4,0.5,1.5,1.5,180,270)"},
+	'|':  {0, "0,11 0,-1"},
+	'}':  {4, "0,11 (0,9.5,1.5,1.5,90,0) 1.5,6.5 3,5 1.5,3.5 1.5,0.5 (0,0.5,1.5,1.5,0,-90)"},
+	'~':  {7, "0,4 (1.75,4,1.75,1.2,180,0) (5.25,4,1.75,1.2,180,360)"},
+}
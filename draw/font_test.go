@@ -0,0 +1,53 @@
+package draw
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zdypro888/godobot/internal/golden"
+)
+
+// TestJHFGolden 解析 testdata 中的 JHF 字体，与 .golden 文件中的字形或错误比较；
+// 折行的记录按顶点数拼接，续行开头的抬笔 " R" 不能被当作空白去掉
+func TestJHFGolden(t *testing.T) {
+	golden.Run(t, "*.jhf", func(t *testing.T, file string) string {
+		font, err := LoadJHF(file)
+		if err != nil {
+			if !errors.Is(err, ErrBadFont) {
+				t.Fatalf("error %v, want ErrBadFont", err)
+			}
+			return fmt.Sprintf("error: %v\n", err)
+		}
+		return dumpFont(font)
+	})
+}
+
+// dumpFont 字体度量与按字符排列的字形，每笔一行
+func dumpFont(font *Font) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "cap %g baseline %g line %g\n", font.CapHeight, font.Baseline, font.LineHeight)
+	chars := make([]rune, 0, len(font.Glyphs))
+	for char := range font.Glyphs {
+		chars = append(chars, char)
+	}
+	slices.Sort(chars)
+	for _, char := range chars {
+		glyph := font.Glyphs[char]
+		fmt.Fprintf(&out, "%q %g %g\n", char, glyph.Left, glyph.Right)
+		out.WriteString(indent(dumpStrokes(&Signature{Strokes: glyph.Strokes})))
+	}
+	return out.String()
+}
+
+func indent(text string) string {
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line != "" {
+			out.WriteString("\t" + line)
+		}
+	}
+	return out.String()
+}
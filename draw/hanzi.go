@@ -0,0 +1,77 @@
+package draw
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Make Me a Hanzi 的坐标：字框边长 1024，y 轴向上，顶边 y 为 900
+const (
+	hanziEm  = 1024
+	hanziTop = 900
+)
+
+// LoadHanzi 读取 Make Me a Hanzi 的 graphics.txt，见 ParseHanzi
+func LoadHanzi(path string, chars string) (*Font, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseHanzi(file, chars)
+}
+
+// ParseHanzi 解析 Make Me a Hanzi graphics.txt 格式（每行一个 JSON，medians 为按笔顺排列的笔画中线），
+// 构造以字框高度为字号的字体，笔画顺序与方向即书写顺序。chars 非空时只保留其中的字符，
+// 以免载入全部九千余字。字体带有半角宽的空格，可通过 Fallback 补充西文与数字
+func ParseHanzi(r io.Reader, chars string) (*Font, error) {
+	font := &Font{
+		Glyphs:     map[rune]*Glyph{' ': {Right: hanziEm / 2}},
+		CapHeight:  hanziEm,
+		Baseline:   hanziEm,
+		LineHeight: hanziEm * 1.25,
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry struct {
+			Character string         `json:"character"`
+			Medians   [][][2]float64 `json:"medians"`
+		}
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrBadFont, line, err)
+		}
+		char, size := utf8.DecodeRuneInString(entry.Character)
+		if char == utf8.RuneError || size != len(entry.Character) {
+			return nil, fmt.Errorf("%w: line %d: character %q", ErrBadFont, line, entry.Character)
+		}
+		if chars != "" && !strings.ContainsRune(chars, char) {
+			continue
+		}
+		glyph := &Glyph{Right: hanziEm}
+		for _, median := range entry.Medians {
+			if len(median) == 0 {
+				continue
+			}
+			points := make([]*Point, 0, len(median))
+			for _, p := range median {
+				points = append(points, &Point{X: float32(p[0]), Y: float32(hanziTop - p[1])})
+			}
+			glyph.Strokes = append(glyph.Strokes, &Stroke{Points: points})
+		}
+		font.Glyphs[char] = glyph
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return font, nil
+}
@@ -0,0 +1,75 @@
+package draw
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// hanziFixture Make Me a Hanzi graphics.txt 的三行，strokes 轮廓不使用
+const hanziFixture = `{"character":"一","strokes":["M 518 382 Q 572 385 623 389 Z"],"medians":[[[121,393],[211,389],[801,392],[880,388]]]}
+{"character":"二","strokes":["M 273 596 Z","M 140 222 Z"],"medians":[[[248,598],[310,604],[622,636],[708,637]],[[122,214],[234,210],[781,225],[900,223]]]}
+{"character":"三","strokes":["M 1 1 Z"],"medians":[[[100,700],[900,700]]]}
+`
+
+// TestParseHanzi y 轴翻转为 hanziTop - y，只保留 chars 中的字符，笔画保持 medians 的顺序
+func TestParseHanzi(t *testing.T) {
+	font, err := ParseHanzi(strings.NewReader(hanziFixture), "一二")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := font.Glyphs['三']; ok {
+		t.Error("character outside chars loaded")
+	}
+	if font.CapHeight != 1024 || font.Baseline != 1024 || font.LineHeight != 1280 {
+		t.Errorf("metrics cap %g baseline %g line %g, want 1024 1024 1280", font.CapHeight, font.Baseline, font.LineHeight)
+	}
+	two := font.Glyphs['二']
+	if two == nil || two.Left != 0 || two.Right != 1024 || len(two.Strokes) != 2 {
+		t.Fatalf("glyph 二 = %+v, want two strokes 1024 wide", two)
+	}
+	// 第一笔为上横（原坐标 y 较大），翻转后 y 较小
+	want := [][][2]float32{
+		{{248, 302}, {310, 296}, {622, 264}, {708, 263}},
+		{{122, 686}, {234, 690}, {781, 675}, {900, 677}},
+	}
+	for i, stroke := range two.Strokes {
+		for j, point := range stroke.Points {
+			if point.X != want[i][j][0] || point.Y != want[i][j][1] {
+				t.Errorf("stroke %d point %d = (%g, %g), want %v", i, j, point.X, point.Y, want[i][j])
+			}
+		}
+	}
+}
+
+// TestHanziText 字号为字框高度：10.24 毫米时 1 个字体单位为 0.01 毫米；字宽为一个字框，换行按 1.25 倍字框
+func TestHanziText(t *testing.T) {
+	font, err := ParseHanzi(strings.NewReader(hanziFixture), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := Text("一二\n三", TextStyle{Font: font, Size: 10.24})
+	if len(signature.Strokes) != 4 {
+		t.Fatalf("%d strokes, want 4", len(signature.Strokes))
+	}
+	// point 第 line 行第 column 个字中原坐标 (x, y) 的位置
+	point := func(line, column int, x, y float64) [2]float64 {
+		return [2]float64{float64(column)*10.24 + x*0.01, 10.24 + float64(line)*12.8 + (hanziTop-y-hanziEm)*0.01}
+	}
+	tests := []struct {
+		stroke, index int
+		want          [2]float64
+	}{
+		{0, 0, point(0, 0, 121, 393)}, // 一
+		{0, 3, point(0, 0, 880, 388)},
+		{1, 0, point(0, 1, 248, 598)}, // 二 的上横
+		{2, 3, point(0, 1, 900, 223)}, // 二 的下横
+		{3, 0, point(1, 0, 100, 700)}, // 第二行的 三
+	}
+	for _, test := range tests {
+		got := signature.Strokes[test.stroke].Points[test.index]
+		if math.Abs(float64(got.X)-test.want[0]) > 1e-4 || math.Abs(float64(got.Y)-test.want[1]) > 1e-4 {
+			t.Errorf("stroke %d point %d = (%g, %g), want %v", test.stroke, test.index, got.X, got.Y, test.want)
+		}
+	}
+}
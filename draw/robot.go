@@ -7,24 +7,25 @@ import (
 
 	"github.com/gomlx/bsplines"
 	"github.com/zdypro888/godobot"
+	"github.com/zdypro888/godobot/teach"
 )
 
 type Robot struct {
 	dobot *godobot.Dobot
 }
 
-func NewRobot(port string, baudrate uint32) (*Robot, error) {
+func NewRobot(ctx context.Context, address string) (*Robot, error) {
 	dobot := godobot.NewDobot()
-	if err := dobot.Connect(port, baudrate); err != nil {
+	if err := dobot.Connect(address); err != nil {
 		return nil, err
 	}
-	if err := dobot.ClearAllAlarmsState(); err != nil {
+	if err := dobot.ClearAllAlarmsState(ctx); err != nil {
 		return nil, err
 	}
-	if err := dobot.SetQueuedCmdClear(); err != nil {
+	if err := dobot.SetQueuedCmdClear(ctx); err != nil {
 		return nil, err
 	}
-	if err := dobot.SetQueuedCmdStartExec(); err != nil {
+	if err := dobot.SetQueuedCmdStartExec(ctx); err != nil {
 		return nil, err
 	}
 	return &Robot{dobot: dobot}, nil
@@ -37,64 +38,59 @@ func (robot *Robot) Close() error {
 	return robot.dobot.Close()
 }
 
-func (robot *Robot) Stop() error {
-	if err := robot.dobot.SetQueuedCmdStopExec(); err != nil {
+func (robot *Robot) Stop(ctx context.Context) error {
+	if err := robot.dobot.SetQueuedCmdStopExec(ctx); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (robot *Robot) EnmergyStop() error {
-	if err := robot.dobot.SetQueuedCmdForceStopExec(); err != nil {
+func (robot *Robot) EnmergyStop(ctx context.Context) error {
+	if err := robot.dobot.SetQueuedCmdForceStopExec(ctx); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (robot *Robot) HomeZero() error {
-	if _, err := robot.dobot.SetHOMEParams(&godobot.HOMEParams{X: 160, Y: 0, Z: 0, R: 0}, true); err != nil {
+func (robot *Robot) HomeZero(ctx context.Context) error {
+	if _, err := robot.dobot.SetHOMEParams(ctx, &godobot.HOMEParams{X: 160, Y: 0, Z: 0, R: 0}, true); err != nil {
 		return err
 	}
-	if err := robot.dobot.QueuedComplete(func() (uint64, error) {
-		return robot.dobot.SetHOMECmd(&godobot.HOMECmd{}, true)
+	if err := robot.dobot.QueuedComplete(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+		return robot.dobot.SetHOMECmd(ctx, &godobot.HOMECmd{}, true)
 	}); err != nil {
 		return err
 	}
 	return nil
 }
 
+// Capture 以 HHT 周期触发采集位姿，直到 ctx 结束时返回；出错时返回 nil 与错误。
+// 返回前关闭 HHT 触发输出。需要时间戳与末端执行器状态时使用 teach.Record
 func (robot *Robot) Capture(ctx context.Context, debug bool) ([]*godobot.Pose, error) {
-	robot.dobot.SetHHTTrigMode(godobot.TriggeredOnPeriodicInterval)
-	robot.dobot.SetHHTTrigOutputEnabled(true)
-	var postions []*godobot.Pose
-	for {
-		select {
-		case <-ctx.Done():
-			return postions, nil
-		default:
-			if enabled, err := robot.dobot.GetHHTTrigOutput(); err != nil {
-				return nil, err
-			} else if enabled {
-				pos, err := robot.dobot.GetPose()
-				if err != nil {
-					return nil, err
-				}
-				if debug {
-					fmt.Printf("current pos: %f, %f, %f\n", pos.X, pos.Y, pos.Z)
-				}
-				postions = append(postions, pos)
-			}
+	options := teach.Options{Mode: godobot.TriggeredOnPeriodicInterval}
+	if debug {
+		options.OnPoint = func(point teach.Point) {
+			fmt.Printf("current pos: %f, %f, %f\n", point.X, point.Y, point.Z)
 		}
 	}
+	trajectory, err := teach.Record(ctx, robot.dobot, options)
+	if err != nil {
+		return nil, err
+	}
+	postions := make([]*godobot.Pose, 0, len(trajectory.Points))
+	for _, point := range trajectory.Points {
+		postions = append(postions, &godobot.Pose{X: point.X, Y: point.Y, Z: point.Z, R: point.R})
+	}
+	return postions, nil
 }
 
-func (robot *Robot) DrawInit() error {
+func (robot *Robot) DrawInit(ctx context.Context) error {
 	// 设置精度和速度
 	ptpCommonParams := godobot.PTPCommonParams{
-		VelocityRatio:     200.0, // 速度百分比
-		AccelerationRatio: 200.0, // 加速度百分比
+		VelocityRatio:     100.0, // 速度百分比
+		AccelerationRatio: 100.0, // 加速度百分比
 	}
-	if _, err := robot.dobot.SetPTPCommonParams(&ptpCommonParams, false); err != nil {
+	if _, err := robot.dobot.SetPTPCommonParams(ctx, &ptpCommonParams, false); err != nil {
 		return err
 	}
 	// 设置 PTP 关节模式的速度和加速度
@@ -102,7 +98,7 @@ func (robot *Robot) DrawInit() error {
 		Velocity:     [4]float32{200.0, 200.0, 200.0, 200.0},
 		Acceleration: [4]float32{200.0, 200.0, 200.0, 200.0},
 	}
-	if _, err := robot.dobot.SetPTPJointParams(ptpJointParams, true); err != nil {
+	if _, err := robot.dobot.SetPTPJointParams(ctx, ptpJointParams, true); err != nil {
 		return err
 	}
 	// 设置 PTP 坐标模式的速度和加速度
@@ -112,7 +108,7 @@ func (robot *Robot) DrawInit() error {
 		XYZAcceleration: 200.0, // 加速度
 		RAcceleration:   200.0, // 旋转轴加速度
 	}
-	if _, err := robot.dobot.SetPTPCoordinateParams(&ptpCoordinateParams, false); err != nil {
+	if _, err := robot.dobot.SetPTPCoordinateParams(ctx, &ptpCoordinateParams, false); err != nil {
 		return err
 	}
 	// 设置 CP 的速度和加速度
@@ -121,7 +117,7 @@ func (robot *Robot) DrawInit() error {
 		JuncitionVel: 10, // 拐点速度（减少顿挫）
 		AccOrPeriod:  80, // 全局加速度比率
 	}
-	if _, err := robot.dobot.SetCPParams(&cpParams, true); err != nil {
+	if _, err := robot.dobot.SetCPParams(ctx, &cpParams, true); err != nil {
 		return err
 	}
 	return nil
@@ -147,12 +143,16 @@ func curvatureAt(points []*Point, i int) float64 {
 	ddx := dx2 - dx1
 	ddy := dy2 - dy1
 
-	// 计算曲率
-	curvature := math.Abs(float64(dx1*ddy-dy1*ddx)) / math.Pow(float64(dx1*dx1+dy1*dy1), 1.5)
+	// 计算曲率，重合的相邻点视为直线
+	length := float64(dx1*dx1 + dy1*dy1)
+	if length == 0 {
+		return 0
+	}
+	curvature := math.Abs(float64(dx1*ddy-dy1*ddx)) / math.Pow(length, 1.5)
 	return curvature
 }
 
-func (robot *Robot) Draw(trajectories *Signature, z float32, scale float64, bspline bool) error {
+func (robot *Robot) Draw(ctx context.Context, trajectories *Signature, z float32, scale float64, bspline bool) error {
 	for _, stroke := range trajectories.Strokes {
 		// 创建 B-Spline（3 阶）
 		degree := 3
@@ -197,8 +197,8 @@ func (robot *Robot) Draw(trajectories *Signature, z float32, scale float64, bspl
 			Z:       z,
 			R:       0,
 		}
-		if err := robot.dobot.QueuedComplete(func() (uint64, error) {
-			return robot.dobot.SetPTPCmd(goFirstPoint, true)
+		if err := robot.dobot.QueuedComplete(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return robot.dobot.SetPTPCmd(ctx, goFirstPoint, true)
 		}); err != nil {
 			return err
 		}
@@ -206,7 +206,8 @@ func (robot *Robot) Draw(trajectories *Signature, z float32, scale float64, bspl
 		for i, currPoint := range smoothPoints {
 			var curvature float32
 			if bspline {
-				curvature = float32(10 + 40*(1-curvatureAt(smoothPoints, i)))
+				// 曲率限制在 [0, 1]，速度在 10 到 50 之间
+				curvature = float32(10 + 40*(1-min(curvatureAt(smoothPoints, i), 1)))
 			} else {
 				curvature = 200.0
 			}
@@ -220,8 +221,8 @@ func (robot *Robot) Draw(trajectories *Signature, z float32, scale float64, bspl
 				Z:        0,
 				Velocity: curvature,
 			}
-			if _, err := robot.dobot.QueuedSend(func() (uint64, error) {
-				return robot.dobot.SetCPCmd(movePoint, true)
+			if _, err := robot.dobot.QueuedSend(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+				return robot.dobot.SetCPCmd(ctx, movePoint, true)
 			}); err != nil {
 				return err
 			}
@@ -234,8 +235,8 @@ func (robot *Robot) Draw(trajectories *Signature, z float32, scale float64, bspl
 		Z:       0,
 		R:       0,
 	}
-	if err := robot.dobot.QueuedComplete(func() (uint64, error) {
-		return robot.dobot.SetPTPCmd(goHomePoint, true)
+	if err := robot.dobot.QueuedComplete(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+		return robot.dobot.SetPTPCmd(ctx, goHomePoint, true)
 	}); err != nil {
 		return err
 	}
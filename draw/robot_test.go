@@ -0,0 +1,59 @@
+package draw
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot/simulator"
+)
+
+// TestDraw 在模拟器上绘制含重合点与折角的笔画，曲率与速度都应保持有限且非负
+func TestDraw(t *testing.T) {
+	stroke := &Stroke{Points: []*Point{
+		{X: 0, Y: 0}, {X: 0, Y: 0}, {X: 5, Y: 0}, {X: 5, Y: 0}, {X: 5, Y: 0.01}, {X: 0, Y: 5}, {X: 10, Y: 10},
+	}}
+	signature := &Signature{Strokes: []*Stroke{stroke, {Points: []*Point{{X: 20, Y: 0}, {X: 20, Y: 0}, {X: 25, Y: 5}, {X: 30, Y: 0}}}}}
+	for _, bspline := range []bool{false, true} {
+		sim := simulator.New()
+		sim.SetTimeScale(100)
+		name := fmt.Sprintf("draw-%v", bspline)
+		if _, err := sim.Listen(name); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		robot, err := NewRobot(ctx, "pipe://"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := robot.Draw(ctx, signature, 0, 1, bspline); err != nil {
+			t.Errorf("bspline %v: Draw: %v", bspline, err)
+		}
+		if pose := sim.Pose(); math.Abs(float64(pose.X)-160) > 0.01 || math.Abs(float64(pose.Y)) > 0.01 {
+			t.Errorf("bspline %v: ended at %+v, want home", bspline, pose)
+		}
+		robot.Close()
+		sim.Close()
+		cancel()
+	}
+}
+
+func TestCurvatureAt(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []*Point
+		want   float64
+	}{
+		{"straight", []*Point{{X: 0}, {X: 1}, {X: 2}}, 0},
+		{"repeated point", []*Point{{X: 1}, {X: 1}, {X: 2, Y: 1}}, 0},
+		{"all repeated", []*Point{{X: 1}, {X: 1}, {X: 1}}, 0},
+		{"right angle", []*Point{{X: 0}, {X: 1}, {X: 1, Y: 1}}, 1},
+	}
+	for _, test := range tests {
+		if got := curvatureAt(test.points, 1); math.IsNaN(got) || math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: curvatureAt = %g, want %g", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,669 @@
+package draw
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrBadSVG = errors.New("bad svg")
+
+// DefaultTolerance 曲线离散的默认弦高（毫米）
+const DefaultTolerance = 0.1
+
+// LoadSVG 读取 SVG 文件，见 ParseSVG
+func LoadSVG(path string, tolerance float64) (*Signature, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseSVG(file, tolerance)
+}
+
+// ParseSVG 将 SVG 中的 path、line、polyline、polygon、rect、circle、ellipse 转换为笔画，
+// 坐标按 viewBox 与 width、height 换算为毫米（y 轴向下，与画布一致），曲线离散的弦高不超过 tolerance 毫米，
+// tolerance 不大于 0 时使用 DefaultTolerance。每个子路径为一笔，填充、描边样式与文字被忽略；
+// 结果以毫米为单位，Robot.Draw 时 scale 取 1
+func ParseSVG(r io.Reader, tolerance float64) (*Signature, error) {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	parser := &svgParser{tolerance: tolerance}
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadSVG, err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if err := parser.start(token); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			parser.end()
+		}
+	}
+	if !parser.root {
+		return nil, fmt.Errorf("%w: no svg element", ErrBadSVG)
+	}
+	return &Signature{Strokes: parser.strokes}, nil
+}
+
+// matrix 仿射变换 [a b c d e f]：x' = a*x + c*y + e，y' = b*x + d*y + f
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul 先应用 n 再应用 m
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m matrix) apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// scale 平均缩放倍数，用于把毫米弦高换算到用户坐标
+func (m matrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// svgParser 元素栈上每层记录累积变换，skip 大于 0 时处于被忽略的子树中
+type svgParser struct {
+	tolerance float64
+	root      bool
+	stack     []matrix
+	skip      int
+	strokes   []*Stroke
+}
+
+func (parser *svgParser) top() matrix {
+	if len(parser.stack) == 0 {
+		return identity
+	}
+	return parser.stack[len(parser.stack)-1]
+}
+
+func (parser *svgParser) start(element xml.StartElement) error {
+	if parser.skip > 0 {
+		parser.skip++
+		return nil
+	}
+	attrs := map[string]string{}
+	for _, attr := range element.Attr {
+		attrs[attr.Name.Local] = attr.Value
+	}
+	if attrs["display"] == "none" {
+		parser.skip = 1
+		return nil
+	}
+	m := parser.top()
+	if element.Name.Local == "svg" {
+		viewport, err := viewportMatrix(attrs, parser.root)
+		if err != nil {
+			return err
+		}
+		m = m.mul(viewport)
+		parser.root = true
+	}
+	transform, err := parseTransform(attrs["transform"])
+	if err != nil {
+		return err
+	}
+	m = m.mul(transform)
+	parser.stack = append(parser.stack, m)
+	var d string
+	switch element.Name.Local {
+	case "defs", "clipPath", "mask", "symbol", "marker", "pattern", "style", "script", "text", "metadata":
+		parser.stack = parser.stack[:len(parser.stack)-1]
+		parser.skip = 1
+		return nil
+	case "path":
+		d = attrs["d"]
+	case "line":
+		d = fmt.Sprintf("M%g,%g L%g,%g", number(attrs["x1"]), number(attrs["y1"]), number(attrs["x2"]), number(attrs["y2"]))
+	case "polyline", "polygon":
+		points := numbers(attrs["points"])
+		if len(points) < 4 {
+			return nil
+		}
+		var builder strings.Builder
+		for i := 0; i+1 < len(points); i += 2 {
+			command := "L"
+			if i == 0 {
+				command = "M"
+			}
+			fmt.Fprintf(&builder, "%s%g,%g ", command, points[i], points[i+1])
+		}
+		if element.Name.Local == "polygon" {
+			builder.WriteString("Z")
+		}
+		d = builder.String()
+	case "rect":
+		d = rectPath(number(attrs["x"]), number(attrs["y"]), number(attrs["width"]), number(attrs["height"]), attrs["rx"], attrs["ry"])
+	case "circle":
+		r := number(attrs["r"])
+		d = ellipsePath(number(attrs["cx"]), number(attrs["cy"]), r, r)
+	case "ellipse":
+		d = ellipsePath(number(attrs["cx"]), number(attrs["cy"]), number(attrs["rx"]), number(attrs["ry"]))
+	default:
+		return nil
+	}
+	if d == "" {
+		return nil
+	}
+	pen := &svgPen{m: m, tolerance: parser.tolerance}
+	if err := pen.path(d); err != nil {
+		return fmt.Errorf("%w: <%s>: %v", ErrBadSVG, element.Name.Local, err)
+	}
+	parser.strokes = append(parser.strokes, pen.strokes...)
+	return nil
+}
+
+func (parser *svgParser) end() {
+	if parser.skip > 0 {
+		parser.skip--
+		return
+	}
+	if len(parser.stack) > 0 {
+		parser.stack = parser.stack[:len(parser.stack)-1]
+	}
+}
+
+// viewportMatrix 由 width、height 与 viewBox 计算用户坐标到毫米的变换（xMidYMid meet）；
+// 嵌套的 svg 以 x、y 平移，尺寸单位沿用外层
+func viewportMatrix(attrs map[string]string, nested bool) (matrix, error) {
+	m := identity
+	unit := 25.4 / 96 // 无单位长度按 CSS 像素
+	if nested {
+		unit = 1
+		m = matrix{1, 0, 0, 1, number(attrs["x"]), number(attrs["y"])}
+	}
+	view := numbers(attrs["viewBox"])
+	width, widthOK := length(attrs["width"], unit)
+	height, heightOK := length(attrs["height"], unit)
+	if len(view) != 4 {
+		if nested {
+			return m, nil
+		}
+		return matrix{unit, 0, 0, unit, 0, 0}, nil
+	}
+	if view[2] <= 0 || view[3] <= 0 {
+		return m, fmt.Errorf("%w: viewBox %q", ErrBadSVG, attrs["viewBox"])
+	}
+	if !widthOK && !heightOK {
+		width, height = view[2]*unit, view[3]*unit
+	} else if !widthOK {
+		width = height * view[2] / view[3]
+	} else if !heightOK {
+		height = width * view[3] / view[2]
+	}
+	scale := min(width/view[2], height/view[3])
+	tx := (width-view[2]*scale)/2 - view[0]*scale
+	ty := (height-view[3]*scale)/2 - view[1]*scale
+	return m.mul(matrix{scale, 0, 0, scale, tx, ty}), nil
+}
+
+// length 解析带单位的长度，返回毫米；百分比与空值视为未指定
+func length(text string, unit float64) (float64, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.HasSuffix(text, "%") {
+		return 0, false
+	}
+	units := map[string]float64{"mm": 1, "cm": 10, "in": 25.4, "pt": 25.4 / 72, "pc": 25.4 / 6, "px": 25.4 / 96}
+	for suffix, factor := range units {
+		if strings.HasSuffix(text, suffix) {
+			text, unit = strings.TrimSuffix(text, suffix), factor
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || value <= 0 {
+		return 0, false
+	}
+	return value * unit, true
+}
+
+// number 解析属性中的数值，忽略 px 单位
+func number(text string) float64 {
+	value, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(text), "px"), 64)
+	return value
+}
+
+// numbers 解析以空白或逗号分隔的数值列表
+func numbers(text string) []float64 {
+	scanner := &pathScanner{text: text}
+	var values []float64
+	for {
+		value, ok := scanner.number()
+		if !ok {
+			return values
+		}
+		values = append(values, value)
+	}
+}
+
+// parseTransform 解析 transform 属性
+func parseTransform(text string) (matrix, error) {
+	m := identity
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimLeft(text, " \t\r\n,") {
+		open := strings.IndexByte(text, '(')
+		close := strings.IndexByte(text, ')')
+		if open < 0 || close < open {
+			return m, fmt.Errorf("%w: transform %q", ErrBadSVG, text)
+		}
+		name := strings.TrimSpace(text[:open])
+		args := numbers(text[open+1 : close])
+		text = text[close+1:]
+		arg := func(i int, fallback float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return fallback
+		}
+		var t matrix
+		switch name {
+		case "matrix":
+			if len(args) != 6 {
+				return m, fmt.Errorf("%w: matrix needs 6 values", ErrBadSVG)
+			}
+			copy(t[:], args)
+		case "translate":
+			t = matrix{1, 0, 0, 1, arg(0, 0), arg(1, 0)}
+		case "scale":
+			t = matrix{arg(0, 1), 0, 0, arg(1, arg(0, 1)), 0, 0}
+		case "rotate":
+			a := arg(0, 0) * math.Pi / 180
+			cx, cy := arg(1, 0), arg(2, 0)
+			t = matrix{1, 0, 0, 1, cx, cy}.mul(matrix{math.Cos(a), math.Sin(a), -math.Sin(a), math.Cos(a), 0, 0}).mul(matrix{1, 0, 0, 1, -cx, -cy})
+		case "skewX":
+			t = matrix{1, 0, math.Tan(arg(0, 0) * math.Pi / 180), 1, 0, 0}
+		case "skewY":
+			t = matrix{1, math.Tan(arg(0, 0) * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return m, fmt.Errorf("%w: transform %s", ErrBadSVG, name)
+		}
+		m = m.mul(t)
+	}
+	return m, nil
+}
+
+// rectPath 矩形的路径，rx、ry 按 SVG 规则互相补全并限制在半边长内
+func rectPath(x, y, width, height float64, rxText, ryText string) string {
+	if width <= 0 || height <= 0 {
+		return ""
+	}
+	rx, ry := number(rxText), number(ryText)
+	if rxText == "" {
+		rx = ry
+	}
+	if ryText == "" {
+		ry = rx
+	}
+	rx, ry = min(max(rx, 0), width/2), min(max(ry, 0), height/2)
+	if rx == 0 || ry == 0 {
+		return fmt.Sprintf("M%g,%g H%g V%g H%g Z", x, y, x+width, y+height, x)
+	}
+	return fmt.Sprintf("M%g,%g H%g A%g,%g 0 0 1 %g,%g V%g A%g,%g 0 0 1 %g,%g H%g A%g,%g 0 0 1 %g,%g V%g A%g,%g 0 0 1 %g,%g Z",
+		x+rx, y, x+width-rx, rx, ry, x+width, y+ry,
+		y+height-ry, rx, ry, x+width-rx, y+height,
+		x+rx, rx, ry, x, y+height-ry,
+		y+ry, rx, ry, x+rx, y)
+}
+
+// ellipsePath 椭圆的路径，由两段半椭圆弧组成
+func ellipsePath(cx, cy, rx, ry float64) string {
+	if rx <= 0 || ry <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("M%g,%g A%g,%g 0 1 0 %g,%g A%g,%g 0 1 0 %g,%g Z", cx-rx, cy, rx, ry, cx+rx, cy, rx, ry, cx-rx, cy)
+}
+
+// pathScanner 路径数据的词法扫描
+type pathScanner struct {
+	text string
+	pos  int
+}
+
+func (scanner *pathScanner) skipSpace() {
+	for scanner.pos < len(scanner.text) && strings.IndexByte(" \t\r\n,", scanner.text[scanner.pos]) >= 0 {
+		scanner.pos++
+	}
+}
+
+// command 读取命令字母，下一个字符不是字母时返回 0
+func (scanner *pathScanner) command() byte {
+	scanner.skipSpace()
+	if scanner.pos >= len(scanner.text) {
+		return 0
+	}
+	c := scanner.text[scanner.pos]
+	if upper := c &^ 0x20; 'A' <= upper && upper <= 'Z' && upper != 'E' {
+		scanner.pos++
+		return c
+	}
+	return 0
+}
+
+// done 是否已读完
+func (scanner *pathScanner) done() bool {
+	scanner.skipSpace()
+	return scanner.pos >= len(scanner.text)
+}
+
+// number 读取一个数值，支持 1.5.5、-1-2 与指数写法
+func (scanner *pathScanner) number() (float64, bool) {
+	scanner.skipSpace()
+	text, start := scanner.text, scanner.pos
+	i := start
+	if i < len(text) && (text[i] == '+' || text[i] == '-') {
+		i++
+	}
+	digits, dot := false, false
+	for ; i < len(text); i++ {
+		c := text[i]
+		if '0' <= c && c <= '9' {
+			digits = true
+		} else if c == '.' && !dot {
+			dot = true
+		} else {
+			break
+		}
+	}
+	if !digits {
+		return 0, false
+	}
+	if i < len(text) && (text[i] == 'e' || text[i] == 'E') {
+		j := i + 1
+		if j < len(text) && (text[j] == '+' || text[j] == '-') {
+			j++
+		}
+		if j < len(text) && '0' <= text[j] && text[j] <= '9' {
+			for j < len(text) && '0' <= text[j] && text[j] <= '9' {
+				j++
+			}
+			i = j
+		}
+	}
+	value, err := strconv.ParseFloat(text[start:i], 64)
+	if err != nil {
+		return 0, false
+	}
+	scanner.pos = i
+	return value, true
+}
+
+// flag 读取圆弧的标志位，允许与后续数值相连
+func (scanner *pathScanner) flag() (bool, bool) {
+	scanner.skipSpace()
+	if scanner.pos < len(scanner.text) {
+		switch scanner.text[scanner.pos] {
+		case '0':
+			scanner.pos++
+			return false, true
+		case '1':
+			scanner.pos++
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// svgPen 在用户坐标下描绘路径，输出变换到毫米后的笔画
+type svgPen struct {
+	m         matrix
+	tolerance float64 // 毫米
+	points    []*Point
+	strokes   []*Stroke
+}
+
+// emit 追加一个用户坐标点
+func (pen *svgPen) emit(x, y float64) {
+	px, py := pen.m.apply(x, y)
+	pen.emitMM(px, py)
+}
+
+func (pen *svgPen) emitMM(x, y float64) {
+	pen.points = append(pen.points, &Point{X: float32(x), Y: float32(y)})
+}
+
+// flush 结束当前子路径，少于两个点的子路径被丢弃
+func (pen *svgPen) flush() {
+	if len(pen.points) >= 2 {
+		pen.strokes = append(pen.strokes, &Stroke{Points: pen.points})
+	}
+	pen.points = nil
+}
+
+// cubic 以弦高 tolerance 离散三次贝塞尔曲线，控制点先变换到毫米
+func (pen *svgPen) cubic(x0, y0, x1, y1, x2, y2, x3, y3 float64) {
+	var p [8]float64
+	p[0], p[1] = pen.m.apply(x0, y0)
+	p[2], p[3] = pen.m.apply(x1, y1)
+	p[4], p[5] = pen.m.apply(x2, y2)
+	p[6], p[7] = pen.m.apply(x3, y3)
+	pen.subdivide(p, 0)
+}
+
+func (pen *svgPen) subdivide(p [8]float64, depth int) {
+	dx, dy := p[6]-p[0], p[7]-p[1]
+	chord := math.Hypot(dx, dy)
+	var d1, d2 float64
+	if chord > 1e-12 {
+		d1 = math.Abs((p[2]-p[0])*dy-(p[3]-p[1])*dx) / chord
+		d2 = math.Abs((p[4]-p[0])*dy-(p[5]-p[1])*dx) / chord
+	} else {
+		d1 = math.Hypot(p[2]-p[0], p[3]-p[1])
+		d2 = math.Hypot(p[4]-p[0], p[5]-p[1])
+	}
+	// 控制点到弦的距离的 3/4 为曲线弦高的上界
+	if 0.75*max(d1, d2) <= pen.tolerance || depth >= 16 {
+		pen.emitMM(p[6], p[7])
+		return
+	}
+	var left, right [8]float64
+	for axis := 0; axis < 2; axis++ {
+		a, b, c, d := p[axis], p[2+axis], p[4+axis], p[6+axis]
+		ab, bc, cd := (a+b)/2, (b+c)/2, (c+d)/2
+		abc, bcd := (ab+bc)/2, (bc+cd)/2
+		mid := (abc + bcd) / 2
+		left[axis], left[2+axis], left[4+axis], left[6+axis] = a, ab, abc, mid
+		right[axis], right[2+axis], right[4+axis], right[6+axis] = mid, bcd, cd, d
+	}
+	pen.subdivide(left, depth+1)
+	pen.subdivide(right, depth+1)
+}
+
+// arc 按 SVG 端点参数离散椭圆弧（不含起点）
+func (pen *svgPen) arc(x0, y0, rx, ry, rotation float64, large, sweep bool, x, y float64) {
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 || (x0 == x && y0 == y) {
+		pen.emit(x, y)
+		return
+	}
+	phi := rotation * math.Pi / 180
+	cos, sin := math.Cos(phi), math.Sin(phi)
+	// 端点参数转换为圆心参数，见 SVG 规范附录 B.2.4
+	dx, dy := (x0-x)/2, (y0-y)/2
+	x1 := cos*dx + sin*dy
+	y1 := -sin*dx + cos*dy
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		rx, ry = rx*math.Sqrt(lambda), ry*math.Sqrt(lambda)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(max(num/den, 0))
+	if large == sweep {
+		coef = -coef
+	}
+	cx1, cy1 := coef*rx*y1/ry, -coef*ry*x1/rx
+	cx := cos*cx1 - sin*cy1 + (x0+x)/2
+	cy := sin*cx1 + cos*cy1 + (y0+y)/2
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+	radius := max(rx, ry) * pen.m.scale()
+	step := math.Pi / 2
+	if pen.tolerance < radius {
+		step = min(step, 2*math.Acos(1-pen.tolerance/radius))
+	}
+	n := max(1, int(math.Ceil(math.Abs(delta)/step)))
+	for i := 1; i <= n; i++ {
+		if i == n {
+			pen.emit(x, y)
+			break
+		}
+		t := theta + delta*float64(i)/float64(n)
+		ex, ey := rx*math.Cos(t), ry*math.Sin(t)
+		pen.emit(cos*ex-sin*ey+cx, sin*ex+cos*ey+cy)
+	}
+}
+
+// path 解析并描绘路径数据
+func (pen *svgPen) path(d string) error {
+	scanner := &pathScanner{text: d}
+	var cx, cy, sx, sy float64 // 当前点与子路径起点
+	var qx, qy float64         // 上一段曲线的控制点，用于 S、T 的反射
+	var last byte
+	defer pen.flush()
+	for !scanner.done() {
+		command := scanner.command()
+		if command == 0 {
+			// 省略命令字母时重复上一命令，M 之后视为 L
+			switch last {
+			case 0, 'Z', 'z':
+				return fmt.Errorf("expected command at %d", scanner.pos)
+			case 'M':
+				command = 'L'
+			case 'm':
+				command = 'l'
+			default:
+				command = last
+			}
+		}
+		relative := 'a' <= command && command <= 'z'
+		upper := command &^ 0x20
+		var args [7]float64
+		counts := map[byte]int{'M': 2, 'L': 2, 'H': 1, 'V': 1, 'C': 6, 'S': 4, 'Q': 4, 'T': 2, 'A': 7, 'Z': 0}
+		count, ok := counts[upper]
+		if !ok {
+			return fmt.Errorf("unknown command %c", command)
+		}
+		for i := 0; i < count; i++ {
+			var value float64
+			if upper == 'A' && (i == 3 || i == 4) {
+				flag, ok := scanner.flag()
+				if !ok {
+					return fmt.Errorf("bad arc flag at %d", scanner.pos)
+				}
+				if flag {
+					value = 1
+				}
+			} else if value, ok = scanner.number(); !ok {
+				return fmt.Errorf("missing argument for %c at %d", command, scanner.pos)
+			}
+			args[i] = value
+		}
+		// abs 将第 i、i+1 个参数换算为绝对坐标
+		abs := func(i int) (float64, float64) {
+			if relative {
+				return cx + args[i], cy + args[i+1]
+			}
+			return args[i], args[i+1]
+		}
+		smooth := last&^0x20 == 'C' || last&^0x20 == 'S' || last&^0x20 == 'Q' || last&^0x20 == 'T'
+		switch upper {
+		case 'M':
+			pen.flush()
+			cx, cy = abs(0)
+			sx, sy = cx, cy
+			pen.emit(cx, cy)
+		case 'L':
+			cx, cy = abs(0)
+			pen.emit(cx, cy)
+		case 'H':
+			if relative {
+				cx += args[0]
+			} else {
+				cx = args[0]
+			}
+			pen.emit(cx, cy)
+		case 'V':
+			if relative {
+				cy += args[0]
+			} else {
+				cy = args[0]
+			}
+			pen.emit(cx, cy)
+		case 'C', 'S':
+			x1, y1 := cx, cy
+			var x2, y2, x, y float64
+			if upper == 'C' {
+				x1, y1 = abs(0)
+				x2, y2 = abs(2)
+				x, y = abs(4)
+			} else {
+				if smooth && (last&^0x20 == 'C' || last&^0x20 == 'S') {
+					x1, y1 = 2*cx-qx, 2*cy-qy
+				}
+				x2, y2 = abs(0)
+				x, y = abs(2)
+			}
+			pen.cubic(cx, cy, x1, y1, x2, y2, x, y)
+			qx, qy, cx, cy = x2, y2, x, y
+		case 'Q', 'T':
+			x1, y1 := cx, cy
+			var x, y float64
+			if upper == 'Q' {
+				x1, y1 = abs(0)
+				x, y = abs(2)
+			} else {
+				if smooth && (last&^0x20 == 'Q' || last&^0x20 == 'T') {
+					x1, y1 = 2*cx-qx, 2*cy-qy
+				}
+				x, y = abs(0)
+			}
+			// 二次曲线升阶为三次
+			pen.cubic(cx, cy, cx+2*(x1-cx)/3, cy+2*(y1-cy)/3, x+2*(x1-x)/3, y+2*(y1-y)/3, x, y)
+			qx, qy, cx, cy = x1, y1, x, y
+		case 'A':
+			x, y := abs(5)
+			pen.arc(cx, cy, args[0], args[1], args[2], args[3] != 0, args[4] != 0, x, y)
+			cx, cy = x, y
+		case 'Z':
+			if cx != sx || cy != sy {
+				pen.emit(sx, sy)
+			}
+			cx, cy = sx, sy
+			pen.flush()
+			// Z 之后直接绘制时从子路径起点开始
+			pen.emit(sx, sy)
+		}
+		last = command
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package draw
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zdypro888/godobot/internal/golden"
+)
+
+// TestSVGGolden 解析 testdata 中的 SVG，与 .golden 文件中的笔画或错误比较
+func TestSVGGolden(t *testing.T) {
+	golden.Run(t, "*.svg", func(t *testing.T, file string) string {
+		signature, err := LoadSVG(file, 0.5)
+		if err != nil {
+			if !errors.Is(err, ErrBadSVG) {
+				t.Fatalf("error %v, want ErrBadSVG", err)
+			}
+			return fmt.Sprintf("error: %v\n", err)
+		}
+		return dumpStrokes(signature)
+	})
+}
+
+// dumpStrokes 每笔一行，坐标保留三位小数
+func dumpStrokes(signature *Signature) string {
+	var out strings.Builder
+	for _, stroke := range signature.Strokes {
+		for i, point := range stroke.Points {
+			if i > 0 {
+				out.WriteByte(' ')
+			}
+			fmt.Fprintf(&out, "%s,%s", coordinate(point.X), coordinate(point.Y))
+		}
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
+
+// coordinate 保留三位小数，-0 写作 0
+func coordinate(v float32) string {
+	s := fmt.Sprintf("%.3f", v)
+	if s == "-0.000" {
+		return "0.000"
+	}
+	return s
+}
@@ -0,0 +1,136 @@
+package draw
+
+import (
+	"math"
+	"strings"
+)
+
+// Align 文本对齐方式
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// TextStyle 文本排版参数，长度单位均为毫米
+type TextStyle struct {
+	Font       *Font   // 字体，nil 时使用 DefaultFont
+	Size       float64 // 字号，即大写字母高度（中文字体为字框高度）
+	Spacing    float64 // 额外字距
+	LineHeight float64 // 行距，0 时按字体默认行距缩放
+	Width      float64 // 文本框宽度，大于 0 时按词自动换行，过长的词按字符断开
+	Align      Align   // 在文本框内对齐，未设宽度时相对最长的行
+	X, Y       float64 // 文本框左上角
+	Angle      float64 // 基线绕左上角逆时针旋转的角度（度）
+}
+
+// Text 将文本渲染为单线笔画，坐标 y 轴向下；\n 强制换行
+func Text(text string, style TextStyle) *Signature {
+	font := style.Font
+	if font == nil {
+		font = DefaultFont
+	}
+	if style.Size <= 0 || font.CapHeight <= 0 {
+		return &Signature{}
+	}
+	lineHeight := style.LineHeight
+	if lineHeight <= 0 {
+		lineHeight = font.LineHeight * style.Size / font.CapHeight
+	}
+	// advance 字符占用的宽度，不含字距
+	advance := func(char rune) float64 {
+		glyph, f := font.Glyph(char)
+		return (glyph.Right - glyph.Left) * style.Size / f.CapHeight
+	}
+	measure := func(line []rune) float64 {
+		width := 0.0
+		for i, char := range line {
+			if i > 0 {
+				width += style.Spacing
+			}
+			width += advance(char)
+		}
+		return width
+	}
+	var lines [][]rune
+	for _, paragraph := range strings.Split(text, "\n") {
+		lines = append(lines, wrap([]rune(paragraph), style.Width, measure)...)
+	}
+	box := style.Width
+	if box <= 0 {
+		for _, line := range lines {
+			box = max(box, measure(line))
+		}
+	}
+	sin, cos := math.Sincos(style.Angle * math.Pi / 180)
+	signature := &Signature{}
+	for i, line := range lines {
+		x := 0.0
+		switch style.Align {
+		case AlignCenter:
+			x = (box - measure(line)) / 2
+		case AlignRight:
+			x = box - measure(line)
+		}
+		baseline := style.Size + float64(i)*lineHeight
+		for _, char := range line {
+			glyph, f := font.Glyph(char)
+			scale := style.Size / f.CapHeight
+			for _, stroke := range glyph.Strokes {
+				points := make([]*Point, 0, len(stroke.Points))
+				for _, p := range stroke.Points {
+					px := x + (float64(p.X)-glyph.Left)*scale
+					py := baseline + (float64(p.Y)-f.Baseline)*scale
+					// y 轴向下时逆时针旋转
+					points = append(points, &Point{
+						X: float32(style.X + px*cos + py*sin),
+						Y: float32(style.Y - px*sin + py*cos),
+					})
+				}
+				signature.Strokes = append(signature.Strokes, &Stroke{Points: points})
+			}
+			x += advance(char) + style.Spacing
+		}
+	}
+	return signature
+}
+
+// wrap 按宽度贪心断行，width 不大于 0 时不断行；行首行尾的空格被去掉
+func wrap(text []rune, width float64, measure func([]rune) float64) [][]rune {
+	if width <= 0 || measure(text) <= width {
+		return [][]rune{text}
+	}
+	var lines [][]rune
+	var line []rune
+	for _, word := range strings.Fields(string(text)) {
+		chars := []rune(word)
+		candidate := chars
+		if len(line) > 0 {
+			candidate = append(append(append([]rune{}, line...), ' '), chars...)
+		}
+		if measure(candidate) <= width {
+			line = candidate
+			continue
+		}
+		if len(line) > 0 {
+			lines = append(lines, line)
+			line = nil
+		}
+		// 单词本身超宽时按字符断开，每行至少一个字符
+		for len(chars) > 0 {
+			n := 1
+			for n < len(chars) && measure(chars[:n+1]) <= width {
+				n++
+			}
+			if n == len(chars) {
+				break
+			}
+			lines = append(lines, chars[:n])
+			chars = chars[n:]
+		}
+		line = chars
+	}
+	return append(lines, line)
+}
@@ -0,0 +1,248 @@
+// Package engrave 将位图转换为往返扫描线，以带功率的 CP 激光指令（SetCPLECmd）雕刻
+//
+// 图像坐标以左上角为原点，x 向右、y 向下，单位为毫米；与 draw.Robot 一致，
+// 图像向右对应机械臂 -Y，向下对应 -X
+package engrave
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+
+	"github.com/zdypro888/godobot"
+)
+
+var ErrConfig = errors.New("invalid engrave config")
+
+// Dither 灰度到功率的转换方式
+type Dither int
+
+const (
+	Grayscale      Dither = iota // 功率与黑度成正比
+	Threshold                    // 黑度不低于阈值时满功率
+	FloydSteinberg               // 误差扩散，输出开关两级功率
+	Ordered                      // 8x8 Bayer 有序抖动，输出开关两级功率
+)
+
+// Config 雕刻配置，功率为百分比
+type Config struct {
+	Origin    [3]float64       // 图像左上角在机械臂坐标系中的位置，Z 为焦点高度（毫米）
+	R         float32          // 末端旋转角度
+	PixelSize float64          // 像素边长，即扫描线间距（毫米）
+	Width     float64          // 雕刻宽度（毫米），0 时为原图宽度像素数乘 PixelSize，高度按比例
+	Dither    Dither           // 转换方式
+	Threshold float64          // Threshold 模式的黑度阈值（0~1）
+	Invert    bool             // 反色，雕刻浅色部分
+	MinPower  float32          // 最浅的非空白像素的功率
+	MaxPower  float32          // 全黑像素的功率
+	Speed     float64          // 雕刻速度（毫米/秒）
+	Overscan  float64          // 扫描线两端关闭激光的加减速距离（毫米）
+	CPParams  godobot.CPParams // CP 参数，JuncitionVel 由 Speed 决定
+}
+
+// DefaultConfig 默认配置：0.2 毫米扫描间距，满功率 100%，速度 20 毫米/秒，两端各空走 2 毫米，图像位置需另行设置
+func DefaultConfig() Config {
+	return Config{
+		PixelSize: 0.2,
+		Threshold: 0.5,
+		MaxPower:  100,
+		Speed:     20,
+		Overscan:  2,
+		CPParams:  godobot.CPParams{PlanAcc: 100, AccOrPeriod: 100},
+	}
+}
+
+func (config *Config) validate() error {
+	switch {
+	case config.PixelSize <= 0:
+		return fmt.Errorf("%w: pixel size %g", ErrConfig, config.PixelSize)
+	case config.Width < 0:
+		return fmt.Errorf("%w: width %g", ErrConfig, config.Width)
+	case config.Dither < Grayscale || config.Dither > Ordered:
+		return fmt.Errorf("%w: dither %d", ErrConfig, config.Dither)
+	case config.MinPower < 0 || config.MaxPower > 100 || config.MinPower > config.MaxPower:
+		return fmt.Errorf("%w: power %g~%g", ErrConfig, config.MinPower, config.MaxPower)
+	case config.Speed <= 0:
+		return fmt.Errorf("%w: speed %g", ErrConfig, config.Speed)
+	case config.Overscan < 0:
+		return fmt.Errorf("%w: overscan %g", ErrConfig, config.Overscan)
+	}
+	return nil
+}
+
+// Load 读取 PNG 或 JPEG 图像
+func Load(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	return img, err
+}
+
+// Segment 扫描线上功率不变的一段，From 到 To 为扫描方向
+type Segment struct {
+	From, To float64 // 图像 x 坐标（毫米）
+	Power    float32
+}
+
+// Scanline 一条扫描线，不含两端空走
+type Scanline struct {
+	Y        float64 // 图像 y 坐标（毫米）
+	Segments []Segment
+}
+
+// Scanlines 将图像转换为往返扫描线：空白行被跳过，每行去掉两端的空白，相邻非空行方向相反
+func Scanlines(img image.Image, config Config) ([]Scanline, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	levels := dither(darkness(img, config), config)
+	var lines []Scanline
+	for j, row := range levels {
+		first, last := -1, -1
+		for i, level := range row {
+			if level > 0 {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		// 偶数条自左向右，奇数条自右向左
+		forward := len(lines)%2 == 0
+		line := Scanline{Y: (float64(j) + 0.5) * config.PixelSize}
+		for k := 0; k <= last-first; k++ {
+			i, edge := first+k, first+k
+			if !forward {
+				i, edge = last-k, last-k+1
+			}
+			power := float32(0)
+			if row[i] > 0 {
+				power = float32(math.Round(float64(config.MinPower + float32(row[i])*(config.MaxPower-config.MinPower))))
+			}
+			from := float64(edge) * config.PixelSize
+			to := from + config.PixelSize
+			if !forward {
+				to = from - config.PixelSize
+			}
+			if n := len(line.Segments); n > 0 && line.Segments[n-1].Power == power {
+				line.Segments[n-1].To = to
+				continue
+			}
+			line.Segments = append(line.Segments, Segment{From: from, To: to, Power: power})
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// darkness 将图像缩放到目标像素数并转换为黑度（0 白 1 黑），透明部分视为白色
+func darkness(img image.Image, config Config) [][]float32 {
+	bounds := img.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	if sw == 0 || sh == 0 {
+		return nil
+	}
+	width := sw
+	if config.Width > 0 {
+		width = max(1, int(math.Round(config.Width/config.PixelSize)))
+	}
+	height := max(1, int(math.Round(float64(width)*float64(sh)/float64(sw))))
+	source := make([]float32, sw*sh)
+	for y := 0; y < sh; y++ {
+		for x := 0; x < sw; x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			// 预乘 alpha，叠加到白色背景上
+			white := float64(0xffff - a)
+			lum := (0.299*(float64(r)+white) + 0.587*(float64(g)+white) + 0.114*(float64(b)+white)) / 0xffff
+			dark := float32(1 - lum)
+			if config.Invert {
+				dark = 1 - dark
+			}
+			source[y*sw+x] = dark
+		}
+	}
+	// 按面积取平均缩放，放大时每个目标像素至少取一个源像素
+	span := func(i, n, sn int) (int, int) {
+		from := i * sn / n
+		to := max((i+1)*sn/n, from+1)
+		return from, to
+	}
+	levels := make([][]float32, height)
+	for j := range levels {
+		levels[j] = make([]float32, width)
+		y0, y1 := span(j, height, sh)
+		for i := range levels[j] {
+			x0, x1 := span(i, width, sw)
+			var sum float32
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					sum += source[y*sw+x]
+				}
+			}
+			levels[j][i] = sum / float32((y1-y0)*(x1-x0))
+		}
+	}
+	return levels
+}
+
+// bayer 8x8 Bayer 矩阵
+var bayer = [8][8]float32{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// dither 按配置将黑度转换为输出级别（0~1），0 表示不出光；原地修改
+func dither(levels [][]float32, config Config) [][]float32 {
+	for j, row := range levels {
+		for i, level := range row {
+			var out float32
+			switch config.Dither {
+			case Grayscale:
+				// 忽略接近白色的噪点
+				if level >= 0.01 {
+					out = min(level, 1)
+				}
+			case Threshold:
+				if float64(level) >= config.Threshold {
+					out = 1
+				}
+			case Ordered:
+				if level > (bayer[j%8][i%8]+0.5)/64 {
+					out = 1
+				}
+			case FloydSteinberg:
+				if level >= 0.5 {
+					out = 1
+				}
+				diffuse := func(dx, dy int, weight float32) {
+					x, y := i+dx, j+dy
+					if x >= 0 && x < len(row) && y < len(levels) {
+						levels[y][x] += (level - out) * weight
+					}
+				}
+				diffuse(1, 0, 7.0/16)
+				diffuse(-1, 1, 3.0/16)
+				diffuse(0, 1, 5.0/16)
+				diffuse(1, 1, 1.0/16)
+			}
+			row[i] = out
+		}
+	}
+	return levels
+}
@@ -0,0 +1,187 @@
+package engrave
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot"
+	"github.com/zdypro888/godobot/protocol"
+	"github.com/zdypro888/godobot/simulator"
+)
+
+// testImage 4x4 像素：第 0 行中间两点、第 1 行空白、第 2 行两端、第 3 行全黑
+func testImage() image.Image {
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	rows := []string{".##.", "....", "#..#", "####"}
+	for y, row := range rows {
+		for x, c := range row {
+			img.SetGray(x, y, color.Gray{Y: 255})
+			if c == '#' {
+				img.SetGray(x, y, color.Gray{Y: 0})
+			}
+		}
+	}
+	return img
+}
+
+// testLines testImage 以 1 毫米像素、阈值转换得到的扫描线
+var testLines = []Scanline{
+	{Y: 0.5, Segments: []Segment{{From: 1, To: 3, Power: 100}}},
+	{Y: 2.5, Segments: []Segment{{From: 4, To: 3, Power: 100}, {From: 3, To: 1, Power: 0}, {From: 1, To: 0, Power: 100}}},
+	{Y: 3.5, Segments: []Segment{{From: 0, To: 4, Power: 100}}},
+}
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.PixelSize = 1
+	config.Dither = Threshold
+	config.Overscan = 1
+	config.Origin = [3]float64{220, 20, 0}
+	return config
+}
+
+// TestScanlines 跳过空白行，相邻非空行方向相反，功率相同的相邻像素合并为一段
+func TestScanlines(t *testing.T) {
+	lines, err := Scanlines(testImage(), testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, testLines) {
+		t.Errorf("Scanlines = %+v, want %+v", lines, testLines)
+	}
+}
+
+// TestScanlinesGrayscale 灰度模式的功率在 MinPower 与 MaxPower 之间按黑度插值
+func TestScanlinesGrayscale(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 2, 1))
+	img.SetGray(0, 0, color.Gray{Y: 0})
+	img.SetGray(1, 0, color.Gray{Y: 128})
+	config := testConfig()
+	config.Dither = Grayscale
+	config.MinPower, config.MaxPower = 20, 80
+	lines, err := Scanlines(img, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Scanline{{Y: 0.5, Segments: []Segment{{From: 0, To: 1, Power: 80}, {From: 1, To: 2, Power: 50}}}}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Scanlines = %+v, want %+v", lines, want)
+	}
+}
+
+// TestDither 均匀的 50% 灰度：阈值模式全部出光，有序抖动与误差扩散约一半出光
+func TestDither(t *testing.T) {
+	tests := []struct {
+		dither   Dither
+		min, max int // 出光像素数范围
+	}{
+		{Grayscale, 64, 64},
+		{Threshold, 64, 64},
+		{Ordered, 32, 32},
+		{FloydSteinberg, 30, 34},
+	}
+	for _, test := range tests {
+		levels := make([][]float32, 8)
+		for j := range levels {
+			levels[j] = make([]float32, 8)
+			for i := range levels[j] {
+				levels[j][i] = 0.5
+			}
+		}
+		config := testConfig()
+		config.Dither = test.dither
+		on := 0
+		for _, row := range dither(levels, config) {
+			for _, level := range row {
+				if level < 0 || level > 1 {
+					t.Fatalf("dither %d: level %g out of [0, 1]", test.dither, level)
+				}
+				if test.dither != Grayscale && level != 0 && level != 1 {
+					t.Fatalf("dither %d: level %g, want 0 or 1", test.dither, level)
+				}
+				if level > 0 {
+					on++
+				}
+			}
+		}
+		if on < test.min || on > test.max {
+			t.Errorf("dither %d: %d pixels on, want %d~%d", test.dither, on, test.min, test.max)
+		}
+	}
+}
+
+// TestRunScanlines 在模拟器上雕刻：空走与线间移动关光，经过各段终点，结束后关闭激光
+func TestRunScanlines(t *testing.T) {
+	sim := simulator.New()
+	sim.SetTimeScale(100)
+	if _, err := sim.Listen("engrave-run"); err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Close()
+	dobot := godobot.NewDobot()
+	if err := dobot.Connect("pipe://engrave-run"); err != nil {
+		t.Fatal(err)
+	}
+	defer dobot.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := dobot.SetQueuedCmdStartExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var link bytes.Buffer
+	if err := dobot.StartRecording(&link); err != nil {
+		t.Fatal(err)
+	}
+	config := testConfig()
+	if err := RunScanlines(ctx, dobot, testLines, config); err != nil {
+		t.Fatal(err)
+	}
+	if err := dobot.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 图像坐标 (x, y) 与功率
+	want := [][3]float64{
+		{1, 0.5, 0}, {3, 0.5, 100}, {4, 0.5, 0},
+		{5, 2.5, 0}, {4, 2.5, 0}, {3, 2.5, 100}, {1, 2.5, 0}, {0, 2.5, 100}, {-1, 2.5, 0},
+		{-1, 3.5, 0}, {0, 3.5, 0}, {4, 3.5, 100}, {5, 3.5, 0},
+	}
+	session, err := godobot.ReadRecording(&link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][3]float64
+	for _, record := range session.Records {
+		decoder := protocol.NewDecoder()
+		decoder.Write(record.Data)
+		message, _ := decoder.Next()
+		if record.Direction != godobot.RecordTx || message == nil || message.Id != protocol.ProtocolCPLECmd {
+			continue
+		}
+		var cmd struct {
+			Mode           uint8
+			X, Y, Z, Power float32
+		}
+		if err := binary.Read(bytes.NewReader(message.Params), binary.LittleEndian, &cmd); err != nil {
+			t.Fatal(err)
+		}
+		// 机械臂坐标换算回图像坐标
+		got = append(got, [3]float64{config.Origin[1] - float64(cmd.Y), config.Origin[0] - float64(cmd.X), float64(cmd.Power)})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CPLE commands %v, want %v", got, want)
+	}
+	if pose := sim.Pose(); math.Abs(float64(pose.X)-216.5) > 0.01 || math.Abs(float64(pose.Y)-15) > 0.01 {
+		t.Errorf("ended at %+v, want (216.5, 15)", pose)
+	}
+	if _, on, err := dobot.GetEndEffectorLaser(ctx); err != nil || on {
+		t.Errorf("laser on %v, error %v after engraving", on, err)
+	}
+}
@@ -0,0 +1,98 @@
+package engrave
+
+import (
+	"context"
+	"image"
+
+	"github.com/zdypro888/godobot"
+)
+
+// Run 将图像转换为扫描线并流式发送到指令队列，全部执行完成后返回，见 RunScanlines
+func Run(ctx context.Context, dobot *godobot.Dobot, img image.Image, config Config) error {
+	lines, err := Scanlines(img, config)
+	if err != nil {
+		return err
+	}
+	return RunScanlines(ctx, dobot, lines, config)
+}
+
+// RunScanlines 依次雕刻扫描线：先以 PTP 关光移动到第一条线的空走起点，之后全部以 SetCPLECmd 连续运动，
+// 空走与线间移动功率为 0，结束后关闭激光并等待执行完成。调用前需 SetQueuedCmdStartExec
+func RunScanlines(ctx context.Context, dobot *godobot.Dobot, lines []Scanline, config Config) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	var last *godobot.QueuedCommand
+	queue := func(command godobot.QueuedCommander) error {
+		cmd, err := dobot.QueuedSend(ctx, command)
+		if err != nil {
+			return err
+		}
+		last = cmd
+		return nil
+	}
+	// robot 图像坐标转换为机械臂坐标
+	robot := func(x, y float64) (float32, float32, float32) {
+		return float32(config.Origin[0] - y), float32(config.Origin[1] - x), float32(config.Origin[2])
+	}
+	// SetCPLECmd 不带速度，由 CP 参数限定
+	params := config.CPParams
+	params.JuncitionVel = float32(config.Speed)
+	if err := queue(func(ctx context.Context) (*godobot.QueuedCommand, error) {
+		return dobot.SetCPParams(ctx, &params, true)
+	}); err != nil {
+		return err
+	}
+	cple := func(x, y float64, power float32) error {
+		rx, ry, rz := robot(x, y)
+		return queue(func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return dobot.SetCPLECmd(ctx, uint8(godobot.CPAbsoluteMode), rx, ry, rz, power, true)
+		})
+	}
+	for i, line := range lines {
+		if len(line.Segments) == 0 {
+			continue
+		}
+		first, end := line.Segments[0], line.Segments[len(line.Segments)-1]
+		overscan := config.Overscan
+		if first.To < first.From {
+			overscan = -overscan
+		}
+		startX := first.From - overscan
+		if i == 0 {
+			cmd := &godobot.PTPCmd{PTPMode: godobot.PTPMOVJXYZMode, R: config.R}
+			cmd.X, cmd.Y, cmd.Z = robot(startX, line.Y)
+			if err := queue(func(ctx context.Context) (*godobot.QueuedCommand, error) {
+				return dobot.SetPTPCmd(ctx, cmd, true)
+			}); err != nil {
+				return err
+			}
+		} else if err := cple(startX, line.Y, 0); err != nil {
+			return err
+		}
+		if overscan != 0 {
+			if err := cple(first.From, line.Y, 0); err != nil {
+				return err
+			}
+		}
+		for _, segment := range line.Segments {
+			if err := cple(segment.To, line.Y, segment.Power); err != nil {
+				return err
+			}
+		}
+		if overscan != 0 {
+			if err := cple(end.To+overscan, line.Y, 0); err != nil {
+				return err
+			}
+		}
+	}
+	if err := queue(func(ctx context.Context) (*godobot.QueuedCommand, error) {
+		return dobot.SetEndEffectorLaser(ctx, true, false, true)
+	}); err != nil {
+		return err
+	}
+	return last.Wait(ctx)
+}
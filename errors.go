@@ -0,0 +1,24 @@
+package godobot
+
+import (
+	"github.com/zdypro888/godobot/internal"
+	"github.com/zdypro888/godobot/protocol"
+)
+
+var (
+	ErrLeftSpace    = internal.ErrLeftSpace
+	ErrTimeout      = internal.ErrTimeout      // 重试后仍未收到应答
+	ErrDisconnected = internal.ErrDisconnected // 连接已断开或尚未连接
+	ErrStopped      = internal.ErrStopped      // 等待发送的队列指令因强制停止或清空队列被取消
+	ErrHostStalled  = internal.ErrHostStalled  // 看门狗：调用方长时间未发送指令
+	ErrLinkDegraded = internal.ErrLinkDegraded // 看门狗：长时间未收到应答或连续超时
+)
+
+// AlarmError 设备处于报警状态，可用 errors.As 取得报警位图
+type AlarmError = internal.AlarmError
+
+// ProtocolError 指令交互失败，可用 errors.As 取得出错的指令
+type ProtocolError = protocol.ProtocolError
+
+// FaultError 看门狗触发的故障，Rearm 前拒绝运动指令
+type FaultError = internal.FaultError
@@ -0,0 +1,221 @@
+package godobot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// EventKind 事件类型，可按位组合用于订阅过滤
+type EventKind uint32
+
+const (
+	EventAlarmRaised        EventKind = 1 << iota // 报警触发
+	EventAlarmCleared                             // 报警清除
+	EventPoseSample                               // 位姿采样
+	EventQueueIndexAdvanced                       // 队列索引前进
+	EventMotionFinished                           // 队列运动完成
+	EventDisconnected                             // 连接断开
+
+	EventAll = EventAlarmRaised | EventAlarmCleared | EventPoseSample | EventQueueIndexAdvanced | EventMotionFinished | EventDisconnected
+)
+
+// Event 订阅事件
+type Event interface {
+	Kind() EventKind
+	Time() time.Time
+}
+
+// AlarmRaised 报警触发事件
+type AlarmRaised struct {
+	Record AlarmRecord
+}
+
+func (event *AlarmRaised) Kind() EventKind { return EventAlarmRaised }
+func (event *AlarmRaised) Time() time.Time { return event.Record.Raised }
+
+// AlarmCleared 报警清除事件
+type AlarmCleared struct {
+	Record AlarmRecord
+}
+
+func (event *AlarmCleared) Kind() EventKind { return EventAlarmCleared }
+func (event *AlarmCleared) Time() time.Time { return event.Record.Cleared }
+
+// PoseSample 位姿采样事件
+type PoseSample struct {
+	At   time.Time
+	Pose Pose
+}
+
+func (event *PoseSample) Kind() EventKind { return EventPoseSample }
+func (event *PoseSample) Time() time.Time { return event.At }
+
+// QueueIndexAdvanced 队列当前索引前进事件
+type QueueIndexAdvanced struct {
+	At       time.Time
+	Previous uint64
+	Index    uint64
+}
+
+func (event *QueueIndexAdvanced) Kind() EventKind { return EventQueueIndexAdvanced }
+func (event *QueueIndexAdvanced) Time() time.Time { return event.At }
+
+// MotionFinished 队列运动全部完成事件
+type MotionFinished struct {
+	At time.Time
+}
+
+func (event *MotionFinished) Kind() EventKind { return EventMotionFinished }
+func (event *MotionFinished) Time() time.Time { return event.At }
+
+// Disconnected 连接断开事件，订阅通道随后关闭
+type Disconnected struct {
+	At  time.Time
+	Err error
+}
+
+func (event *Disconnected) Kind() EventKind { return EventDisconnected }
+func (event *Disconnected) Time() time.Time { return event.At }
+
+// 默认订阅参数
+const (
+	DefaultPoseInterval  = 100 * time.Millisecond
+	DefaultQueueInterval = 50 * time.Millisecond
+	DefaultEventBuffer   = 64
+)
+
+// EventFilter 订阅过滤与采样配置，零值订阅全部事件并使用默认间隔
+type EventFilter struct {
+	Kinds         EventKind     // 订阅的事件类型，0 表示全部
+	PoseInterval  time.Duration // 位姿采样间隔
+	QueueInterval time.Duration // 队列索引与运动完成的轮询间隔
+	Buffer        int           // 通道缓冲大小，消费过慢时丢弃新事件
+}
+
+// subscription 一个事件订阅
+type subscription struct {
+	mutex   sync.Mutex
+	closed  bool
+	events  chan Event
+	cancels []func()
+}
+
+// emit 非阻塞投递事件，通道已满时丢弃
+func (sub *subscription) emit(event Event) {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+	if sub.closed {
+		return
+	}
+	select {
+	case sub.events <- event:
+	default:
+	}
+}
+
+// close 取消所有轮询并关闭通道
+func (sub *subscription) close() {
+	for _, cancel := range sub.cancels {
+		cancel()
+	}
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+	sub.closed = true
+	close(sub.events)
+}
+
+// Subscribe 订阅设备事件。轮询由连接统一调度，多个订阅共用同一组总线请求；
+// ctx 结束或连接断开后通道关闭，断开时先投递 Disconnected 事件
+func (dobot *Dobot) Subscribe(ctx context.Context, filter EventFilter) <-chan Event {
+	kinds := filter.Kinds
+	if kinds == 0 {
+		kinds = EventAll
+	}
+	poseInterval := filter.PoseInterval
+	if poseInterval <= 0 {
+		poseInterval = DefaultPoseInterval
+	}
+	queueInterval := filter.QueueInterval
+	if queueInterval <= 0 {
+		queueInterval = DefaultQueueInterval
+	}
+	buffer := filter.Buffer
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+	sub := &subscription{events: make(chan Event, buffer)}
+	conn := dobot.conn
+	select {
+	case <-conn.Done():
+		if kinds&EventDisconnected != 0 {
+			sub.emit(&Disconnected{At: time.Now(), Err: conn.Err()})
+		}
+		sub.close()
+		return sub.events
+	default:
+	}
+	if kinds&(EventAlarmRaised|EventAlarmCleared) != 0 {
+		sub.cancels = append(sub.cancels, conn.WatchAlarms(func(raised, cleared []AlarmRecord) {
+			if kinds&EventAlarmCleared != 0 {
+				for _, record := range cleared {
+					sub.emit(&AlarmCleared{Record: record})
+				}
+			}
+			if kinds&EventAlarmRaised != 0 {
+				for _, record := range raised {
+					sub.emit(&AlarmRaised{Record: record})
+				}
+			}
+		}))
+	}
+	if kinds&EventPoseSample != 0 {
+		sub.cancels = append(sub.cancels, conn.Poll(protocol.ProtocolGetPose, poseInterval, func(message *protocol.Message) {
+			event := &PoseSample{At: time.Now()}
+			if err := message.Read(&event.Pose); err == nil {
+				sub.emit(event)
+			}
+		}))
+	}
+	if kinds&EventQueueIndexAdvanced != 0 {
+		var index uint64
+		started := false
+		sub.cancels = append(sub.cancels, conn.Poll(protocol.ProtocolQueuedCmdCurrentIndex, queueInterval, func(message *protocol.Message) {
+			current := message.Uint64()
+			if started && current > index {
+				sub.emit(&QueueIndexAdvanced{At: time.Now(), Previous: index, Index: current})
+			}
+			index, started = current, true
+		}))
+	}
+	if kinds&EventMotionFinished != 0 {
+		finished := true
+		sub.cancels = append(sub.cancels, conn.Poll(protocol.ProtocolQueuedCmdMotionFinish, queueInterval, func(message *protocol.Message) {
+			current := message.Bool()
+			if current && !finished {
+				sub.emit(&MotionFinished{At: time.Now()})
+			}
+			finished = current
+		}))
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-conn.Done():
+			if kinds&EventDisconnected != 0 {
+				sub.emit(&Disconnected{At: time.Now(), Err: conn.Err()})
+			}
+		}
+		sub.close()
+	}()
+	return sub.events
+}
+
+// SetAlarmPollInterval 设置后台报警轮询间隔，默认 100ms
+func (dobot *Dobot) SetAlarmPollInterval(interval time.Duration) {
+	if dobot.conn != nil {
+		dobot.conn.SetAlarmInterval(interval)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -10,13 +11,13 @@ import (
 )
 
 func main() {
-	robot, err := draw.NewRobot("/dev/cu.usbserial-840", 115200)
+	robot, err := draw.NewRobot(context.Background(), "serial:///dev/cu.usbserial-840?baud=115200")
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 	defer robot.Close()
-	robot.DrawInit()
+	robot.DrawInit(context.Background())
 	// robot.Capture(context.Background(), true)
 
 	notify := make(chan os.Signal, 1)
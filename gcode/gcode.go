@@ -0,0 +1,132 @@
+// Package gcode 解析 G 代码并通过指令队列在 Dobot 上执行，用于笔式绘图与激光雕刻
+//
+// 支持 G0/G1/G2/G3/G4/G17/G20/G21/G54/G90/G91/G92/G94 与 M2/M3/M4/M5/M30，
+// G4 暂停 P 毫秒或 S 秒，吸盘开关的 M 代码见 Config
+package gcode
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrSyntax      = errors.New("syntax error")
+	ErrUnsupported = errors.New("unsupported code")
+	ErrNoFeed      = errors.New("feed rate not set")
+	ErrArc         = errors.New("invalid arc")
+)
+
+// Error 第 Line 行的错误
+type Error struct {
+	Line int
+	Err  error
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("gcode line %d: %v", err.Line, err.Err)
+}
+
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+// Word 一个字，如 G1、X10.5
+type Word struct {
+	Letter byte // 大写字母
+	Value  float64
+}
+
+// Block 一行程序段
+type Block struct {
+	Line  int
+	Words []Word
+}
+
+// Get 取坐标、进给等参数字，同一字母只取第一个
+func (block *Block) Get(letter byte) (float64, bool) {
+	for _, word := range block.Words {
+		if word.Letter == letter {
+			return word.Value, true
+		}
+	}
+	return 0, false
+}
+
+// Has 是否包含代码，如 Has('G', 92)
+func (block *Block) Has(letter byte, code float64) bool {
+	for _, word := range block.Words {
+		if word.Letter == letter && word.Value == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse 解析 G 代码，跳过空行、注释与 % 行，忽略行号 N 与校验 *
+func Parse(r io.Reader) ([]Block, error) {
+	var blocks []Block
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		block, err := parseLine(line, scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		if len(block.Words) > 0 {
+			blocks = append(blocks, block)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// parseLine 解析一行
+func parseLine(line int, text string) (Block, error) {
+	block := Block{Line: line}
+	if i := strings.IndexAny(text, ";*"); i >= 0 {
+		text = text[:i]
+	}
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "%") {
+		return block, nil
+	}
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			end := strings.IndexByte(text[i:], ')')
+			if end < 0 {
+				return block, &Error{Line: line, Err: fmt.Errorf("%w: unclosed comment", ErrSyntax)}
+			}
+			i += end + 1
+		case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+			letter := c &^ 0x20
+			j := i + 1
+			for j < len(text) && text[j] == ' ' {
+				j++
+			}
+			start := j
+			for j < len(text) && strings.IndexByte("+-.0123456789", text[j]) >= 0 {
+				j++
+			}
+			value, err := strconv.ParseFloat(text[start:j], 64)
+			if err != nil {
+				return block, &Error{Line: line, Err: fmt.Errorf("%w: bad value for %c: %q", ErrSyntax, letter, text[start:j])}
+			}
+			if letter != 'N' {
+				block.Words = append(block.Words, Word{Letter: letter, Value: value})
+			}
+			i = j
+		default:
+			return block, &Error{Line: line, Err: fmt.Errorf("%w: unexpected %q", ErrSyntax, c)}
+		}
+	}
+	return block, nil
+}
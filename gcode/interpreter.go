@@ -0,0 +1,439 @@
+package gcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/zdypro888/godobot"
+)
+
+// Config 执行配置
+type Config struct {
+	Offset     [3]float64        // 工件原点在机械臂坐标系中的位置（毫米）
+	R          float32           // 末端旋转角度
+	MaxPower   float64           // S 的满量程，对应激光功率 100%
+	ArcSegment float64           // 激光开启时圆弧离散的弦长（毫米）
+	SuctionOn  float64           // 吸盘开启的 M 代码
+	SuctionOff float64           // 吸盘关闭的 M 代码
+	CPParams   godobot.CPParams  // 激光进给的 CP 参数，JuncitionVel 由进给速度决定
+	ARCParams  godobot.ARCParams // 圆弧参数，XYZVelocity 由进给速度决定
+}
+
+// DefaultConfig 默认配置：S 满量程 1000（与 Grbl 一致），M10/M11 控制吸盘，工件原点需另行设置
+func DefaultConfig() Config {
+	return Config{
+		MaxPower:   1000,
+		ArcSegment: 0.5,
+		SuctionOn:  10,
+		SuctionOff: 11,
+		CPParams:   godobot.CPParams{PlanAcc: 100, AccOrPeriod: 100},
+		ARCParams:  godobot.ARCParams{RVelocity: 100, XYZAcceleration: 200, RAcceleration: 200},
+	}
+}
+
+// Interpreter G 代码解释器，记录模态与当前位置
+type Interpreter struct {
+	dobot    *godobot.Dobot
+	config   Config
+	synced   bool       // 已从设备读取初始位置
+	pos      [3]float64 // 当前位置，程序坐标（毫米）
+	shift    [3]float64 // G92 设定的坐标偏移
+	motion   float64    // 模态运动代码
+	inches   bool
+	relative bool
+	feed     float64 // 进给速度（毫米/分钟）
+	power    float64 // S 值
+	laser    bool    // M3/M4 已开启
+	lit      bool    // 激光正在输出
+	cpFeed   float64 // 已下发的激光进给速度
+	arcFeed  float64 // 已下发的圆弧速度
+	last     *godobot.QueuedCommand
+}
+
+// New 创建解释器
+func New(dobot *godobot.Dobot, config Config) *Interpreter {
+	return &Interpreter{dobot: dobot, config: config}
+}
+
+// Run 解析并执行 G 代码，全部执行完成后返回。调用前需 SetQueuedCmdStartExec
+func (it *Interpreter) Run(ctx context.Context, r io.Reader) error {
+	blocks, err := Parse(r)
+	if err != nil {
+		return err
+	}
+	for i := range blocks {
+		if err := it.Execute(ctx, &blocks[i]); err != nil {
+			return err
+		}
+	}
+	if err := it.extinguish(ctx); err != nil {
+		return err
+	}
+	return it.Wait(ctx)
+}
+
+// Wait 等待已发送的指令执行完成
+func (it *Interpreter) Wait(ctx context.Context) error {
+	return it.last.Wait(ctx)
+}
+
+// Execute 执行一个程序段，运动指令进入队列后即返回
+func (it *Interpreter) Execute(ctx context.Context, block *Block) error {
+	if err := it.execute(ctx, block); err != nil {
+		var lineErr *Error
+		if errors.As(err, &lineErr) {
+			return err
+		}
+		return &Error{Line: block.Line, Err: err}
+	}
+	return nil
+}
+
+// unit 长度单位换算到毫米
+func (it *Interpreter) unit() float64 {
+	if it.inches {
+		return 25.4
+	}
+	return 1
+}
+
+// execute 按 单位与模式、进给与功率、M 代码、暂停、G92、运动 的顺序执行
+func (it *Interpreter) execute(ctx context.Context, block *Block) error {
+	if !it.synced {
+		pose, err := it.dobot.GetPose(ctx)
+		if err != nil {
+			return err
+		}
+		it.pos = [3]float64{float64(pose.X) - it.config.Offset[0], float64(pose.Y) - it.config.Offset[1], float64(pose.Z) - it.config.Offset[2]}
+		it.synced = true
+	}
+	var dwell, setOrigin, end, moved bool
+	for _, word := range block.Words {
+		switch word.Letter {
+		case 'G':
+			switch word.Value {
+			case 0, 1, 2, 3:
+				it.motion = word.Value
+			case 4:
+				dwell = true
+			case 17, 54, 94:
+				// 仅支持 XY 平面、单一工件坐标系与每分钟进给
+			case 20:
+				it.inches = true
+			case 21:
+				it.inches = false
+			case 90:
+				it.relative = false
+			case 91:
+				it.relative = true
+			case 92:
+				setOrigin = true
+			default:
+				return fmt.Errorf("%w: G%g", ErrUnsupported, word.Value)
+			}
+		case 'M':
+			switch word.Value {
+			case 2, 30:
+				end = true
+			case 3, 4, 5, it.config.SuctionOn, it.config.SuctionOff:
+			default:
+				return fmt.Errorf("%w: M%g", ErrUnsupported, word.Value)
+			}
+		case 'X', 'Y', 'Z':
+			moved = true
+		case 'F', 'S', 'I', 'J', 'R', 'P':
+		default:
+			return fmt.Errorf("%w: word %c", ErrUnsupported, word.Letter)
+		}
+	}
+	if feed, ok := block.Get('F'); ok {
+		if feed <= 0 {
+			return fmt.Errorf("%w: F%g", ErrSyntax, feed)
+		}
+		it.feed = feed * it.unit()
+	}
+	if power, ok := block.Get('S'); ok && !dwell {
+		it.power = max(power, 0)
+	}
+	for _, word := range block.Words {
+		if word.Letter != 'M' {
+			continue
+		}
+		var err error
+		switch word.Value {
+		case 3, 4:
+			it.laser = true
+		case 5:
+			it.laser = false
+			err = it.extinguish(ctx)
+		case it.config.SuctionOn, it.config.SuctionOff:
+			suck := word.Value == it.config.SuctionOn
+			err = it.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+				return it.dobot.SetEndEffectorSuctionCup(ctx, true, suck, true)
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if dwell {
+		// P 为毫秒、S 为秒，同时给出时以 S 为准，与 Marlin、RepRap 一致
+		milliseconds, _ := block.Get('P')
+		if seconds, ok := block.Get('S'); ok {
+			milliseconds = seconds * 1000
+		}
+		if milliseconds < 0 || milliseconds > math.MaxUint32 {
+			return fmt.Errorf("%w: G4 dwell %gms", ErrSyntax, milliseconds)
+		}
+		wait := &godobot.WAITCmd{Timeout: uint32(math.Round(milliseconds))}
+		if err := it.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return it.dobot.SetWAITCmd(ctx, wait, true)
+		}); err != nil {
+			return err
+		}
+	}
+	switch {
+	case setOrigin:
+		if err := it.setOrigin(block); err != nil {
+			return err
+		}
+	case moved && !dwell:
+		if err := it.move(ctx, block); err != nil {
+			return err
+		}
+	}
+	if end {
+		return it.extinguish(ctx)
+	}
+	return nil
+}
+
+// setOrigin G92：设定当前位置在程序坐标中的值
+func (it *Interpreter) setOrigin(block *Block) error {
+	found := false
+	for axis, letter := range []byte{'X', 'Y', 'Z'} {
+		if value, ok := block.Get(letter); ok {
+			value *= it.unit()
+			it.shift[axis] += it.pos[axis] - value
+			it.pos[axis] = value
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: G92 without axis", ErrSyntax)
+	}
+	return nil
+}
+
+// target 程序段的目标位置
+func (it *Interpreter) target(block *Block) [3]float64 {
+	target := it.pos
+	for axis, letter := range []byte{'X', 'Y', 'Z'} {
+		if value, ok := block.Get(letter); ok {
+			value *= it.unit()
+			if it.relative {
+				value += it.pos[axis]
+			}
+			target[axis] = value
+		}
+	}
+	return target
+}
+
+// robot 程序坐标换算到机械臂坐标
+func (it *Interpreter) robot(p [3]float64) (x, y, z float32) {
+	return float32(p[0] + it.shift[0] + it.config.Offset[0]),
+		float32(p[1] + it.shift[1] + it.config.Offset[1]),
+		float32(p[2] + it.shift[2] + it.config.Offset[2])
+}
+
+// move 按模态运动代码运动到目标位置
+func (it *Interpreter) move(ctx context.Context, block *Block) error {
+	target := it.target(block)
+	var err error
+	switch it.motion {
+	case 0:
+		if err = it.extinguish(ctx); err != nil {
+			return err
+		}
+		x, y, z := it.robot(target)
+		cmd := &godobot.PTPCmd{PTPMode: godobot.PTPMOVJXYZMode, X: x, Y: y, Z: z, R: it.config.R}
+		err = it.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return it.dobot.SetPTPCmd(ctx, cmd, true)
+		})
+	case 1:
+		err = it.line(ctx, target)
+	case 2, 3:
+		err = it.arc(ctx, block, it.motion == 2, target)
+	}
+	if err != nil {
+		return err
+	}
+	it.pos = target
+	return nil
+}
+
+// burning 进给时是否输出激光
+func (it *Interpreter) burning() bool {
+	return it.laser && it.power > 0
+}
+
+// line 直线进给，激光开启时以 SetCPLECmd 带功率运动
+func (it *Interpreter) line(ctx context.Context, target [3]float64) error {
+	if it.feed <= 0 {
+		return ErrNoFeed
+	}
+	x, y, z := it.robot(target)
+	if !it.burning() {
+		if err := it.extinguish(ctx); err != nil {
+			return err
+		}
+		cmd := &godobot.CPCmd{CPMode: godobot.CPAbsoluteMode, X: x, Y: y, Z: z, Velocity: float32(it.feed / 60)}
+		return it.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return it.dobot.SetCPCmd(ctx, cmd, true)
+		})
+	}
+	// SetCPLECmd 不带速度，由 CP 参数限定
+	if it.cpFeed != it.feed {
+		params := it.config.CPParams
+		params.JuncitionVel = float32(min(it.feed/60, 500))
+		if err := it.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return it.dobot.SetCPParams(ctx, &params, true)
+		}); err != nil {
+			return err
+		}
+		it.cpFeed = it.feed
+	}
+	power := float32(min(it.power/it.config.MaxPower, 1) * 100)
+	if err := it.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+		return it.dobot.SetCPLECmd(ctx, uint8(godobot.CPAbsoluteMode), x, y, z, power, true)
+	}); err != nil {
+		return err
+	}
+	it.lit = true
+	return nil
+}
+
+// arc 圆弧进给，激光开启时离散为带功率的直线，否则以 SetARCCmd 运动，超过半圆时分段
+func (it *Interpreter) arc(ctx context.Context, block *Block, clockwise bool, target [3]float64) error {
+	if it.feed <= 0 {
+		return ErrNoFeed
+	}
+	start := it.pos
+	var cx, cy float64
+	if r, ok := block.Get('R'); ok {
+		r *= it.unit()
+		dx, dy := target[0]-start[0], target[1]-start[1]
+		chord := math.Hypot(dx, dy)
+		if chord == 0 || chord > 2*math.Abs(r)+1e-6 {
+			return fmt.Errorf("%w: radius %g cannot reach end point", ErrArc, r)
+		}
+		// 圆心在弦的垂直平分线上，R 为负时取大于半圆的一侧
+		h := math.Sqrt(max(r*r-chord*chord/4, 0))
+		side := 1.0
+		if clockwise != (r < 0) {
+			side = -1
+		}
+		cx = (start[0]+target[0])/2 - side*h*dy/chord
+		cy = (start[1]+target[1])/2 + side*h*dx/chord
+	} else {
+		i, iok := block.Get('I')
+		j, jok := block.Get('J')
+		if !iok && !jok {
+			return fmt.Errorf("%w: missing I, J or R", ErrArc)
+		}
+		cx, cy = start[0]+i*it.unit(), start[1]+j*it.unit()
+	}
+	radius := math.Hypot(start[0]-cx, start[1]-cy)
+	if radius == 0 || math.Abs(math.Hypot(target[0]-cx, target[1]-cy)-radius) > max(0.01, radius*1e-3) {
+		return fmt.Errorf("%w: end point not on circle", ErrArc)
+	}
+	a0 := math.Atan2(start[1]-cy, start[0]-cx)
+	sweep := math.Atan2(target[1]-cy, target[0]-cx) - a0
+	if clockwise {
+		for sweep >= 0 {
+			sweep -= 2 * math.Pi
+		}
+	} else {
+		for sweep <= 0 {
+			sweep += 2 * math.Pi
+		}
+	}
+	point := func(t float64) [3]float64 {
+		a := a0 + sweep*t
+		return [3]float64{cx + radius*math.Cos(a), cy + radius*math.Sin(a), start[2] + (target[2]-start[2])*t}
+	}
+	if it.burning() {
+		n := max(1, int(math.Ceil(math.Abs(sweep)*radius/it.config.ArcSegment)))
+		for k := 1; k <= n; k++ {
+			p := target
+			if k < n {
+				p = point(float64(k) / float64(n))
+			}
+			if err := it.line(ctx, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := it.extinguish(ctx); err != nil {
+		return err
+	}
+	if it.arcFeed != it.feed {
+		params := it.config.ARCParams
+		params.XYZVelocity = float32(min(it.feed/60, 500))
+		if err := it.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return it.dobot.SetARCParams(ctx, &params, true)
+		}); err != nil {
+			return err
+		}
+		it.arcFeed = it.feed
+	}
+	pieces := 1
+	if math.Abs(sweep) > math.Pi {
+		pieces = 2
+	}
+	for k := 0; k < pieces; k++ {
+		end := target
+		if k < pieces-1 {
+			end = point(float64(k+1) / float64(pieces))
+		}
+		cmd := &godobot.ARCCmd{}
+		cmd.CirPoint.X, cmd.CirPoint.Y, cmd.CirPoint.Z = it.robot(point((float64(k) + 0.5) / float64(pieces)))
+		cmd.ToPoint.X, cmd.ToPoint.Y, cmd.ToPoint.Z = it.robot(end)
+		cmd.CirPoint.R, cmd.ToPoint.R = it.config.R, it.config.R
+		if err := it.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return it.dobot.SetARCCmd(ctx, cmd, true)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extinguish 关闭正在输出的激光
+func (it *Interpreter) extinguish(ctx context.Context) error {
+	if !it.lit {
+		return nil
+	}
+	if err := it.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+		return it.dobot.SetEndEffectorLaser(ctx, true, false, true)
+	}); err != nil {
+		return err
+	}
+	it.lit = false
+	return nil
+}
+
+// queue 发送队列指令，队列满时等待
+func (it *Interpreter) queue(ctx context.Context, command godobot.QueuedCommander) error {
+	cmd, err := it.dobot.QueuedSend(ctx, command)
+	if err != nil {
+		return err
+	}
+	it.last = cmd
+	return nil
+}
@@ -0,0 +1,155 @@
+package gcode_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot"
+	"github.com/zdypro888/godobot/gcode"
+	"github.com/zdypro888/godobot/internal/golden"
+	"github.com/zdypro888/godobot/protocol"
+	"github.com/zdypro888/godobot/simulator"
+)
+
+// TestGolden 在模拟器上执行 testdata 中的 G 代码，与 .golden 文件中下发的队列指令比较
+func TestGolden(t *testing.T) {
+	golden.Run(t, "*.gcode", func(t *testing.T, file string) string {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return run(t, filepath.Base(file), src)
+	})
+}
+
+// run 以工件原点 (200, 0, 0) 执行 src，返回下发的队列指令与执行结果
+func run(t *testing.T, name string, src []byte) string {
+	sim := simulator.New()
+	defer sim.Close()
+	sim.SetTimeScale(100)
+	if _, err := sim.Listen("gcode-" + name); err != nil {
+		t.Fatal(err)
+	}
+	dobot := godobot.NewDobot()
+	var recording bytes.Buffer
+	if err := dobot.StartRecording(&recording); err != nil {
+		t.Fatal(err)
+	}
+	if err := dobot.Connect("pipe://gcode-" + name); err != nil {
+		t.Fatal(err)
+	}
+	defer dobot.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := dobot.SetQueuedCmdStartExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	config := gcode.DefaultConfig()
+	config.Offset = [3]float64{200, 0, 0}
+	result := gcode.New(dobot, config).Run(ctx, bytes.NewReader(src))
+	if err := dobot.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	writeCommands(t, &out, &recording)
+	var lineErr *gcode.Error
+	switch {
+	case result == nil:
+		out.WriteString("ok\n")
+	case errors.As(result, &lineErr):
+		fmt.Fprintf(&out, "error: %v\n", result)
+	default:
+		t.Fatalf("Run: %v", result)
+	}
+	return out.String()
+}
+
+// writeCommands 按发送顺序写出记录中的队列指令
+func writeCommands(t *testing.T, out *strings.Builder, recording *bytes.Buffer) {
+	rec, err := godobot.ReadRecording(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range rec.Records {
+		if record.Direction != godobot.RecordTx {
+			continue
+		}
+		decoder := protocol.NewDecoder()
+		decoder.Write(record.Data)
+		message, err := decoder.Next()
+		if err != nil || message == nil {
+			t.Fatalf("bad frame % X", record.Data)
+		}
+		if !message.IsQueued || !message.RW {
+			continue
+		}
+		writeCommand(t, out, message)
+	}
+}
+
+func writeCommand(t *testing.T, out *strings.Builder, message *protocol.Message) {
+	switch message.Id {
+	case protocol.ProtocolPTPCmd:
+		var cmd godobot.PTPCmd
+		read(t, message, &cmd)
+		fmt.Fprintf(out, "PTP mode %d %s\n", cmd.PTPMode, point(cmd.X, cmd.Y, cmd.Z))
+	case protocol.ProtocolCPCmd:
+		var cmd godobot.CPCmd
+		read(t, message, &cmd)
+		fmt.Fprintf(out, "CP %s v %.3f\n", point(cmd.X, cmd.Y, cmd.Z), cmd.Velocity)
+	case protocol.ProtocolCPLECmd:
+		var cmd struct {
+			Mode           uint8
+			X, Y, Z, Power float32
+		}
+		read(t, message, &cmd)
+		fmt.Fprintf(out, "CPLE %s power %.1f\n", point(cmd.X, cmd.Y, cmd.Z), cmd.Power)
+	case protocol.ProtocolCPParams:
+		var params godobot.CPParams
+		read(t, message, &params)
+		fmt.Fprintf(out, "CPParams junction %.3f\n", params.JuncitionVel)
+	case protocol.ProtocolARCParams:
+		var params godobot.ARCParams
+		read(t, message, &params)
+		fmt.Fprintf(out, "ARCParams v %.3f\n", params.XYZVelocity)
+	case protocol.ProtocolARCCmd:
+		var cmd godobot.ARCCmd
+		read(t, message, &cmd)
+		fmt.Fprintf(out, "ARC via %s to %s\n", point(cmd.CirPoint.X, cmd.CirPoint.Y, cmd.CirPoint.Z), point(cmd.ToPoint.X, cmd.ToPoint.Y, cmd.ToPoint.Z))
+	case protocol.ProtocolWAITCmd:
+		var cmd godobot.WAITCmd
+		read(t, message, &cmd)
+		fmt.Fprintf(out, "WAIT %dms\n", cmd.Timeout)
+	case protocol.ProtocolEndEffectorLaser:
+		fmt.Fprintf(out, "laser % X\n", message.Params)
+	case protocol.ProtocolEndEffectorSuctionCup:
+		fmt.Fprintf(out, "suction % X\n", message.Params)
+	default:
+		fmt.Fprintf(out, "id %d % X\n", message.Id, message.Params)
+	}
+}
+
+func read(t *testing.T, message *protocol.Message, data any) {
+	if err := message.Read(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// point 坐标保留三位小数，-0 写作 0
+func point(x, y, z float32) string {
+	format := func(v float32) string {
+		s := fmt.Sprintf("%.3f", v)
+		if s == "-0.000" {
+			return "0.000"
+		}
+		return s
+	}
+	return "(" + format(x) + ", " + format(y) + ", " + format(z) + ")"
+}
@@ -0,0 +1,9 @@
+; 由 R 求圆心：R 为正取小于半圆的一侧，为负取大于半圆的一侧并分为两段
+G21 G90
+G0 X0 Y0 Z0
+G2 X10 Y10 R10 F600
+G3 X20 Y0 R10
+G2 X0 Y0 R-15
+; I、J 给出圆心，整圆分为两段
+G3 X0 Y0 I10 J0
+G2 X10 Y0 I5
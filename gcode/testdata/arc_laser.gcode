@@ -0,0 +1,6 @@
+; 激光开启时圆弧离散为带功率的直线
+G0 X0 Y0 Z0
+M3 S500
+G2 X2 Y0 R1 F300
+M5
+G0 X0 Y0
@@ -0,0 +1,3 @@
+; 半径不足以连接起点与终点
+G0 X0 Y0 Z0
+G2 X30 Y0 R10 F600
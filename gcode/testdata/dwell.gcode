@@ -0,0 +1,10 @@
+; G4 P 为毫秒、S 为秒，G4 的 S 不改变激光功率
+G0 X0 Y0 Z0
+M3 S500
+G1 X10 F600
+G4 P250
+G4 S1.5
+G4 P100 S0.02
+G1 X20
+M5
+G4
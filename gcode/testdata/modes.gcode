@@ -0,0 +1,17 @@
+; G91 相对坐标、G92 设定坐标偏移、G20 英寸
+G90 G0 X0 Y0 Z0
+G91
+G1 X10 F1200
+G1 X10 Y-5
+G1 Z5
+G90
+G92 X0 Y0
+G1 X5 Y5
+G92 Z10
+G0 Z10
+G20
+G1 X1 Y0 F10
+G21 G91
+G0 X-5 Y-5
+G90 G92 X0 Y0 Z0
+G0 X0 Y0 Z0
@@ -0,0 +1,2 @@
+G0 X0 Y0 Z0
+G1 X10 (unclosed comment
@@ -0,0 +1,3 @@
+G0 X0 Y0 Z0
+G1 X10 F600
+G18
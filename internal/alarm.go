@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AlarmCategory 报警类别
+type AlarmCategory uint8
+
+const (
+	AlarmCategoryUnknown   AlarmCategory = iota // 未定义
+	AlarmCategoryCommon                         // 公共报警
+	AlarmCategoryPlan                           // 规划报警
+	AlarmCategoryMove                           // 运动报警
+	AlarmCategoryOverSpeed                      // 超速报警
+	AlarmCategoryLimit                          // 限位报警
+	AlarmCategoryLostStep                       // 丢步报警
+	AlarmCategoryDriver                         // 驱动报警
+	AlarmCategoryMotor                          // 电机报警
+)
+
+func (category AlarmCategory) String() string {
+	switch category {
+	case AlarmCategoryCommon:
+		return "common"
+	case AlarmCategoryPlan:
+		return "plan"
+	case AlarmCategoryMove:
+		return "move"
+	case AlarmCategoryOverSpeed:
+		return "overspeed"
+	case AlarmCategoryLimit:
+		return "limit"
+	case AlarmCategoryLostStep:
+		return "lost step"
+	case AlarmCategoryDriver:
+		return "driver"
+	case AlarmCategoryMotor:
+		return "motor"
+	}
+	return "unknown"
+}
+
+// Alarm 报警定义
+type Alarm struct {
+	Code        uint8
+	Name        string
+	Category    AlarmCategory
+	Description string
+	Recovery    string // 建议的恢复方法
+}
+
+func (alarm *Alarm) String() string {
+	return fmt.Sprintf("%s (0x%02X)", alarm.Name, alarm.Code)
+}
+
+const (
+	recoveryClear      = "clear the alarm with ClearAllAlarmsState"
+	recoveryTarget     = "clear the alarm and send a target inside the workspace"
+	recoveryJog        = "clear the alarm and jog the joint back inside its range"
+	recoveryParams     = "clear the alarm and lower velocity or acceleration"
+	recoveryHome       = "clear the alarm and run the homing procedure"
+	recoveryPowerCycle = "power cycle the arm; contact support if it persists"
+	recoveryCooldown   = "stop motion and let the motor cool down before resuming"
+)
+
+var alarmTable = map[uint8]*Alarm{}
+
+func init() {
+	define := func(code uint8, category AlarmCategory, name, description, recovery string) {
+		alarmTable[code] = &Alarm{Code: code, Name: name, Category: category, Description: description, Recovery: recovery}
+	}
+	define(0x00, AlarmCategoryCommon, "reset", "the controller has been reset", recoveryClear)
+	define(0x01, AlarmCategoryCommon, "undefined instruction", "the controller received an unknown instruction", recoveryClear)
+	define(0x02, AlarmCategoryCommon, "file system error", "the controller file system is damaged", recoveryPowerCycle)
+	define(0x03, AlarmCategoryCommon, "MCU/FPGA communication failure", "communication between MCU and FPGA failed", recoveryPowerCycle)
+	define(0x04, AlarmCategoryCommon, "angle sensor error", "reading the angle sensor failed", recoveryPowerCycle)
+	define(0x10, AlarmCategoryPlan, "planning kinematic singularity", "the target point is a kinematic singularity", recoveryTarget)
+	define(0x11, AlarmCategoryPlan, "planning target unreachable", "inverse kinematics has no solution for the target point", recoveryTarget)
+	define(0x12, AlarmCategoryPlan, "planning joint limit", "the target point exceeds a joint limit", recoveryTarget)
+	define(0x13, AlarmCategoryPlan, "repeated point", "the same point was pushed repeatedly", recoveryClear)
+	define(0x14, AlarmCategoryPlan, "invalid arc parameters", "the arc points cannot form an arc", recoveryTarget)
+	define(0x15, AlarmCategoryPlan, "invalid jump parameters", "the jump height or z limit is invalid", recoveryTarget)
+	define(0x20, AlarmCategoryMove, "kinematic singularity", "the path passes through a kinematic singularity", recoveryTarget)
+	define(0x21, AlarmCategoryMove, "path unreachable", "inverse kinematics failed during interpolation", recoveryTarget)
+	define(0x22, AlarmCategoryMove, "path joint limit", "the path exceeds a joint limit during interpolation", recoveryTarget)
+	for joint := uint8(0); joint < 4; joint++ {
+		define(0x30+joint, AlarmCategoryOverSpeed, fmt.Sprintf("joint %d overspeed", joint+1), fmt.Sprintf("joint %d exceeded its maximum speed", joint+1), recoveryParams)
+		define(0x40+joint*2, AlarmCategoryLimit, fmt.Sprintf("joint %d upper limit", joint+1), fmt.Sprintf("joint %d reached its positive limit", joint+1), recoveryJog)
+		define(0x41+joint*2, AlarmCategoryLimit, fmt.Sprintf("joint %d lower limit", joint+1), fmt.Sprintf("joint %d reached its negative limit", joint+1), recoveryJog)
+		define(0x50+joint, AlarmCategoryLostStep, fmt.Sprintf("joint %d lost step", joint+1), fmt.Sprintf("joint %d lost steps, the pose is no longer reliable", joint+1), recoveryHome)
+		define(0x60+joint, AlarmCategoryDriver, fmt.Sprintf("joint %d driver alarm", joint+1), fmt.Sprintf("the joint %d motor driver reported a fault", joint+1), recoveryPowerCycle)
+		define(0x64+joint, AlarmCategoryDriver, fmt.Sprintf("joint %d position overflow", joint+1), fmt.Sprintf("the joint %d position counter overflowed", joint+1), recoveryHome)
+		define(0x68+joint, AlarmCategoryDriver, fmt.Sprintf("joint %d following error", joint+1), fmt.Sprintf("joint %d deviates too far from the commanded position", joint+1), recoveryParams)
+		define(0x70+joint, AlarmCategoryMotor, fmt.Sprintf("joint %d motor over-temperature", joint+1), fmt.Sprintf("the joint %d motor is overheating", joint+1), recoveryCooldown)
+	}
+	define(0x48, AlarmCategoryLimit, "joint 2/3 upper limit", "the rear and fore arm combination reached its positive limit", recoveryJog)
+	define(0x49, AlarmCategoryLimit, "joint 2/3 lower limit", "the rear and fore arm combination reached its negative limit", recoveryJog)
+}
+
+// LookupAlarm 查询报警定义，未定义的报警码返回 AlarmCategoryUnknown 类别的定义
+func LookupAlarm(code uint8) *Alarm {
+	if alarm, ok := alarmTable[code]; ok {
+		return alarm
+	}
+	return &Alarm{Code: code, Name: "unknown alarm", Category: AlarmCategoryUnknown, Description: "alarm code is not documented", Recovery: recoveryClear}
+}
+
+// AlarmSet 报警位图，报警码 N 对应第 N/8 字节的第 N%8 位
+type AlarmSet []uint8
+
+// NewAlarmSet 由设备返回的位图创建报警集合
+func NewAlarmSet(state []uint8) AlarmSet {
+	return append(AlarmSet(nil), state...)
+}
+
+// Has 是否存在指定报警
+func (set AlarmSet) Has(code uint8) bool {
+	index := int(code / 8)
+	return index < len(set) && set[index]&(1<<(code%8)) != 0
+}
+
+// Empty 是否没有报警
+func (set AlarmSet) Empty() bool {
+	for _, value := range set {
+		if value != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Codes 所有置位的报警码
+func (set AlarmSet) Codes() []uint8 {
+	var codes []uint8
+	for i, value := range set {
+		for bit := 0; bit < 8; bit++ {
+			if value&(1<<bit) != 0 {
+				codes = append(codes, uint8(i*8+bit))
+			}
+		}
+	}
+	return codes
+}
+
+// Alarms 所有置位报警的定义
+func (set AlarmSet) Alarms() []*Alarm {
+	var alarms []*Alarm
+	for _, code := range set.Codes() {
+		alarms = append(alarms, LookupAlarm(code))
+	}
+	return alarms
+}
+
+// Diff 与旧集合比较，返回新触发与已清除的报警码
+func (set AlarmSet) Diff(old AlarmSet) (raised, cleared []uint8) {
+	for _, code := range set.Codes() {
+		if !old.Has(code) {
+			raised = append(raised, code)
+		}
+	}
+	for _, code := range old.Codes() {
+		if !set.Has(code) {
+			cleared = append(cleared, code)
+		}
+	}
+	return raised, cleared
+}
+
+func (set AlarmSet) String() string {
+	var names []string
+	for _, alarm := range set.Alarms() {
+		names = append(names, alarm.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// AlarmRecord 报警记录
+type AlarmRecord struct {
+	Alarm   *Alarm
+	Raised  time.Time
+	Cleared time.Time // 零值表示尚未清除
+}
+
+// Active 报警是否仍然存在
+func (record *AlarmRecord) Active() bool {
+	return record.Cleared.IsZero()
+}
+
+// AlarmHistory 报警历史环形缓冲区，记录每个报警的触发与清除时间
+type AlarmHistory struct {
+	mutex   sync.Mutex
+	records []*AlarmRecord
+	next    int
+	full    bool
+	active  map[uint8]*AlarmRecord
+}
+
+// NewAlarmHistory 创建容量为 capacity 的报警历史
+func NewAlarmHistory(capacity int) *AlarmHistory {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &AlarmHistory{records: make([]*AlarmRecord, capacity), active: map[uint8]*AlarmRecord{}}
+}
+
+// Update 以最新的报警集合更新历史，返回本次触发与清除的记录
+func (history *AlarmHistory) Update(set AlarmSet, now time.Time) (raised, cleared []AlarmRecord) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	for code, record := range history.active {
+		if !set.Has(code) {
+			record.Cleared = now
+			delete(history.active, code)
+			cleared = append(cleared, *record)
+		}
+	}
+	for _, code := range set.Codes() {
+		if _, ok := history.active[code]; ok {
+			continue
+		}
+		record := &AlarmRecord{Alarm: LookupAlarm(code), Raised: now}
+		history.active[code] = record
+		history.records[history.next] = record
+		history.next = (history.next + 1) % len(history.records)
+		if history.next == 0 {
+			history.full = true
+		}
+		raised = append(raised, *record)
+	}
+	return raised, cleared
+}
+
+// Records 按触发时间从早到晚返回历史记录的副本
+func (history *AlarmHistory) Records() []AlarmRecord {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	var records []AlarmRecord
+	if history.full {
+		for _, record := range history.records[history.next:] {
+			records = append(records, *record)
+		}
+	}
+	for _, record := range history.records[:history.next] {
+		records = append(records, *record)
+	}
+	return records
+}
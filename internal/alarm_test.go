@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestAlarmSet 报警码 N 对应第 N/8 字节的第 N%8 位，未定义的报警码归为未知类别
+func TestAlarmSet(t *testing.T) {
+	set := NewAlarmSet([]uint8{0x01, 0, 0x06, 0, 0, 0, 0, 0, 0x01, 0x80})
+	if codes := set.Codes(); !reflect.DeepEqual(codes, []uint8{0x00, 0x11, 0x12, 0x40, 0x4F}) {
+		t.Errorf("Codes = %#v", codes)
+	}
+	if !set.Has(0x12) || set.Has(0x10) || set.Has(0xFF) {
+		t.Error("Has does not match the bitmap")
+	}
+	tests := []struct {
+		code     uint8
+		category AlarmCategory
+	}{
+		{0x00, AlarmCategoryCommon},
+		{0x11, AlarmCategoryPlan},
+		{0x12, AlarmCategoryPlan},
+		{0x40, AlarmCategoryLimit},
+		{0x4F, AlarmCategoryUnknown},
+	}
+	for i, alarm := range set.Alarms() {
+		if alarm.Code != tests[i].code || alarm.Category != tests[i].category || alarm.Recovery == "" {
+			t.Errorf("alarm %d = %+v, want code 0x%02X category %v", i, alarm, tests[i].code, tests[i].category)
+		}
+	}
+	raised, cleared := NewAlarmSet([]uint8{0, 0, 0x04}).Diff(NewAlarmSet([]uint8{0, 0, 0x02}))
+	if !reflect.DeepEqual(raised, []uint8{0x12}) || !reflect.DeepEqual(cleared, []uint8{0x11}) {
+		t.Errorf("Diff raised %v cleared %v, want [0x12] [0x11]", raised, cleared)
+	}
+	if !NewAlarmSet(make([]uint8, 16)).Empty() || set.Empty() {
+		t.Error("Empty does not match the bitmap")
+	}
+}
+
+// TestAlarmHistory 报警持续期间只记录一次，清除时填写清除时间；超出容量时丢弃最早的记录
+func TestAlarmHistory(t *testing.T) {
+	history := NewAlarmHistory(2)
+	base := time.Now()
+	at := func(seconds int) time.Time { return base.Add(time.Duration(seconds) * time.Second) }
+	steps := []struct {
+		set             AlarmSet
+		raised, cleared int
+	}{
+		{NewAlarmSet([]uint8{0, 0, 0x02}), 1, 0}, // 0x11
+		{NewAlarmSet([]uint8{0, 0, 0x02}), 0, 0},
+		{NewAlarmSet([]uint8{0, 0, 0x04}), 1, 1}, // 0x11 清除，0x12 触发
+		{NewAlarmSet([]uint8{0x01}), 1, 1},       // 0x12 清除，0x00 触发
+	}
+	for i, step := range steps {
+		raised, cleared := history.Update(step.set, at(i))
+		if len(raised) != step.raised || len(cleared) != step.cleared {
+			t.Fatalf("step %d: raised %d cleared %d, want %d %d", i, len(raised), len(cleared), step.raised, step.cleared)
+		}
+	}
+	records := history.Records()
+	if len(records) != 2 {
+		t.Fatalf("%d records, want 2", len(records))
+	}
+	if first := records[0]; first.Alarm.Code != 0x12 || !first.Raised.Equal(at(2)) || !first.Cleared.Equal(at(3)) || first.Active() {
+		t.Errorf("first record %+v, want 0x12 raised at 2s cleared at 3s", first)
+	}
+	if last := records[1]; last.Alarm.Code != 0x00 || !last.Raised.Equal(at(3)) || !last.Active() {
+		t.Errorf("last record %+v, want active 0x00 raised at 3s", last)
+	}
+}
@@ -1,340 +1,408 @@
 package internal
 
 import (
-	"bufio"
-	"bytes"
-	"encoding/binary"
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"math"
-	"net"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"go.bug.st/serial"
+	"github.com/zdypro888/godobot/protocol"
 )
 
-const (
-	SyncByte       = 0xAA
-	MaxPayloadSize = SyncByte - 1 // 确保payload不大于SYNC_BYTE
-)
-
-var ErrLeftSpace = errors.New("left space is not enough")
-
 // Packet 负载结构
 type MessageAck struct {
-	Message *Message
+	Message *protocol.Message
 	Error   error
 }
 
-// Message 用于协议通信的消息结构
-type Message struct {
-	Id       ProtocolId // 原 id
-	RW       bool       // 原 rw
-	IsQueued bool       // 原 isQueued
-	Params   []byte
-	AckLen   uint8
-}
-
-func (message *Message) Ctrl() uint8 {
-	ctrl := uint8(0)
-	if message.RW {
-		ctrl |= 0x01
-	}
-	if message.IsQueued {
-		ctrl |= 0x02
-	}
-	return ctrl
-}
-
-func (message *Message) SetCtrl(ctrl uint8) {
-	if ctrl&0x01 != 0 {
-		message.RW = true
-	}
-	if (ctrl>>1)&0x01 != 0 {
-		message.IsQueued = true
-	}
-}
-
-func (message *Message) Data() []byte {
-	return message.Params[:message.AckLen]
-}
-
-func (message *Message) Reader() io.Reader {
-	return bytes.NewReader(message.Params)
-}
-
-func (message *Message) Read(data any) error {
-	return binary.Read(message.Reader(), binary.LittleEndian, data)
-}
-
-func (message *Message) Bool() bool {
-	return message.Params[0] != 0
-}
-
-func (message *Message) Uint16() uint16 {
-	return binary.LittleEndian.Uint16(message.Params)
+type outMessage struct {
+	*protocol.Message
+	ctx  context.Context
+	done chan *MessageAck
 }
 
-func (message *Message) Uint32() uint32 {
-	return binary.LittleEndian.Uint32(message.Params)
+func (outmsg *outMessage) Reply(message *protocol.Message) {
+	outmsg.done <- &MessageAck{Message: message, Error: nil}
 }
 
-func (message *Message) Uint64() uint64 {
-	return binary.LittleEndian.Uint64(message.Params)
+func (outmsg *outMessage) Error(err error) {
+	outmsg.done <- &MessageAck{Message: nil, Error: err}
 }
 
-func (message *Message) Float32() float32 {
-	return math.Float32frombits(binary.LittleEndian.Uint32(message.Params))
-}
+const (
+	alarmHistorySize  = 64                     // 报警历史保留的记录数
+	AlarmPollInterval = 100 * time.Millisecond // 默认报警轮询间隔
+)
 
-func (message *Message) Float64() float64 {
-	return math.Float64frombits(binary.LittleEndian.Uint64(message.Params))
-}
+// AlarmWatcher 报警变化回调，在收发协程中调用，不可阻塞
+type AlarmWatcher func(raised, cleared []AlarmRecord)
 
-type outMessage struct {
-	*Message
-	done chan *MessageAck
+type Connector struct {
+	Error          error
+	alarmMutex     sync.Mutex
+	alarms         AlarmSet
+	history        *AlarmHistory
+	alarmWatchers  map[int]AlarmWatcher
+	alarmWatcherId int
+	alarmPoll      func()
+	scheduler      *scheduler
+	portMutex      sync.Mutex
+	port           Transport
+	reopen         func() (Transport, error)
+	policy         *ReconnectPolicy
+	session        *session
+	recorder       atomic.Pointer[Recorder]
+	recevieError   chan error
+	recevieMessage chan *protocol.Message
+	stopReceive    chan struct{}
+	sendingMessage chan *outMessage
+	urgentMessage  chan *outMessage
+	closed         chan struct{}
+	shutdown       chan struct{}
+	shutdownOnce   sync.Once
+	pipeline       *pipeline
+	counters       linkCounters
+	watchdog       watchdog
+	tracker        tracker
 }
 
-func (outmsg *outMessage) Reply(message *Message) {
-	if outmsg.done != nil {
-		outmsg.done <- &MessageAck{Message: message, Error: nil}
+// Open 按地址打开传输层并启动收发协程，断线重连时按同一地址重新打开
+func (connector *Connector) Open(address string) error {
+	connector.reopen = func() (Transport, error) {
+		return OpenTransport(address)
+	}
+	transport, err := connector.reopen()
+	if err != nil {
+		return err
 	}
+	connector.Attach(transport)
+	return nil
 }
 
-func (outmsg *outMessage) Error(err error) {
-	if outmsg.done != nil {
-		outmsg.done <- &MessageAck{Message: nil, Error: err}
+// Attach 使用已打开的传输层启动收发协程
+func (connector *Connector) Attach(transport Transport) {
+	connector.sendingMessage = make(chan *outMessage)
+	connector.urgentMessage = make(chan *outMessage)
+	connector.closed = make(chan struct{})
+	connector.shutdown = make(chan struct{})
+	connector.session = newSession()
+	connector.start(transport)
+	if connector.history == nil {
+		connector.history = NewAlarmHistory(alarmHistorySize)
+	}
+	if connector.scheduler == nil {
+		connector.scheduler = newScheduler()
+		connector.SetAlarmInterval(AlarmPollInterval)
 	}
+	go connector.processGoRoutine()
 }
 
-func (outmsg *outMessage) Close() {
-	if outmsg.done != nil {
-		close(outmsg.done)
-		outmsg.done = nil
-	}
+// start 在新的传输层上启动接收协程
+func (connector *Connector) start(transport Transport) {
+	transport = &tappedTransport{Transport: transport, recorder: connector.recorder.Load}
+	connector.portMutex.Lock()
+	connector.port = transport
+	connector.portMutex.Unlock()
+	connector.recevieError = make(chan error, 1)
+	connector.recevieMessage = make(chan *protocol.Message, replyBuffer)
+	connector.stopReceive = make(chan struct{})
+	connector.pipeline = newPipeline()
+	go connector.receiveGoRoutine(transport, connector.recevieMessage, connector.recevieError, connector.stopReceive)
 }
 
-type Connector struct {
-	Alarms         []uint8
-	Error          error
-	port           io.ReadWriteCloser
-	recevieError   chan error
-	recevieMessage chan *Message
-	sendingMessage chan *outMessage
-	leftSpace      uint32
+// SetRecorder 记录之后收发的所有数据，nil 停止记录；返回之前的记录器
+func (connector *Connector) SetRecorder(recorder *Recorder) *Recorder {
+	return connector.recorder.Swap(recorder)
 }
 
-func (connector *Connector) Open(name string, baudrate uint32) error {
-	if !strings.HasPrefix(name, "/dev/") {
-		udpAddr, err := net.ResolveUDPAddr("udp", name)
-		if err != nil {
-			return err
-		}
-		conn, err := net.DialUDP("udp", nil, udpAddr)
-		if err != nil {
-			return err
-		}
-		connector.port = conn
-	} else {
-		if baudrate == 0 {
-			baudrate = 115200
-		}
-		mode := &serial.Mode{
-			BaudRate: int(baudrate),
-			DataBits: 8,
-			Parity:   serial.NoParity,
-			StopBits: serial.OneStopBit,
-		}
-		serialPort, err := serial.Open(name, mode)
-		if err != nil {
-			return err
-		}
-		connector.port = serialPort
-	}
-	connector.recevieError = make(chan error)
-	connector.recevieMessage = make(chan *Message)
-	connector.sendingMessage = make(chan *outMessage)
-	connector.leftSpace = 0
-	go connector.receiveGoRoutine()
-	go connector.processGoRoutine()
-	return nil
+// stop 关闭当前传输层并结束接收协程
+func (connector *Connector) stop() {
+	close(connector.stopReceive)
+	connector.portMutex.Lock()
+	connector.port.Close()
+	connector.portMutex.Unlock()
 }
 
 func (connector *Connector) Close() error {
+	if connector == nil || connector.shutdown == nil {
+		return nil
+	}
+	connector.shutdownOnce.Do(func() {
+		close(connector.shutdown)
+	})
+	connector.portMutex.Lock()
+	defer connector.portMutex.Unlock()
 	return connector.port.Close()
 }
 
-func (connector *Connector) writeMessage(message *Message) error {
-	var checksum uint8
-	ctrl := message.Ctrl()
-	checksum += uint8(message.Id)
-	checksum += ctrl
-	for _, v := range message.Params {
-		checksum += v
-	}
-	checksum = uint8(0) - checksum
-	var err error
-	if _, err = connector.port.Write([]byte{byte(SyncByte), byte(SyncByte), byte(len(message.Params) + 2)}); err != nil {
-		return err
-	}
-	if _, err = connector.port.Write([]byte{byte(message.Id), byte(ctrl)}); err != nil {
-		return err
-	}
-	if _, err = connector.port.Write(message.Params); err != nil {
-		return err
+func (connector *Connector) writeMessage(message *protocol.Message) error {
+	frame := protocol.Encode(message)
+	if frame == nil {
+		return &protocol.FrameError{Err: protocol.ErrBadLength, Data: message.Params}
 	}
-	if _, err = connector.port.Write([]byte{byte(checksum)}); err != nil {
-		return err
+	if _, err := connector.port.Write(frame); err != nil {
+		return disconnected(err)
 	}
 	return nil
 }
 
-func (connector *Connector) sendMessage(message *Message) (*Message, error) {
+// disconnected 包装传输层错误
+func disconnected(err error) error {
+	if err == nil || errors.Is(err, ErrDisconnected) {
+		return ErrDisconnected
+	}
+	return fmt.Errorf("%w: %w", ErrDisconnected, err)
+}
+
+// exchange 同步发送并等待应答，仅在收发循环之外使用（如重连后恢复参数）
+func (connector *Connector) exchange(ctx context.Context, message *protocol.Message) (*protocol.Message, error) {
 	var err error
-	const maxRetries = 3
-	for retry := 0; retry < maxRetries; retry++ {
+	for retry := 0; retry < maxAttempts; retry++ {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
 		if err = connector.writeMessage(message); err != nil {
 			return nil, err
 		}
-		select {
-		case ack := <-connector.recevieMessage:
-			if ack.Id == message.Id {
-				if ack.Id == ProtocolQueuedCmdLeftSpace {
-					connector.leftSpace = binary.LittleEndian.Uint32(ack.Params)
+		timeout := time.NewTimer(replyTimeout)
+	wait:
+		for {
+			select {
+			case ack := <-connector.recevieMessage:
+				if ack.Id == message.Id {
+					timeout.Stop()
+					return ack, nil
 				}
-				return ack, nil
+				connector.counters.unmatched.Add(1)
+			case err = <-connector.recevieError:
+				timeout.Stop()
+				return nil, disconnected(err)
+			case <-timeout.C:
+				break wait
+			case <-ctx.Done():
+				timeout.Stop()
+				return nil, ctx.Err()
 			}
-			// 非预期消息，丢弃。TODO: 是否要判断丢弃几个？
-		case <-time.After(3 * time.Second):
-			// 超时
 		}
 	}
-	return nil, nil
+	return nil, &protocol.ProtocolError{Id: message.Id, Err: ErrTimeout}
 }
 
-func (connector *Connector) receiveGoRoutine() {
+func (connector *Connector) receiveGoRoutine(port Transport, messages chan<- *protocol.Message, errs chan<- error, stop <-chan struct{}) {
 	var err error
-	reader := bufio.NewReader(connector.port)
+	reader := protocol.NewReader(port)
 	for {
-		var sbyte byte
-		if sbyte, err = reader.ReadByte(); err != nil {
-			break
-		}
-		if sbyte != SyncByte {
-			continue
-		}
-		if sbyte, err = reader.ReadByte(); err != nil {
-			break
-		}
-		if sbyte != SyncByte {
-			continue
-		}
-		// PayloadLen
-		if sbyte, err = reader.ReadByte(); err != nil {
+		var message *protocol.Message
+		if message, err = reader.ReadMessage(); err != nil {
+			var frameErr *protocol.FrameError
+			if errors.As(err, &frameErr) {
+				// 损坏的帧，丢弃后继续同步
+				continue
+			}
 			break
 		}
-		if sbyte >= SyncByte {
-			continue
+		select {
+		case messages <- message:
+		case <-stop:
+			return
 		}
-		// id ctrl params + checksum
-		var data []byte
-		if data, err = reader.Peek(int(sbyte) + 1); err != nil {
+	}
+	errs <- err
+}
+
+func (connector *Connector) processGoRoutine() {
+	var err error
+	for {
+		err = connector.serve()
+		if err = connector.reconnect(err); err != nil {
 			break
 		}
-		var checksum uint8
-		for _, v := range data {
-			checksum += v
-		}
-		if checksum != 0 {
-			if _, err = reader.Discard(3); err != nil {
-				break
-			}
-			continue
-		}
-		var idbyte, ctrlbyte uint8
-		idbyte, _ = reader.ReadByte()
-		ctrlbyte, _ = reader.ReadByte()
-		message := &Message{}
-		message.Id = ProtocolId(idbyte)
-		message.SetCtrl(ctrlbyte)
-		message.Params = make([]byte, MaxPayloadSize-2)
-		message.AckLen = sbyte - 2
-		if message.AckLen > 0 {
-			reader.Read(message.Params[:message.AckLen])
-		}
-		reader.ReadByte()
-		connector.recevieMessage <- message
 	}
-	connector.recevieError <- err
+	connector.Error = err
+	close(connector.closed)
+	connector.discard(err)
 }
 
-func (connector *Connector) processGoRoutine() {
+// serve 处理收发直到连接不可用
+func (connector *Connector) serve() error {
 	var err error
-	breakfor := false
-	alarmTicker := time.NewTicker(100 * time.Millisecond)
-	defer alarmTicker.Stop()
-	for !breakfor {
+	ticker := time.NewTicker(pollTick)
+	defer ticker.Stop()
+	for err == nil {
+		// 紧急指令优先于其他任何事件
 		select {
-		case <-alarmTicker.C:
-			alarmStateGet := &Message{Id: ProtocolAlarmsState, RW: false, IsQueued: false}
-			var alarmState *Message
-			if alarmState, err = connector.sendMessage(alarmStateGet); err != nil {
-				breakfor = true
-			} else if alarmState != nil {
-				connector.Alarms = alarmState.Data()
+		case outmsg := <-connector.urgentMessage:
+			err = connector.preempt(outmsg)
+			continue
+		default:
+		}
+		select {
+		case outmsg := <-connector.urgentMessage:
+			err = connector.preempt(outmsg)
+		case now := <-ticker.C:
+			if err = connector.expire(now); err == nil {
+				if err = connector.watch(now); err == nil {
+					err = connector.poll(now)
+				}
 			}
+		case message := <-connector.recevieMessage:
+			err = connector.dispatch(message)
 		case err = <-connector.recevieError:
-			breakfor = true
+			err = disconnected(err)
 		case outmsg := <-connector.sendingMessage:
-			for i, alarm := range connector.Alarms {
-				if alarm != 0 {
-					outmsg.Error(fmt.Errorf("alarm: %d-%d", i, alarm))
-					break
-				}
-			}
-			var ack *Message
-			for {
-				if !outmsg.IsQueued || connector.leftSpace > 0 {
-					// 非队列消息直接发送
-					if ack, err = connector.sendMessage(outmsg.Message); err != nil {
-						breakfor = true
-						outmsg.Error(err)
-					} else if ack != nil {
-						if outmsg.IsQueued {
-							connector.leftSpace--
-						}
-						outmsg.Reply(ack)
-					} else {
-						outmsg.Error(errors.New("send message timeout max retries"))
-					}
-					break
-				}
-				cmdGetLeftSpace := &Message{Id: ProtocolQueuedCmdLeftSpace, RW: false, IsQueued: false}
-				if ack, err = connector.sendMessage(cmdGetLeftSpace); err != nil {
-					breakfor = true
-					outmsg.Error(err)
-					break
-				}
-				if ack != nil {
-					connector.leftSpace = binary.LittleEndian.Uint32(ack.Params)
-					if connector.leftSpace == 0 {
-						outmsg.Error(ErrLeftSpace)
-						break
-					}
-				}
-			}
+			err = connector.submit(outmsg)
 		}
 	}
-	connector.Error = err
+	connector.abort(err)
+	return err
+}
+
+// Poll 以 interval 间隔轮询读指令 id，应答在收发协程中交给 handle，handle 不可阻塞。
+// 多个订阅共用同一次请求，返回的函数取消订阅
+func (connector *Connector) Poll(id protocol.ProtocolId, interval time.Duration, handle func(*protocol.Message)) func() {
+	return connector.scheduler.add(id, interval, handle)
+}
+
+// SetAlarmInterval 设置报警轮询间隔
+func (connector *Connector) SetAlarmInterval(interval time.Duration) {
+	connector.alarmMutex.Lock()
+	cancel := connector.alarmPoll
+	connector.alarmPoll = connector.Poll(protocol.ProtocolAlarmsState, interval, func(message *protocol.Message) {
+		connector.updateAlarms(NewAlarmSet(message.Data()))
+	})
+	connector.alarmMutex.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// updateAlarms 记录轮询到的报警状态并通知订阅者
+func (connector *Connector) updateAlarms(alarms AlarmSet) {
+	raised, cleared := connector.history.Update(alarms, time.Now())
+	connector.alarmMutex.Lock()
+	connector.alarms = alarms
+	var watchers []AlarmWatcher
+	if len(raised) > 0 || len(cleared) > 0 {
+		for _, watcher := range connector.alarmWatchers {
+			watchers = append(watchers, watcher)
+		}
+	}
+	connector.alarmMutex.Unlock()
+	for _, watcher := range watchers {
+		watcher(raised, cleared)
+	}
+}
+
+// WatchAlarms 订阅报警触发与清除，返回的函数取消订阅
+func (connector *Connector) WatchAlarms(watcher AlarmWatcher) func() {
+	connector.alarmMutex.Lock()
+	defer connector.alarmMutex.Unlock()
+	if connector.alarmWatchers == nil {
+		connector.alarmWatchers = map[int]AlarmWatcher{}
+	}
+	connector.alarmWatcherId++
+	id := connector.alarmWatcherId
+	connector.alarmWatchers[id] = watcher
+	return func() {
+		connector.alarmMutex.Lock()
+		delete(connector.alarmWatchers, id)
+		connector.alarmMutex.Unlock()
+	}
 }
 
-func (connector *Connector) SendMessage(message *Message) (*Message, error) {
-	outmsg := &outMessage{Message: message, done: make(chan *MessageAck)}
-	defer outmsg.Close()
-	connector.sendingMessage <- outmsg
-	ack := <-outmsg.done
-	return ack.Message, ack.Error
+// Done 连接断开后关闭的通道，未连接时返回已关闭的通道
+func (connector *Connector) Done() <-chan struct{} {
+	if connector == nil || connector.closed == nil {
+		closed := make(chan struct{})
+		close(closed)
+		return closed
+	}
+	return connector.closed
+}
+
+// Err 连接断开的原因，连接正常时返回 nil
+func (connector *Connector) Err() error {
+	select {
+	case <-connector.Done():
+		if connector == nil || connector.Error == nil {
+			return ErrDisconnected
+		}
+		return connector.Error
+	default:
+		return nil
+	}
+}
+
+// Alarms 最近一次轮询到的报警状态
+func (connector *Connector) Alarms() AlarmSet {
+	if connector == nil {
+		return nil
+	}
+	connector.alarmMutex.Lock()
+	defer connector.alarmMutex.Unlock()
+	return NewAlarmSet(connector.alarms)
+}
+
+// AlarmHistory 报警历史记录，按触发时间排序
+func (connector *Connector) AlarmHistory() []AlarmRecord {
+	if connector == nil || connector.history == nil {
+		return nil
+	}
+	return connector.history.Records()
+}
+
+// refusedOnAlarm 报警状态下拒绝的指令：队列指令与非队列运动指令，点动用于脱离限位不受限制
+func refusedOnAlarm(message *protocol.Message) bool {
+	if message.IsQueued {
+		return true
+	}
+	if !message.RW {
+		return false
+	}
+	switch message.Id {
+	case protocol.ProtocolHOMECmd, protocol.ProtocolPTPCmd, protocol.ProtocolPTPWithLCmd,
+		protocol.ProtocolPTPPOCmd, protocol.ProtocolPTPPOWithLCmd, protocol.ProtocolCPCmd,
+		protocol.ProtocolCPLECmd, protocol.ProtocolARCCmd, protocol.ProtocolCircleCmd:
+		return true
+	}
+	return false
+}
+
+// urgent 停止与清空队列指令走优先通道，越过等待中的请求立即发送
+func urgent(message *protocol.Message) bool {
+	if message.IsQueued || !message.RW {
+		return false
+	}
+	switch message.Id {
+	case protocol.ProtocolQueuedCmdStopExec, protocol.ProtocolQueuedCmdForceStopExec, protocol.ProtocolQueuedCmdClear:
+		return true
+	}
+	return false
+}
+
+// SendMessage 发送消息并等待应答，ctx 取消时立即返回 ctx.Err()
+func (connector *Connector) SendMessage(ctx context.Context, message *protocol.Message) (*protocol.Message, error) {
+	if connector == nil || connector.closed == nil {
+		return nil, ErrDisconnected
+	}
+	outmsg := &outMessage{Message: message, ctx: ctx, done: make(chan *MessageAck, 1)}
+	sending := connector.sendingMessage
+	if urgent(message) {
+		sending = connector.urgentMessage
+	}
+	select {
+	case sending <- outmsg:
+	case <-connector.closed:
+		return nil, connector.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case ack := <-outmsg.done:
+		return ack.Message, ack.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// device 管道另一端的设备桩：队列指令返回递增的索引，读队列索引返回当前索引，队列空间总是充足，其余指令原样应答参数
+type device struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	conns    []net.Conn
+	frames   []*protocol.Message
+	silent   map[protocol.ProtocolId]bool // 不应答的指令
+	index    uint64
+}
+
+func newDevice(t *testing.T, name string) *device {
+	t.Helper()
+	listener, err := ListenPipe(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := &device{listener: listener, silent: map[protocol.ProtocolId]bool{}}
+	t.Cleanup(func() {
+		listener.Close()
+		dev.drop()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			dev.mutex.Lock()
+			dev.conns = append(dev.conns, conn)
+			dev.mutex.Unlock()
+			go dev.serve(conn)
+		}
+	}()
+	return dev
+}
+
+func (dev *device) serve(conn net.Conn) {
+	reader := protocol.NewReader(conn)
+	for {
+		message, err := reader.ReadMessage()
+		if err != nil {
+			return
+		}
+		dev.mutex.Lock()
+		dev.frames = append(dev.frames, message)
+		silent := dev.silent[message.Id]
+		reply := &protocol.Message{Id: message.Id, RW: message.RW, IsQueued: message.IsQueued, Params: message.Params}
+		switch {
+		case message.IsQueued:
+			dev.index++
+			reply.Params = binary.LittleEndian.AppendUint64(nil, dev.index)
+		case message.Id == protocol.ProtocolQueuedCmdCurrentIndex:
+			reply.Params = binary.LittleEndian.AppendUint64(nil, dev.index)
+		case message.Id == protocol.ProtocolQueuedCmdLeftSpace:
+			reply.Params = binary.LittleEndian.AppendUint32(nil, 32)
+		case message.Id == protocol.ProtocolAlarmsState:
+			reply.Params = make([]byte, 16)
+		}
+		dev.mutex.Unlock()
+		if silent {
+			continue
+		}
+		if _, err := conn.Write(protocol.Encode(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// setSilent 设置指令是否应答
+func (dev *device) setSilent(id protocol.ProtocolId, silent bool) {
+	dev.mutex.Lock()
+	dev.silent[id] = silent
+	dev.mutex.Unlock()
+}
+
+// received 收到的指定指令帧
+func (dev *device) received(id protocol.ProtocolId) []*protocol.Message {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+	var frames []*protocol.Message
+	for _, frame := range dev.frames {
+		if frame.Id == id {
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+// drop 断开所有连接
+func (dev *device) drop() {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+	for _, conn := range dev.conns {
+		conn.Close()
+	}
+	dev.conns = nil
+}
+
+// connectDevice 启动设备桩并连接，测试结束时关闭
+func connectDevice(t *testing.T, name string) (*Connector, *device) {
+	t.Helper()
+	dev := newDevice(t, name)
+	connector := &Connector{}
+	if err := connector.Open("pipe://" + name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { connector.Close() })
+	return connector, dev
+}
+
+// TestSendMessageContext ctx 到期时立即返回而不等待重发超时，已取消的请求不再发送，连接仍可继续使用
+func TestSendMessageContext(t *testing.T) {
+	connector, dev := connectDevice(t, "send-context")
+	dev.setSilent(protocol.ProtocolGetPose, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := connector.SendMessage(ctx, &protocol.Message{Id: protocol.ProtocolGetPose}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unanswered request: %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > replyTimeout/2 {
+		t.Errorf("returned after %v, want soon after the deadline", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := connector.SendMessage(cancelled, &protocol.Message{Id: protocol.ProtocolDeviceName}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled request: %v, want canceled", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	reply, err := connector.SendMessage(ctx, &protocol.Message{Id: protocol.ProtocolDeviceSN})
+	if err != nil || reply.Id != protocol.ProtocolDeviceSN {
+		t.Fatalf("request after cancellation: %+v, %v", reply, err)
+	}
+	if frames := dev.received(protocol.ProtocolDeviceName); len(frames) != 0 {
+		t.Errorf("cancelled request sent %d times", len(frames))
+	}
+}
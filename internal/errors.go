@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrLeftSpace    = errors.New("left space is not enough")
+	ErrTimeout      = errors.New("reply timeout")
+	ErrDisconnected = errors.New("disconnected")
+	ErrStopped      = errors.New("queue force stopped")
+	ErrHostStalled  = errors.New("host stopped sending commands")
+	ErrLinkDegraded = errors.New("link degraded")
+)
+
+// AlarmError 设备处于报警状态，拒绝运动指令
+type AlarmError struct {
+	Alarms AlarmSet // 报警位图
+}
+
+// Codes 返回所有置位的报警码
+func (err *AlarmError) Codes() []uint8 {
+	return err.Alarms.Codes()
+}
+
+func (err *AlarmError) Error() string {
+	return "alarm: " + err.Alarms.String()
+}
+
+// FaultError 看门狗触发后拒绝运动指令，Rearm 后恢复
+type FaultError struct {
+	Cause error     // ErrHostStalled 或 ErrLinkDegraded
+	At    time.Time // 触发时间
+}
+
+func (err *FaultError) Error() string {
+	return "watchdog fault: " + err.Cause.Error()
+}
+
+func (err *FaultError) Unwrap() error {
+	return err.Cause
+}
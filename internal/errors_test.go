@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// broken 写入总是失败的传输层
+type broken struct{}
+
+func (broken) Read(p []byte) (int, error)  { return 0, io.EOF }
+func (broken) Write(p []byte) (int, error) { return 0, io.ErrClosedPipe }
+func (broken) Close() error                { return nil }
+
+// TestErrors 各类失败以可用 errors.Is 与 errors.As 区分的错误返回
+func TestErrors(t *testing.T) {
+	t.Run("alarm", func(t *testing.T) {
+		connector, w := newTestConnector()
+		connector.pipeline.leftSpace = 5
+		connector.alarms = NewAlarmSet([]uint8{0, 0, 0x02}) // 0x11 规划逆解报警
+		cmd := request(protocol.ProtocolPTPCmd, true, true)
+		if err := connector.submit(cmd); err != nil {
+			t.Fatal(err)
+		}
+		var alarmErr *AlarmError
+		if ack := answered(cmd); ack == nil || !errors.As(ack.Error, &alarmErr) {
+			t.Fatalf("queued command answered with %+v, want AlarmError", ack)
+		}
+		if codes := alarmErr.Codes(); len(codes) != 1 || codes[0] != 0x11 {
+			t.Errorf("alarm codes %v, want [0x11]", codes)
+		}
+		// 读指令不受报警影响
+		if err := connector.submit(request(protocol.ProtocolGetPose, false, false)); err != nil {
+			t.Fatal(err)
+		}
+		if len(w.frames) != 1 || w.frames[0].Id != protocol.ProtocolGetPose {
+			t.Errorf("sent %d frames, want only the pose query", len(w.frames))
+		}
+	})
+	t.Run("timeout", func(t *testing.T) {
+		connector, _ := newTestConnector()
+		pose := request(protocol.ProtocolGetPose, false, false)
+		if err := connector.submit(pose); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			now = now.Add(replyTimeout)
+			if err := connector.expire(now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var protocolErr *protocol.ProtocolError
+		ack := answered(pose)
+		if ack == nil || !errors.Is(ack.Error, ErrTimeout) || !errors.As(ack.Error, &protocolErr) || protocolErr.Id != protocol.ProtocolGetPose {
+			t.Fatalf("answered with %+v, want ProtocolError for GetPose wrapping ErrTimeout", ack)
+		}
+	})
+	t.Run("disconnected", func(t *testing.T) {
+		connector := &Connector{port: broken{}, pipeline: newPipeline(), session: newSession()}
+		pose := request(protocol.ProtocolGetPose, false, false)
+		if err := connector.submit(pose); !errors.Is(err, ErrDisconnected) {
+			t.Errorf("submit: %v, want ErrDisconnected", err)
+		}
+		if ack := answered(pose); ack == nil || !errors.Is(ack.Error, ErrDisconnected) || !errors.Is(ack.Error, io.ErrClosedPipe) {
+			t.Errorf("answered with %+v, want ErrDisconnected wrapping the transport error", ack)
+		}
+		if _, err := (&Connector{}).SendMessage(context.Background(), pose.Message); !errors.Is(err, ErrDisconnected) {
+			t.Errorf("not connected: %v, want ErrDisconnected", err)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+// Package golden 测试输出与 testdata 中 .golden 文件的比较，go test -update 时改写 golden 文件
+package golden
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// Run 对 testdata 中匹配 pattern 的每个文件运行子测试，output 的结果与去掉扩展名的同名 .golden 文件比较
+func Run(t *testing.T, pattern string, output func(t *testing.T, file string) string) {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("testdata", pattern))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no testdata matches %s", pattern)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			got := output(t, file)
+			Compare(t, strings.TrimSuffix(file, filepath.Ext(file))+".golden", got)
+		})
+	}
+}
+
+// Compare 与 golden 文件 name 比较，-update 时改写
+func Compare(t *testing.T, name, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(name, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (run with -update to inspect)\n--- got\n%s--- want\n%s", name, got, want)
+	}
+}
@@ -0,0 +1,321 @@
+package internal
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+const (
+	replyTimeout  = 3 * time.Second        // 单次发送等待应答的时间
+	urgentTimeout = 200 * time.Millisecond // 紧急指令单次等待应答的时间，最坏 urgentTimeout*maxAttempts 内得到结果
+	maxAttempts   = 3                      // 同一请求最多发送次数
+	maxInFlight   = 8                      // 同时在途的请求数，受设备接收缓冲限制
+	// replyBuffer 应答通道缓冲，容纳全部在途请求的应答，
+	// 避免同步传输层（如 pipe）上收发协程写入时接收协程阻塞
+	replyBuffer = 4 * maxInFlight
+)
+
+// LinkStats 链路统计
+type LinkStats struct {
+	Sent        uint64 // 发送的帧数，含重发
+	Received    uint64 // 收到的应答数
+	Retransmits uint64 // 超时重发次数
+	Timeouts    uint64 // 重发后仍无应答的请求数
+	Unmatched   uint64 // 没有对应请求的应答数
+}
+
+// linkCounters 链路统计计数器
+type linkCounters struct {
+	sent, received, retransmits, timeouts, unmatched atomic.Uint64
+}
+
+// pending 等待发送或等待应答的请求
+type pending struct {
+	message  *protocol.Message
+	outmsg   *outMessage             // 调用方请求，内部请求为 nil
+	handle   func(*protocol.Message) // 内部请求的应答处理
+	sentAt   time.Time
+	timeout  time.Duration // 单次等待应答的时间
+	attempts int
+	queued   uint64 // 发送时已发出的队列指令数，用于修正剩余空间
+}
+
+// cancelled 调用方已放弃等待
+func (p *pending) cancelled() bool {
+	return p.outmsg != nil && p.outmsg.ctx.Err() != nil
+}
+
+// reply 应答请求方
+func (p *pending) reply(message *protocol.Message) {
+	if p.outmsg != nil {
+		p.outmsg.Reply(message)
+	} else if p.handle != nil {
+		p.handle(message)
+	}
+}
+
+// fail 以错误应答请求方，内部请求以 nil 应答
+func (p *pending) fail(err error) {
+	if p.outmsg != nil {
+		p.outmsg.Error(err)
+	} else if p.handle != nil {
+		p.handle(nil)
+	}
+}
+
+// pipeline 在途请求表：同一指令的应答按发送顺序对应，
+// 不同指令可同时在途，队列指令在设备剩余空间内流水发送
+type pipeline struct {
+	outstanding map[protocol.ProtocolId][]*pending
+	inFlight    int
+	ready       []*pending // 等待发送，按提交顺序
+	leftSpace   uint32     // 设备队列剩余空间的估计
+	queuedSent  uint64     // 已发送的队列指令数
+	spaceQuery  bool       // 内部剩余空间查询在途
+	polling     map[protocol.ProtocolId]bool
+}
+
+func newPipeline() *pipeline {
+	return &pipeline{outstanding: map[protocol.ProtocolId][]*pending{}, polling: map[protocol.ProtocolId]bool{}}
+}
+
+// submit 提交调用方请求
+func (connector *Connector) submit(outmsg *outMessage) error {
+	connector.watchdog.host(outmsg.Message, time.Now())
+	if fault := connector.watchdog.refused(outmsg.Message); fault != nil {
+		outmsg.Error(fault)
+		return nil
+	}
+	if refusedOnAlarm(outmsg.Message) {
+		if alarms := connector.Alarms(); !alarms.Empty() {
+			outmsg.Error(&AlarmError{Alarms: alarms})
+			return nil
+		}
+	}
+	connector.pipeline.ready = append(connector.pipeline.ready, &pending{message: outmsg.Message, outmsg: outmsg, timeout: replyTimeout})
+	return connector.drain()
+}
+
+// preempt 立即发送紧急指令，不受在途数量限制；
+// 强制停止与清空队列同时取消等待剩余空间的队列指令
+func (connector *Connector) preempt(outmsg *outMessage) error {
+	connector.watchdog.host(outmsg.Message, time.Now())
+	switch outmsg.Id {
+	case protocol.ProtocolQueuedCmdForceStopExec, protocol.ProtocolQueuedCmdClear:
+		connector.pipeline.rejectQueued(ErrStopped)
+	}
+	if err := connector.transmit(&pending{message: outmsg.Message, outmsg: outmsg, timeout: urgentTimeout}); err != nil {
+		return err
+	}
+	if outmsg.Id == protocol.ProtocolQueuedCmdClear {
+		// 已入队的指令被清除，不会再执行
+		connector.discard(ErrStopped)
+	}
+	return connector.drain()
+}
+
+// poll 提交到期的轮询请求，上一次轮询尚未应答的指令本轮跳过
+func (connector *Connector) poll(now time.Time) error {
+	line := connector.pipeline
+	for _, id := range connector.scheduler.due(now) {
+		if line.polling[id] {
+			continue
+		}
+		line.polling[id] = true
+		message := &protocol.Message{Id: id, RW: false, IsQueued: false}
+		line.ready = append(line.ready, &pending{message: message, timeout: replyTimeout, handle: func(reply *protocol.Message) {
+			delete(line.polling, id)
+			if reply != nil {
+				connector.scheduler.deliver(reply, now)
+			}
+		}})
+	}
+	return connector.drain()
+}
+
+// drain 在在途数量与队列空间允许时发送等待中的请求，返回的错误表示连接已不可用
+func (connector *Connector) drain() error {
+	line := connector.pipeline
+	var blocked []*pending
+	for len(line.ready) > 0 && line.inFlight < maxInFlight {
+		p := line.ready[0]
+		line.ready = line.ready[1:]
+		if p.cancelled() {
+			p.fail(p.outmsg.ctx.Err())
+			continue
+		}
+		if p.message.IsQueued {
+			if len(blocked) > 0 || line.leftSpace == 0 {
+				// 队列指令保持提交顺序，等待剩余空间
+				blocked = append(blocked, p)
+				continue
+			}
+			line.leftSpace--
+			line.queuedSent++
+		}
+		if err := connector.transmit(p); err != nil {
+			line.ready = append(blocked, line.ready...)
+			return err
+		}
+	}
+	line.ready = append(blocked, line.ready...)
+	if len(blocked) > 0 && !line.spaceQuery {
+		line.spaceQuery = true
+		query := &pending{message: &protocol.Message{Id: protocol.ProtocolQueuedCmdLeftSpace, RW: false, IsQueued: false}, timeout: replyTimeout}
+		query.handle = func(reply *protocol.Message) {
+			line.spaceQuery = false
+			if reply != nil && line.leftSpace == 0 {
+				line.rejectQueued(ErrLeftSpace)
+			}
+		}
+		// 剩余空间查询不受在途数量限制，避免队列指令占满在途表时无法恢复
+		return connector.transmit(query)
+	}
+	return nil
+}
+
+// rejectQueued 以 err 应答等待中的队列指令：
+// 设备队列已满时为 ErrLeftSpace 由调用方重试，强制停止时为 ErrStopped
+func (line *pipeline) rejectQueued(err error) {
+	ready := line.ready[:0]
+	for _, p := range line.ready {
+		if p.message.IsQueued {
+			p.fail(err)
+		} else {
+			ready = append(ready, p)
+		}
+	}
+	line.ready = ready
+}
+
+// transmit 发送请求并登记到在途表
+func (connector *Connector) transmit(p *pending) error {
+	line := connector.pipeline
+	if err := connector.writeMessage(p.message); err != nil {
+		if p.attempts > 0 {
+			// 重发的请求仍在在途表中，由 abort 应答
+			return err
+		}
+		p.fail(err)
+		if errors.Is(err, ErrDisconnected) {
+			return err
+		}
+		return nil
+	}
+	connector.counters.sent.Add(1)
+	p.sentAt = time.Now()
+	p.attempts++
+	p.queued = line.queuedSent
+	if p.attempts == 1 {
+		line.outstanding[p.message.Id] = append(line.outstanding[p.message.Id], p)
+		line.inFlight++
+	}
+	return nil
+}
+
+// dispatch 将应答交给对应指令最早发送的请求
+func (connector *Connector) dispatch(message *protocol.Message) error {
+	line := connector.pipeline
+	waiters := line.outstanding[message.Id]
+	if len(waiters) == 0 {
+		connector.counters.unmatched.Add(1)
+		return nil
+	}
+	connector.counters.received.Add(1)
+	p := waiters[0]
+	connector.remove(p)
+	connector.watchdog.replied(p.message, message, time.Now())
+	if message.Id == protocol.ProtocolQueuedCmdLeftSpace {
+		// 应答之后发出的队列指令尚未计入设备的剩余空间
+		space := int64(message.Uint32()) - int64(line.queuedSent-p.queued)
+		line.leftSpace = uint32(max(space, 0))
+	}
+	if p.message.RW {
+		connector.session.record(p.message)
+	}
+	p.reply(message)
+	return connector.drain()
+}
+
+// remove 从在途表移除请求
+func (connector *Connector) remove(p *pending) {
+	line := connector.pipeline
+	waiters := line.outstanding[p.message.Id]
+	for i, waiter := range waiters {
+		if waiter == p {
+			waiters = append(waiters[:i:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(line.outstanding, p.message.Id)
+	} else {
+		line.outstanding[p.message.Id] = waiters
+	}
+	line.inFlight--
+}
+
+// expire 处理超时的请求：同一指令仅有一个在途时重发，否则无法区分迟到的应答，直接失败
+func (connector *Connector) expire(now time.Time) error {
+	line := connector.pipeline
+	var expired []*pending
+	// 按检查前的在途数判断，同批失败的请求移除后剩下的一个也不重发
+	shared := map[protocol.ProtocolId]bool{}
+	for id, waiters := range line.outstanding {
+		shared[id] = len(waiters) > 1
+		for _, p := range waiters {
+			if now.Sub(p.sentAt) >= p.timeout {
+				expired = append(expired, p)
+			}
+		}
+	}
+	for _, p := range expired {
+		if !shared[p.message.Id] && p.attempts < maxAttempts && !p.cancelled() {
+			connector.counters.retransmits.Add(1)
+			if err := connector.transmit(p); err != nil {
+				return err
+			}
+			continue
+		}
+		connector.counters.timeouts.Add(1)
+		connector.watchdog.timedOut()
+		connector.remove(p)
+		if p.message.IsQueued {
+			// 无法确定设备是否已收到，重新查询剩余空间
+			line.leftSpace = 0
+		}
+		p.fail(&protocol.ProtocolError{Id: p.message.Id, Err: ErrTimeout})
+	}
+	return connector.drain()
+}
+
+// abort 连接不可用时应答所有请求
+func (connector *Connector) abort(err error) {
+	line := connector.pipeline
+	for _, waiters := range line.outstanding {
+		for _, p := range waiters {
+			p.fail(err)
+		}
+	}
+	for _, p := range line.ready {
+		p.fail(err)
+	}
+	connector.pipeline = newPipeline()
+}
+
+// Stats 链路统计
+func (connector *Connector) Stats() LinkStats {
+	if connector == nil {
+		return LinkStats{}
+	}
+	return LinkStats{
+		Sent:        connector.counters.sent.Load(),
+		Received:    connector.counters.received.Load(),
+		Retransmits: connector.counters.retransmits.Load(),
+		Timeouts:    connector.counters.timeouts.Load(),
+		Unmatched:   connector.counters.unmatched.Load(),
+	}
+}
@@ -0,0 +1,259 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// wire 记录写出帧的传输层，收发协程不运行，应答由测试直接交给 dispatch
+type wire struct {
+	frames []*protocol.Message
+}
+
+func (w *wire) Read(p []byte) (int, error) { return 0, io.EOF }
+func (w *wire) Close() error               { return nil }
+
+func (w *wire) Write(p []byte) (int, error) {
+	decoder := protocol.NewDecoder()
+	decoder.Write(p)
+	message, err := decoder.Next()
+	if err != nil || message == nil {
+		return 0, errors.New("not a frame")
+	}
+	w.frames = append(w.frames, message)
+	return len(p), nil
+}
+
+// count 写出的指定指令帧数
+func (w *wire) count(id protocol.ProtocolId) int {
+	n := 0
+	for _, frame := range w.frames {
+		if frame.Id == id {
+			n++
+		}
+	}
+	return n
+}
+
+func newTestConnector() (*Connector, *wire) {
+	w := &wire{}
+	return &Connector{port: w, pipeline: newPipeline(), session: newSession()}, w
+}
+
+func request(id protocol.ProtocolId, rw, queued bool) *outMessage {
+	message := &protocol.Message{Id: id, RW: rw, IsQueued: queued}
+	return &outMessage{Message: message, ctx: context.Background(), done: make(chan *MessageAck, 1)}
+}
+
+func leftSpaceReply(space uint32) *protocol.Message {
+	return &protocol.Message{Id: protocol.ProtocolQueuedCmdLeftSpace, Params: binary.LittleEndian.AppendUint32(nil, space)}
+}
+
+func indexReply(id protocol.ProtocolId, index uint64) *protocol.Message {
+	return &protocol.Message{Id: id, RW: true, IsQueued: true, Params: binary.LittleEndian.AppendUint64(nil, index)}
+}
+
+// answered 取出请求的应答，尚未应答时返回 nil
+func answered(outmsg *outMessage) *MessageAck {
+	select {
+	case ack := <-outmsg.done:
+		return ack
+	default:
+		return nil
+	}
+}
+
+// TestLeftSpaceRace 剩余空间应答之前已发出、之后才到达设备的队列指令应从应答中扣除
+func TestLeftSpaceRace(t *testing.T) {
+	tests := []struct {
+		name      string
+		sentAfter int    // 查询发出后、应答到达前发出的队列指令数
+		space     uint32 // 应答中的剩余空间
+		want      uint32
+	}{
+		{"none sent after", 0, 4, 4},
+		{"all consumed", 3, 3, 0},
+		{"partly consumed", 3, 5, 2},
+		{"reply below in flight", 3, 1, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connector, w := newTestConnector()
+			line := connector.pipeline
+			line.leftSpace = uint32(test.sentAfter)
+			query := request(protocol.ProtocolQueuedCmdLeftSpace, false, false)
+			if err := connector.submit(query); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < test.sentAfter; i++ {
+				if err := connector.submit(request(protocol.ProtocolPTPCmd, true, true)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := w.count(protocol.ProtocolPTPCmd); got != test.sentAfter {
+				t.Fatalf("sent %d queued commands, want %d", got, test.sentAfter)
+			}
+			if err := connector.dispatch(leftSpaceReply(test.space)); err != nil {
+				t.Fatal(err)
+			}
+			if line.leftSpace != test.want {
+				t.Errorf("leftSpace = %d, want %d", line.leftSpace, test.want)
+			}
+			if ack := answered(query); ack == nil || ack.Message.Uint32() != test.space {
+				t.Errorf("caller query answered with %+v", ack)
+			}
+		})
+	}
+}
+
+// TestLeftSpaceBlocked 队列空间耗尽时保持提交顺序等待，内部查询得到空间后依次发出
+func TestLeftSpaceBlocked(t *testing.T) {
+	connector, w := newTestConnector()
+	line := connector.pipeline
+	line.leftSpace = 1
+	var cmds []*outMessage
+	for i := 0; i < 4; i++ {
+		cmds = append(cmds, request(protocol.ProtocolPTPCmd, true, true))
+		if err := connector.submit(cmds[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 非队列指令不受剩余空间限制
+	pose := request(protocol.ProtocolGetPose, false, false)
+	if err := connector.submit(pose); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.count(protocol.ProtocolPTPCmd); got != 1 {
+		t.Fatalf("sent %d queued commands before space query, want 1", got)
+	}
+	if got := w.count(protocol.ProtocolGetPose); got != 1 {
+		t.Fatalf("non-queued request held behind queued ones")
+	}
+	if got := w.count(protocol.ProtocolQueuedCmdLeftSpace); got != 1 {
+		t.Fatalf("sent %d space queries, want 1", got)
+	}
+	// 再次提交不重复查询
+	cmds = append(cmds, request(protocol.ProtocolPTPCmd, true, true))
+	if err := connector.submit(cmds[4]); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.count(protocol.ProtocolQueuedCmdLeftSpace); got != 1 {
+		t.Fatalf("sent %d space queries while one in flight, want 1", got)
+	}
+	// 第一条队列指令已计入应答前发出的数量
+	if err := connector.dispatch(leftSpaceReply(3)); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.count(protocol.ProtocolPTPCmd); got != 4 {
+		t.Fatalf("sent %d queued commands after space reply, want 4", got)
+	}
+	if line.leftSpace != 0 || len(line.ready) != 1 || line.ready[0].outmsg != cmds[4] {
+		t.Fatalf("leftSpace %d, ready %d after space reply", line.leftSpace, len(line.ready))
+	}
+	// 应答中没有空间时等待的队列指令以 ErrLeftSpace 交还调用方重试
+	if err := connector.dispatch(leftSpaceReply(0)); err != nil {
+		t.Fatal(err)
+	}
+	if ack := answered(cmds[4]); ack == nil || !errors.Is(ack.Error, ErrLeftSpace) {
+		t.Fatalf("blocked command answered with %+v, want ErrLeftSpace", ack)
+	}
+	// 队列指令的应答按发送顺序对应
+	for i := 0; i < 4; i++ {
+		if err := connector.dispatch(indexReply(protocol.ProtocolPTPCmd, uint64(i+1))); err != nil {
+			t.Fatal(err)
+		}
+		if ack := answered(cmds[i]); ack == nil || ack.Message.Uint64() != uint64(i+1) {
+			t.Fatalf("command %d answered with %+v", i, ack)
+		}
+	}
+}
+
+// TestExpire 同一指令仅一个在途时超时重发，多个在途时无法区分迟到的应答，超时即失败
+func TestExpire(t *testing.T) {
+	tests := []struct {
+		name        string
+		sent        []time.Duration // 各请求的发送时间
+		at          time.Duration   // 检查超时的时间
+		retransmits int
+		failed      []bool // 各请求是否以超时失败
+	}{
+		{"single is retransmitted", []time.Duration{0}, replyTimeout, 1, []bool{false}},
+		{"single not yet due", []time.Duration{0}, replyTimeout - time.Millisecond, 0, []bool{false}},
+		{"shared id fails all expired", []time.Duration{0, 0}, replyTimeout, 0, []bool{true, true}},
+		{"shared id fails only expired", []time.Duration{0, time.Second}, replyTimeout, 0, []bool{true, false}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connector, w := newTestConnector()
+			base := time.Now()
+			var requests []*outMessage
+			for range test.sent {
+				outmsg := request(protocol.ProtocolGetPose, false, false)
+				if err := connector.submit(outmsg); err != nil {
+					t.Fatal(err)
+				}
+				requests = append(requests, outmsg)
+			}
+			for i, p := range connector.pipeline.outstanding[protocol.ProtocolGetPose] {
+				p.sentAt = base.Add(test.sent[i])
+			}
+			if err := connector.expire(base.Add(test.at)); err != nil {
+				t.Fatal(err)
+			}
+			if got := int(connector.Stats().Retransmits); got != test.retransmits {
+				t.Errorf("retransmits = %d, want %d", got, test.retransmits)
+			}
+			if got, want := len(w.frames), len(test.sent)+test.retransmits; got != want {
+				t.Errorf("frames = %d, want %d", got, want)
+			}
+			for i, outmsg := range requests {
+				ack := answered(outmsg)
+				if failed := ack != nil && errors.Is(ack.Error, ErrTimeout); failed != test.failed[i] {
+					t.Errorf("request %d answered with %+v, want failed %v", i, ack, test.failed[i])
+				}
+			}
+		})
+	}
+}
+
+// TestExpireGivesUp 重发 maxAttempts 次后失败，队列指令失败时剩余空间重新查询
+func TestExpireGivesUp(t *testing.T) {
+	connector, w := newTestConnector()
+	line := connector.pipeline
+	line.leftSpace = 5
+	outmsg := request(protocol.ProtocolPTPCmd, true, true)
+	if err := connector.submit(outmsg); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		now = now.Add(replyTimeout)
+		if err := connector.expire(now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := w.count(protocol.ProtocolPTPCmd); got != maxAttempts {
+		t.Errorf("sent %d times, want %d", got, maxAttempts)
+	}
+	ack := answered(outmsg)
+	var protocolErr *protocol.ProtocolError
+	if ack == nil || !errors.As(ack.Error, &protocolErr) || !errors.Is(ack.Error, ErrTimeout) {
+		t.Fatalf("answered with %+v, want timeout", ack)
+	}
+	if line.leftSpace != 0 || line.inFlight != 0 || len(line.outstanding) != 0 {
+		t.Errorf("leftSpace %d, inFlight %d, outstanding %d after give up", line.leftSpace, line.inFlight, len(line.outstanding))
+	}
+	// 迟到的应答没有对应请求
+	if err := connector.dispatch(indexReply(protocol.ProtocolPTPCmd, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if got := connector.Stats().Unmatched; got != 1 {
+		t.Errorf("unmatched = %d, want 1", got)
+	}
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// 重连默认参数
+const (
+	DefaultReconnectBackoff    = 500 * time.Millisecond
+	DefaultReconnectMaxBackoff = 10 * time.Second
+	restoreTimeout             = 10 * time.Second
+)
+
+// ReconnectPolicy 断线自动重连策略
+type ReconnectPolicy struct {
+	MaxAttempts int                           // 最大尝试次数，0 表示不限
+	Backoff     time.Duration                 // 首次重试等待，之后每次翻倍
+	MaxBackoff  time.Duration                 // 重试等待上限
+	Open        func() (Transport, error)     // 重新打开传输层，为空时按连接地址重新打开
+	OnResult    func(result *ReconnectResult) // 重连结束时回调，在独立协程中调用
+}
+
+// ReconnectResult 一次断线重连的结果
+type ReconnectResult struct {
+	Cause      error                 // 断线原因
+	Attempts   int                   // 尝试次数
+	Restored   []protocol.ProtocolId // 已恢复的参数指令
+	QueueIndex uint64                // 重连后设备的队列当前索引
+	Err        error                 // 为 nil 表示重连成功
+}
+
+// sessionKey 参数指令的键，带地址的指令按地址区分
+type sessionKey struct {
+	id      protocol.ProtocolId
+	address uint8
+}
+
+// session 记录会话中最后写入的参数，重连后按写入顺序恢复
+type session struct {
+	messages map[sessionKey]*protocol.Message
+	order    []sessionKey
+}
+
+func newSession() *session {
+	return &session{messages: map[sessionKey]*protocol.Message{}}
+}
+
+// restorable 重连后需要恢复的参数指令
+func restorable(id protocol.ProtocolId) (addressed bool, ok bool) {
+	switch id {
+	case protocol.ProtocolHOMEParams, protocol.ProtocolArmOrientation,
+		protocol.ProtocolEndEffectorParams, protocol.ProtocolEndEffectorLaser,
+		protocol.ProtocolEndEffectorSuctionCup, protocol.ProtocolEndEffectorGripper,
+		protocol.ProtocolJOGJointParams, protocol.ProtocolJOGCoordinateParams,
+		protocol.ProtocolJOGCommonParams, protocol.ProtocolJOGLParams,
+		protocol.ProtocolPTPJointParams, protocol.ProtocolPTPCoordinateParams,
+		protocol.ProtocolPTPJumpParams, protocol.ProtocolPTPCommonParams,
+		protocol.ProtocolPTPLParams, protocol.ProtocolPTPJump2Params,
+		protocol.ProtocolCPParams, protocol.ProtocolCPCommonParams, protocol.ProtocolCPRHoldEnable,
+		protocol.ProtocolARCParams, protocol.ProtocolARCCommonParams:
+		return false, true
+	case protocol.ProtocolIOMultiplexing:
+		return true, true
+	}
+	return false, false
+}
+
+// record 记录写入成功的参数指令
+func (sess *session) record(message *protocol.Message) {
+	addressed, ok := restorable(message.Id)
+	if !ok {
+		return
+	}
+	key := sessionKey{id: message.Id}
+	if addressed {
+		key.address = message.Byte(0)
+	}
+	if _, ok := sess.messages[key]; !ok {
+		sess.order = append(sess.order, key)
+	}
+	// 恢复时不进入指令队列
+	sess.messages[key] = &protocol.Message{Id: message.Id, RW: true, IsQueued: false, Params: append([]byte(nil), message.Params...)}
+}
+
+// SetReconnectPolicy 设置断线重连策略，nil 表示断线后不重连
+func (connector *Connector) SetReconnectPolicy(policy *ReconnectPolicy) {
+	connector.portMutex.Lock()
+	defer connector.portMutex.Unlock()
+	connector.policy = policy
+}
+
+func (connector *Connector) reconnectPolicy() *ReconnectPolicy {
+	connector.portMutex.Lock()
+	defer connector.portMutex.Unlock()
+	return connector.policy
+}
+
+// shuttingDown 是否已调用 Close
+func (connector *Connector) shuttingDown() bool {
+	select {
+	case <-connector.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+// reconnect 按策略重新建立连接，返回 nil 表示已恢复，否则返回连接最终的错误
+func (connector *Connector) reconnect(cause error) error {
+	connector.stop()
+	policy := connector.reconnectPolicy()
+	if policy == nil || connector.shuttingDown() {
+		return cause
+	}
+	result := &ReconnectResult{Cause: cause}
+	defer func() {
+		if policy.OnResult != nil {
+			go policy.OnResult(result)
+		}
+	}()
+	open := policy.Open
+	if open == nil {
+		open = connector.reopen
+	}
+	if open == nil {
+		result.Err = errors.New("transport can not be reopened")
+		return cause
+	}
+	backoff := policy.Backoff
+	if backoff <= 0 {
+		backoff = DefaultReconnectBackoff
+	}
+	maxBackoff := policy.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultReconnectMaxBackoff
+	}
+	for policy.MaxAttempts <= 0 || result.Attempts < policy.MaxAttempts {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-connector.shutdown:
+			timer.Stop()
+			result.Err = ErrDisconnected
+			return cause
+		case <-timer.C:
+		}
+		result.Attempts++
+		backoff = min(backoff*2, maxBackoff)
+		transport, err := open()
+		if err != nil {
+			result.Err = err
+			continue
+		}
+		connector.start(transport)
+		if connector.shuttingDown() {
+			connector.stop()
+			result.Err = ErrDisconnected
+			return cause
+		}
+		if err = connector.restore(result); err != nil {
+			connector.stop()
+			result.Err = err
+			continue
+		}
+		result.Err = nil
+		return nil
+	}
+	return disconnected(result.Err)
+}
+
+// restore 恢复会话参数并同步队列索引
+func (connector *Connector) restore(result *ReconnectResult) error {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+	result.Restored = nil
+	for _, key := range connector.session.order {
+		if _, err := connector.exchange(ctx, connector.session.messages[key]); err != nil {
+			return err
+		}
+		result.Restored = append(result.Restored, key.id)
+	}
+	index, err := connector.exchange(ctx, &protocol.Message{Id: protocol.ProtocolQueuedCmdCurrentIndex, RW: false, IsQueued: false})
+	if err != nil {
+		return err
+	}
+	result.QueueIndex = index.Uint64()
+	connector.advance(result.QueueIndex)
+	return nil
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// TestReconnect 断线后按策略重新打开连接，恢复写入过的参数（不进入队列）并同步队列索引
+func TestReconnect(t *testing.T) {
+	connector, dev := connectDevice(t, "reconnect")
+	results := make(chan *ReconnectResult, 1)
+	connector.SetReconnectPolicy(&ReconnectPolicy{Backoff: 10 * time.Millisecond, OnResult: func(result *ReconnectResult) {
+		results <- result
+	}})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	params := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	if _, err := connector.SendMessage(ctx, &protocol.Message{Id: protocol.ProtocolPTPCommonParams, RW: true, IsQueued: true, Params: params}); err != nil {
+		t.Fatal(err)
+	}
+	// 读指令与非参数指令不恢复
+	if _, err := connector.SendMessage(ctx, &protocol.Message{Id: protocol.ProtocolPTPCommonParams}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := connector.SendMessage(ctx, &protocol.Message{Id: protocol.ProtocolPTPCmd, RW: true, IsQueued: true, Params: make([]byte, 17)}); err != nil {
+		t.Fatal(err)
+	}
+
+	dev.drop()
+	var result *ReconnectResult
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		t.Fatal("no reconnect result")
+	}
+	if result.Err != nil || result.Cause == nil || result.Attempts != 1 {
+		t.Fatalf("result %+v, want success on the first attempt", result)
+	}
+	if !reflect.DeepEqual(result.Restored, []protocol.ProtocolId{protocol.ProtocolPTPCommonParams}) {
+		t.Errorf("restored %v, want PTP common params", result.Restored)
+	}
+	if result.QueueIndex != 2 {
+		t.Errorf("queue index %d, want 2", result.QueueIndex)
+	}
+	writes := 0
+	for _, frame := range dev.received(protocol.ProtocolPTPCommonParams) {
+		if frame.RW {
+			writes++
+			if writes == 2 && (frame.IsQueued || !bytes.Equal(frame.Params, params)) {
+				t.Errorf("restored frame %+v, want the same params outside the queue", frame)
+			}
+		}
+	}
+	if writes != 2 {
+		t.Errorf("params written %d times, want 2", writes)
+	}
+	if frames := dev.received(protocol.ProtocolPTPCmd); len(frames) != 1 {
+		t.Errorf("motion command sent %d times, want 1", len(frames))
+	}
+	if _, err := connector.SendMessage(ctx, &protocol.Message{Id: protocol.ProtocolGetPose}); err != nil || connector.Err() != nil {
+		t.Errorf("after reconnect: %v, connection error %v", err, connector.Err())
+	}
+}
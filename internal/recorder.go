@@ -0,0 +1,486 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// 记录文件格式：
+//
+//	头部   "DOBOTREC" 版本(1 字节) 开始时间(int64 Unix 纳秒，小端)
+//	记录   方向(1 字节) 距上一条的间隔(uvarint 纳秒) 长度(uvarint) 数据
+const (
+	recordMagic   = "DOBOTREC"
+	recordVersion = 1
+)
+
+var (
+	ErrBadRecording   = errors.New("bad recording")
+	ErrReplayMismatch = errors.New("replay mismatch")
+)
+
+// RecordDirection 记录方向
+type RecordDirection uint8
+
+const (
+	RecordTx RecordDirection = iota // 发往设备
+	RecordRx                        // 来自设备
+)
+
+func (direction RecordDirection) String() string {
+	if direction == RecordTx {
+		return "tx"
+	}
+	return "rx"
+}
+
+// Record 一条收发记录。发送记录为完整的帧，接收记录为从链路读到的原始字节
+type Record struct {
+	Direction RecordDirection
+	At        time.Duration // 相对记录开始的单调时间
+	Data      []byte
+}
+
+// Recording 一次会话的记录
+type Recording struct {
+	Start   time.Time
+	Records []Record
+}
+
+// Recorder 将收发记录写入 io.Writer，可被多个协程同时使用
+type Recorder struct {
+	mutex  sync.Mutex
+	writer *bufio.Writer
+	closer io.Closer
+	start  time.Time
+	last   time.Duration
+	err    error
+}
+
+// NewRecorder 创建记录器并写入文件头，w 实现 io.Closer 时随 Close 关闭
+func NewRecorder(w io.Writer) (*Recorder, error) {
+	recorder := &Recorder{writer: bufio.NewWriter(w), start: time.Now()}
+	if closer, ok := w.(io.Closer); ok {
+		recorder.closer = closer
+	}
+	header := make([]byte, 0, len(recordMagic)+9)
+	header = append(header, recordMagic...)
+	header = append(header, recordVersion)
+	header = binary.LittleEndian.AppendUint64(header, uint64(recorder.start.UnixNano()))
+	if _, err := recorder.writer.Write(header); err != nil {
+		return nil, err
+	}
+	return recorder, nil
+}
+
+// Record 写入一条记录，写入失败后记录器停止工作，错误由 Close 返回
+func (recorder *Recorder) Record(direction RecordDirection, data []byte) {
+	if recorder == nil {
+		return
+	}
+	at := time.Since(recorder.start)
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if recorder.err != nil {
+		return
+	}
+	delta := max(at-recorder.last, 0)
+	recorder.last = at
+	entry := make([]byte, 0, len(data)+2*binary.MaxVarintLen64+1)
+	entry = append(entry, byte(direction))
+	entry = binary.AppendUvarint(entry, uint64(delta))
+	entry = binary.AppendUvarint(entry, uint64(len(data)))
+	entry = append(entry, data...)
+	if _, err := recorder.writer.Write(entry); err != nil {
+		recorder.err = err
+		return
+	}
+	// 每条记录立即落盘，进程崩溃时保留现场
+	recorder.err = recorder.writer.Flush()
+}
+
+// Close 刷新缓冲并关闭底层 Writer
+func (recorder *Recorder) Close() error {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	err := recorder.err
+	if err == nil {
+		err = recorder.writer.Flush()
+	}
+	if recorder.closer != nil {
+		if closeErr := recorder.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if recorder.err == nil {
+		recorder.err = os.ErrClosed
+	}
+	return err
+}
+
+// ReadRecording 读取记录文件
+func ReadRecording(r io.Reader) (*Recording, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, len(recordMagic)+9)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadRecording, err)
+	}
+	if string(header[:len(recordMagic)]) != recordMagic || header[len(recordMagic)] != recordVersion {
+		return nil, fmt.Errorf("%w: unknown header", ErrBadRecording)
+	}
+	recording := &Recording{Start: time.Unix(0, int64(binary.LittleEndian.Uint64(header[len(recordMagic)+1:])))}
+	var at time.Duration
+	for {
+		direction, err := reader.ReadByte()
+		if err == io.EOF {
+			return recording, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if direction > byte(RecordRx) {
+			return nil, fmt.Errorf("%w: unknown direction %d", ErrBadRecording, direction)
+		}
+		delta, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrBadRecording, io.ErrUnexpectedEOF)
+		}
+		size, err := binary.ReadUvarint(reader)
+		if err != nil || size > 1<<20 {
+			return nil, fmt.Errorf("%w: bad record length", ErrBadRecording)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrBadRecording, io.ErrUnexpectedEOF)
+		}
+		at += time.Duration(delta)
+		recording.Records = append(recording.Records, Record{Direction: RecordDirection(direction), At: at, Data: data})
+	}
+}
+
+// tappedTransport 记录经过传输层的所有字节
+type tappedTransport struct {
+	Transport
+	recorder func() *Recorder
+}
+
+func (tapped *tappedTransport) Read(p []byte) (int, error) {
+	n, err := tapped.Transport.Read(p)
+	if n > 0 {
+		tapped.recorder().Record(RecordRx, p[:n])
+	}
+	return n, err
+}
+
+// Write 在写入前记录，同步传输层的应答可能先于 Write 返回
+func (tapped *tappedTransport) Write(p []byte) (int, error) {
+	tapped.recorder().Record(RecordTx, p)
+	return tapped.Transport.Write(p)
+}
+
+// ReplayTransport 回放记录的传输层：打开时按指令 id 将记录中的请求与应答配对（与收发协程的对应规则相同），
+// 发送的帧按指令、读写与队列标志对应该类请求中下一条未回放的记录，并得到其记录的应答。
+// 后台轮询与调用方请求的先后不影响各自得到的应答，回放结果与轮询时机无关；
+// 仅轮询与调用方读取同一指令时共用该指令记录的应答序列。
+//
+// 原样回放（NewRawReplayTransport）不配对，按记录顺序读出所有接收的数据，包括没有对应请求的应答与损坏的帧，
+// 用于复现原会话中的链路问题
+type ReplayTransport struct {
+	mutex     sync.Mutex
+	cond      *sync.Cond
+	exchanges map[exchangeKey][]*exchange // 各类请求尚未回放的记录
+	last      map[exchangeKey]*exchange   // 已回放完的各类请求的最后一条记录
+	replies   []*exchange                 // 已发送请求、等待读出的应答
+	pending   []byte
+	strict    bool    // 发送内容与记录不一致时返回 ErrReplayMismatch
+	speed     float64 // 大于 0 时按记录的应答时间回放，2 表示两倍速
+	opened    time.Time
+	closed    bool
+	raw       *rawReplay // 原样回放，nil 表示按请求配对
+}
+
+// rawReplay 原样回放的进度
+type rawReplay struct {
+	records  []Record            // 尚未回放的记录
+	requests [][]byte            // 尚未发送的请求帧，严格回放时依次比较
+	written  map[exchangeKey]int // 已发送、尚未对应到记录的帧数
+}
+
+// exchangeKey 请求的分类：指令 id 与 ctrl（读写、队列标志）
+type exchangeKey struct {
+	id   protocol.ProtocolId
+	ctrl uint8
+}
+
+// exchange 记录中的一次请求与应答
+type exchange struct {
+	index   int           // 请求在记录中的序号
+	request []byte        // 请求帧
+	sent    time.Duration // 请求的发送时间
+	reply   []byte        // 应答帧，原会话未收到应答时为 nil
+	at      time.Duration // 收到应答的时间
+}
+
+// pairExchanges 按收发协程的规则配对请求与应答：同一指令的应答依次对应最早未应答的请求；
+// 同一指令仅一个请求在途且间隔超过紧急指令超时后再次发送相同内容视为重发，不单独配对，
+// 损坏的帧与没有对应请求的应答被丢弃
+func pairExchanges(recording *Recording) map[exchangeKey][]*exchange {
+	exchanges := map[exchangeKey][]*exchange{}
+	waiting := map[protocol.ProtocolId][]*exchange{}
+	received := protocol.NewDecoder()
+	for i, record := range recording.Records {
+		if record.Direction == RecordTx {
+			frame := protocol.NewDecoder()
+			frame.Write(record.Data)
+			message, _ := frame.Next()
+			if message == nil {
+				continue
+			}
+			if w := waiting[message.Id]; len(w) == 1 && bytes.Equal(w[0].request, record.Data) && record.At-w[0].sent >= urgentTimeout {
+				continue
+			}
+			key := exchangeKey{id: message.Id, ctrl: message.Ctrl()}
+			ex := &exchange{index: i, request: record.Data, sent: record.At}
+			exchanges[key] = append(exchanges[key], ex)
+			waiting[message.Id] = append(waiting[message.Id], ex)
+			continue
+		}
+		received.Write(record.Data)
+		for {
+			message, err := received.Next()
+			if err != nil {
+				continue
+			}
+			if message == nil {
+				break
+			}
+			if w := waiting[message.Id]; len(w) > 0 {
+				w[0].reply, w[0].at = protocol.Encode(message), record.At
+				waiting[message.Id] = w[1:]
+			}
+		}
+	}
+	return exchanges
+}
+
+// NewReplayTransport 创建回放传输层
+func NewReplayTransport(recording *Recording, strict bool, speed float64) *ReplayTransport {
+	replay := &ReplayTransport{exchanges: pairExchanges(recording), last: map[exchangeKey]*exchange{}, strict: strict, speed: speed, opened: time.Now()}
+	replay.cond = sync.NewCond(&replay.mutex)
+	return replay
+}
+
+// NewRawReplayTransport 创建原样回放传输层：接收的记录按原顺序读出，
+// 每条接收记录在它之前记录的请求都已发送（按指令、读写与队列标志对应）后才可读出
+func NewRawReplayTransport(recording *Recording, strict bool, speed float64) *ReplayTransport {
+	raw := &rawReplay{records: recording.Records, written: map[exchangeKey]int{}}
+	for _, record := range recording.Records {
+		if record.Direction == RecordTx {
+			raw.requests = append(raw.requests, record.Data)
+		}
+	}
+	replay := &ReplayTransport{last: map[exchangeKey]*exchange{}, strict: strict, speed: speed, opened: time.Now(), raw: raw}
+	replay.cond = sync.NewCond(&replay.mutex)
+	return replay
+}
+
+// requestKey 请求帧的分类，不是完整帧时返回 false
+func requestKey(p []byte) (exchangeKey, bool) {
+	if len(p) < 5 {
+		return exchangeKey{}, false
+	}
+	return exchangeKey{id: protocol.ProtocolId(p[3]), ctrl: p[4]}, true
+}
+
+// openReplay replay:///path/to/session.rec?strict=1&speed=1&raw=1
+func openReplay(address *url.URL) (Transport, error) {
+	name := address.Host + address.Path
+	if address.Opaque != "" {
+		name = address.Opaque
+	}
+	if name == "" {
+		return nil, errors.New("invalid address: empty recording path")
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	recording, err := ReadRecording(file)
+	if err != nil {
+		return nil, err
+	}
+	query := address.Query()
+	strict, _ := strconv.ParseBool(query.Get("strict"))
+	var speed float64
+	if value := query.Get("speed"); value != "" {
+		if speed, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("invalid speed: %s", value)
+		}
+	}
+	if raw, _ := strconv.ParseBool(query.Get("raw")); raw {
+		return NewRawReplayTransport(recording, strict, speed), nil
+	}
+	return NewReplayTransport(recording, strict, speed), nil
+}
+
+// Read 依次读出已发送请求的应答，全部记录回放后返回 io.EOF
+func (replay *ReplayTransport) Read(p []byte) (int, error) {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	if replay.raw != nil {
+		return replay.readRaw(p)
+	}
+	for len(replay.pending) == 0 {
+		if replay.closed {
+			return 0, os.ErrClosed
+		}
+		if len(replay.replies) == 0 {
+			if len(replay.exchanges) == 0 {
+				return 0, io.EOF
+			}
+			replay.cond.Wait()
+			continue
+		}
+		ex := replay.replies[0]
+		if replay.speed > 0 {
+			due := replay.opened.Add(time.Duration(float64(ex.at) / replay.speed))
+			if wait := time.Until(due); wait > 0 {
+				replay.mutex.Unlock()
+				time.Sleep(wait)
+				replay.mutex.Lock()
+				continue
+			}
+		}
+		replay.pending = ex.reply
+		replay.replies = replay.replies[1:]
+	}
+	n := copy(p, replay.pending)
+	replay.pending = replay.pending[n:]
+	return n, nil
+}
+
+// readRaw 按记录顺序读出接收的数据，遇到尚未发送的请求记录时等待
+func (replay *ReplayTransport) readRaw(p []byte) (int, error) {
+	raw := replay.raw
+	for len(replay.pending) == 0 {
+		if replay.closed {
+			return 0, os.ErrClosed
+		}
+		if len(raw.records) == 0 {
+			return 0, io.EOF
+		}
+		record := raw.records[0]
+		if record.Direction == RecordTx {
+			key, _ := requestKey(record.Data)
+			if raw.written[key] == 0 {
+				replay.cond.Wait()
+				continue
+			}
+			raw.written[key]--
+			raw.records = raw.records[1:]
+			continue
+		}
+		if replay.speed > 0 {
+			due := replay.opened.Add(time.Duration(float64(record.At) / replay.speed))
+			if wait := time.Until(due); wait > 0 {
+				replay.mutex.Unlock()
+				time.Sleep(wait)
+				replay.mutex.Lock()
+				continue
+			}
+		}
+		replay.pending = record.Data
+		raw.records = raw.records[1:]
+	}
+	n := copy(p, replay.pending)
+	replay.pending = replay.pending[n:]
+	return n, nil
+}
+
+// writeRaw 记录发送的帧，严格回放时按顺序与记录的请求比较
+func (replay *ReplayTransport) writeRaw(p []byte) (int, error) {
+	raw := replay.raw
+	if replay.strict {
+		if len(raw.requests) == 0 {
+			return 0, fmt.Errorf("%w: sent % X, no more request in recording", ErrReplayMismatch, p)
+		}
+		if !bytes.Equal(raw.requests[0], p) {
+			return 0, fmt.Errorf("%w: sent % X, want % X", ErrReplayMismatch, p, raw.requests[0])
+		}
+		raw.requests = raw.requests[1:]
+	}
+	if key, ok := requestKey(p); ok {
+		raw.written[key]++
+		replay.cond.Broadcast()
+	}
+	return len(p), nil
+}
+
+// Write 将帧对应到同类请求中下一条未回放的记录，原会话未收到应答的请求同样没有应答；
+// 读指令（如后台轮询）的记录用完后重复其最后一条应答，视为设备保持记录结束时的状态
+func (replay *ReplayTransport) Write(p []byte) (int, error) {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	if replay.closed {
+		return 0, os.ErrClosed
+	}
+	if replay.raw != nil {
+		return replay.writeRaw(p)
+	}
+	if len(p) < 5 {
+		if replay.strict {
+			return 0, fmt.Errorf("%w: sent % X, not a frame", ErrReplayMismatch, p)
+		}
+		return len(p), nil
+	}
+	key, _ := requestKey(p)
+	queue := replay.exchanges[key]
+	if len(queue) == 0 {
+		if last := replay.last[key]; last != nil && key.ctrl == 0 {
+			if last.reply != nil {
+				replay.replies = append(replay.replies, last)
+				replay.cond.Broadcast()
+			}
+			return len(p), nil
+		}
+		if replay.strict {
+			return 0, fmt.Errorf("%w: sent % X, no more such request in recording", ErrReplayMismatch, p)
+		}
+		return len(p), nil
+	}
+	ex := queue[0]
+	if replay.strict && !bytes.Equal(ex.request, p) {
+		return 0, fmt.Errorf("%w: record %d sent % X, want % X", ErrReplayMismatch, ex.index, p, ex.request)
+	}
+	if len(queue) == 1 {
+		delete(replay.exchanges, key)
+		replay.last[key] = ex
+	} else {
+		replay.exchanges[key] = queue[1:]
+	}
+	if ex.reply != nil {
+		replay.replies = append(replay.replies, ex)
+	}
+	replay.cond.Broadcast()
+	return len(p), nil
+}
+
+func (replay *ReplayTransport) Close() error {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	replay.closed = true
+	replay.cond.Broadcast()
+	return nil
+}
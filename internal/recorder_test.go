@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// TestRawReplay 原样回放保留没有对应请求的应答，收发协程应将其计入 Unmatched；配对回放则丢弃
+func TestRawReplay(t *testing.T) {
+	pose := &protocol.Message{Id: protocol.ProtocolGetPose, Params: make([]byte, 32)}
+	stray := &protocol.Message{Id: protocol.ProtocolPTPCmd, RW: true, IsQueued: true, Params: binary.LittleEndian.AppendUint64(nil, 7)}
+	request := protocol.Encode(&protocol.Message{Id: protocol.ProtocolGetPose})
+	recording := &Recording{Records: []Record{
+		{Direction: RecordTx, Data: request},
+		{Direction: RecordRx, Data: protocol.Encode(pose)},
+		{Direction: RecordRx, Data: protocol.Encode(stray)},
+		{Direction: RecordTx, Data: request},
+		{Direction: RecordRx, Data: protocol.Encode(pose)},
+		// 回放时不发送的请求，使回放停在这里而不结束连接
+		{Direction: RecordTx, Data: protocol.Encode(&protocol.Message{Id: protocol.ProtocolHOMECmd, RW: true, IsQueued: true})},
+	}}
+	tests := []struct {
+		name      string
+		transport *ReplayTransport
+		unmatched uint64
+	}{
+		{"paired", NewReplayTransport(recording, false, 0), 0},
+		{"raw", NewRawReplayTransport(recording, false, 0), 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connector := &Connector{}
+			connector.Attach(test.transport)
+			defer connector.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			for i := 0; i < 2; i++ {
+				if _, err := connector.SendMessage(ctx, &protocol.Message{Id: protocol.ProtocolGetPose}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// 多余的应答在第二个应答之前读出，第二次请求返回时已经处理
+			if got := connector.Stats().Unmatched; got != test.unmatched {
+				t.Errorf("Unmatched = %d, want %d", got, test.unmatched)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// pollTick 轮询调度的时间粒度
+const pollTick = 10 * time.Millisecond
+
+// pollHandler 轮询订阅，interval 为期望的采样间隔
+type pollHandler struct {
+	interval time.Duration
+	last     time.Time
+	handle   func(*protocol.Message)
+}
+
+// scheduler 轮询调度器：同一指令的多个订阅共用一次总线请求，
+// 请求间隔取订阅中最短的间隔，应答按各自间隔分发
+type scheduler struct {
+	mutex    sync.Mutex
+	handlers map[protocol.ProtocolId][]*pollHandler
+	sent     map[protocol.ProtocolId]time.Time
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		handlers: map[protocol.ProtocolId][]*pollHandler{},
+		sent:     map[protocol.ProtocolId]time.Time{},
+	}
+}
+
+// add 添加订阅，返回取消函数
+func (sched *scheduler) add(id protocol.ProtocolId, interval time.Duration, handle func(*protocol.Message)) func() {
+	if interval < pollTick {
+		interval = pollTick
+	}
+	handler := &pollHandler{interval: interval, handle: handle}
+	sched.mutex.Lock()
+	sched.handlers[id] = append(sched.handlers[id], handler)
+	sched.mutex.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sched.mutex.Lock()
+			defer sched.mutex.Unlock()
+			handlers := sched.handlers[id]
+			for i, h := range handlers {
+				if h == handler {
+					handlers = append(handlers[:i:i], handlers[i+1:]...)
+					break
+				}
+			}
+			if len(handlers) == 0 {
+				delete(sched.handlers, id)
+				delete(sched.sent, id)
+			} else {
+				sched.handlers[id] = handlers
+			}
+		})
+	}
+}
+
+// due 返回到期需要请求的指令，并记录请求时间
+func (sched *scheduler) due(now time.Time) []protocol.ProtocolId {
+	sched.mutex.Lock()
+	defer sched.mutex.Unlock()
+	var ids []protocol.ProtocolId
+	for id, handlers := range sched.handlers {
+		interval := handlers[0].interval
+		for _, handler := range handlers[1:] {
+			interval = min(interval, handler.interval)
+		}
+		if now.Sub(sched.sent[id]) >= interval {
+			sched.sent[id] = now
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// deliver 将应答分发给到期的订阅
+func (sched *scheduler) deliver(message *protocol.Message, now time.Time) {
+	sched.mutex.Lock()
+	var handles []func(*protocol.Message)
+	for _, handler := range sched.handlers[message.Id] {
+		// 允许一个调度粒度的误差，避免与请求间隔相同的订阅被隔次跳过
+		if handler.last.IsZero() || now.Sub(handler.last)+pollTick >= handler.interval {
+			handler.last = now
+			handles = append(handles, handler.handle)
+		}
+	}
+	sched.mutex.Unlock()
+	for _, handle := range handles {
+		handle(message)
+	}
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// TestScheduler 同一指令的订阅共用一次请求，请求间隔取最短的间隔，应答按各自的间隔分发，取消后停止请求
+func TestScheduler(t *testing.T) {
+	sched := newScheduler()
+	counts := map[string]int{}
+	counter := func(name string) func(*protocol.Message) {
+		return func(*protocol.Message) { counts[name]++ }
+	}
+	cancelFast := sched.add(protocol.ProtocolGetPose, 20*time.Millisecond, counter("fast"))
+	sched.add(protocol.ProtocolGetPose, 100*time.Millisecond, counter("slow"))
+	sched.add(protocol.ProtocolAlarmsState, 50*time.Millisecond, counter("alarm"))
+	base := time.Now()
+	requests := map[protocol.ProtocolId]int{}
+	for tick := 0; tick < 20; tick++ {
+		now := base.Add(time.Duration(tick) * pollTick)
+		for _, id := range sched.due(now) {
+			requests[id]++
+			sched.deliver(&protocol.Message{Id: id}, now)
+		}
+	}
+	// 200 毫秒内：位姿每 20 毫秒请求一次，报警每 50 毫秒一次
+	if requests[protocol.ProtocolGetPose] != 10 || requests[protocol.ProtocolAlarmsState] != 4 {
+		t.Errorf("requests %v, want 10 pose and 4 alarm", requests)
+	}
+	if counts["fast"] != 10 || counts["slow"] != 2 || counts["alarm"] != 4 {
+		t.Errorf("deliveries %v, want fast 10, slow 2, alarm 4", counts)
+	}
+
+	// 取消短间隔的订阅后按剩余订阅的间隔请求
+	cancelFast()
+	cancelFast()
+	requests = map[protocol.ProtocolId]int{}
+	for tick := 20; tick < 40; tick++ {
+		for _, id := range sched.due(base.Add(time.Duration(tick) * pollTick)) {
+			requests[id]++
+		}
+	}
+	if requests[protocol.ProtocolGetPose] != 2 {
+		t.Errorf("%d pose requests after cancel, want 2", requests[protocol.ProtocolGetPose])
+	}
+}
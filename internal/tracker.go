@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// trackInterval 有等待者时轮询队列当前索引的间隔
+const trackInterval = 20 * time.Millisecond
+
+// QueuedCommand 已进入设备队列的指令，设备队列索引到达 Index 时完成。
+// nil 表示非队列指令，视为已完成
+type QueuedCommand struct {
+	index     uint64
+	done      chan struct{}
+	err       error
+	callbacks []func(error)
+	waiters   *atomic.Int32 // 连接中阻塞等待的调用方计数，看门狗据此判断调用方仍在工作
+}
+
+// Index 队列索引，非队列指令为 0
+func (cmd *QueuedCommand) Index() uint64 {
+	if cmd == nil {
+		return 0
+	}
+	return cmd.index
+}
+
+// Done 指令执行完成或无法完成时关闭
+func (cmd *QueuedCommand) Done() <-chan struct{} {
+	if cmd == nil {
+		return closedChannel
+	}
+	return cmd.done
+}
+
+// Err 指令无法完成的原因，完成前与正常完成时为 nil
+func (cmd *QueuedCommand) Err() error {
+	if cmd == nil {
+		return nil
+	}
+	select {
+	case <-cmd.done:
+		return cmd.err
+	default:
+		return nil
+	}
+}
+
+// Wait 等待指令执行完成，ctx 取消时返回 ctx.Err()；等待期间看门狗视调用方仍在工作
+func (cmd *QueuedCommand) Wait(ctx context.Context) error {
+	if cmd != nil && cmd.waiters != nil {
+		cmd.waiters.Add(1)
+		defer cmd.waiters.Add(-1)
+	}
+	select {
+	case <-cmd.Done():
+		return cmd.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stored 下载到离线程序的指令，下载时不执行，视为已完成
+func Stored(index uint64) *QueuedCommand {
+	return &QueuedCommand{index: index, done: closedChannel}
+}
+
+var closedChannel = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// tracker 跟踪设备队列当前索引，索引前进时唤醒所有到达的等待者；
+// 仅在有等待者时轮询，多个等待者共用一路轮询
+type tracker struct {
+	mutex    sync.Mutex
+	index    uint64 // 最近一次读到的队列当前索引
+	pending  []*QueuedCommand
+	advanced chan struct{} // 索引前进时关闭并替换
+	cancel   func()
+	waiters  atomic.Int32 // 阻塞在 Wait 或 WaitAdvance 中的调用方数
+}
+
+// Track 跟踪已入队的指令
+func (connector *Connector) Track(index uint64) *QueuedCommand {
+	track := &connector.tracker
+	cmd := &QueuedCommand{index: index, done: make(chan struct{}), waiters: &track.waiters}
+	track.mutex.Lock()
+	defer track.mutex.Unlock()
+	if connector.Err() != nil {
+		cmd.err = connector.Err()
+		close(cmd.done)
+		return cmd
+	}
+	track.pending = append(track.pending, cmd)
+	if track.cancel == nil && connector.scheduler != nil {
+		track.cancel = connector.Poll(protocol.ProtocolQueuedCmdCurrentIndex, trackInterval, func(message *protocol.Message) {
+			connector.advance(message.Uint64())
+		})
+	}
+	return cmd
+}
+
+// OnComplete 指令完成或无法完成时在独立协程中调用 callback，err 为 nil 表示已执行
+func (connector *Connector) OnComplete(cmd *QueuedCommand, callback func(err error)) {
+	if cmd == nil {
+		go callback(nil)
+		return
+	}
+	track := &connector.tracker
+	track.mutex.Lock()
+	select {
+	case <-cmd.done:
+		track.mutex.Unlock()
+		go callback(cmd.err)
+		return
+	default:
+	}
+	cmd.callbacks = append(cmd.callbacks, callback)
+	track.mutex.Unlock()
+}
+
+// WaitAdvance 等待队列索引前进，用于队列已满时等待腾出空间；等待期间看门狗视调用方仍在工作
+func (connector *Connector) WaitAdvance(ctx context.Context) error {
+	track := &connector.tracker
+	track.waiters.Add(1)
+	defer track.waiters.Add(-1)
+	track.mutex.Lock()
+	if track.advanced == nil {
+		track.advanced = make(chan struct{})
+	}
+	advanced := track.advanced
+	track.mutex.Unlock()
+	cancel := connector.Poll(protocol.ProtocolQueuedCmdCurrentIndex, trackInterval, func(message *protocol.Message) {
+		connector.advance(message.Uint64())
+	})
+	defer cancel()
+	select {
+	case <-advanced:
+		return nil
+	case <-connector.Done():
+		return connector.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// advance 记录队列当前索引，完成已到达的指令
+func (connector *Connector) advance(index uint64) {
+	track := &connector.tracker
+	track.mutex.Lock()
+	defer track.mutex.Unlock()
+	if index < track.index {
+		// 设备重启后索引归零，之前入队的指令不会再执行
+		track.finish(func(*QueuedCommand) bool { return true }, ErrDisconnected)
+	}
+	if index != track.index && track.advanced != nil {
+		close(track.advanced)
+		track.advanced = nil
+	}
+	track.index = index
+	track.finish(func(cmd *QueuedCommand) bool { return cmd.index <= index }, nil)
+	if len(track.pending) == 0 && track.cancel != nil {
+		track.cancel()
+		track.cancel = nil
+	}
+}
+
+// discard 清空队列或连接断开，以 err 结束所有等待中的指令
+func (connector *Connector) discard(err error) {
+	track := &connector.tracker
+	track.mutex.Lock()
+	defer track.mutex.Unlock()
+	track.finish(func(*QueuedCommand) bool { return true }, err)
+	if track.cancel != nil {
+		track.cancel()
+		track.cancel = nil
+	}
+}
+
+// finish 以 err 结束满足条件的指令，调用方持有锁
+func (track *tracker) finish(match func(*QueuedCommand) bool, err error) {
+	pending := track.pending[:0]
+	for _, cmd := range track.pending {
+		if !match(cmd) {
+			pending = append(pending, cmd)
+			continue
+		}
+		cmd.err = err
+		close(cmd.done)
+		for _, callback := range cmd.callbacks {
+			go callback(err)
+		}
+		cmd.callbacks = nil
+	}
+	clear(track.pending[len(pending):])
+	track.pending = pending
+}
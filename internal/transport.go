@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// Transport 传输层，任何可读写关闭的字节流都可以承载协议帧
+type Transport interface {
+	io.ReadWriteCloser
+}
+
+// TransportOpener 根据地址打开传输层
+type TransportOpener func(address *url.URL) (Transport, error)
+
+var (
+	transportsMutex sync.RWMutex
+	transports      = map[string]TransportOpener{}
+)
+
+func init() {
+	RegisterTransport("serial", openSerial)
+	RegisterTransport("udp", openNet)
+	RegisterTransport("tcp", openNet)
+	RegisterTransport("pipe", openPipe)
+	RegisterTransport("replay", openReplay)
+}
+
+// RegisterTransport 注册传输层，scheme 重复注册时覆盖之前的实现
+func RegisterTransport(scheme string, opener TransportOpener) {
+	transportsMutex.Lock()
+	defer transportsMutex.Unlock()
+	transports[strings.ToLower(scheme)] = opener
+}
+
+// ParseAddress 解析地址，兼容旧的 "/dev/xxx" 与 "ip:port" 写法
+func ParseAddress(address string) (*url.URL, error) {
+	if !strings.Contains(address, "://") {
+		if strings.HasPrefix(address, "/dev/") || strings.HasPrefix(strings.ToUpper(address), "COM") {
+			return &url.URL{Scheme: "serial", Path: address}, nil
+		}
+		return &url.URL{Scheme: "udp", Host: address}, nil
+	}
+	return url.Parse(address)
+}
+
+// OpenTransport 按地址的 scheme 打开传输层
+func OpenTransport(address string) (Transport, error) {
+	addr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	transportsMutex.RLock()
+	opener, ok := transports[strings.ToLower(addr.Scheme)]
+	transportsMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported transport: %s", addr.Scheme)
+	}
+	return opener(addr)
+}
+
+// openSerial serial:///dev/ttyUSB0?baud=115200 或 serial://COM3
+func openSerial(address *url.URL) (Transport, error) {
+	name := address.Host + address.Path
+	if name == "" {
+		return nil, errors.New("invalid address: empty serial port")
+	}
+	baudrate := 115200
+	if baud := address.Query().Get("baud"); baud != "" {
+		var err error
+		if baudrate, err = strconv.Atoi(baud); err != nil {
+			return nil, fmt.Errorf("invalid baud: %s", baud)
+		}
+	}
+	mode := &serial.Mode{
+		BaudRate: baudrate,
+		DataBits: 8,
+		Parity:   serial.NoParity,
+		StopBits: serial.OneStopBit,
+	}
+	return serial.Open(name, mode)
+}
+
+// openNet udp://192.168.1.5:8899 或 tcp://host:port?timeout=5s
+func openNet(address *url.URL) (Transport, error) {
+	if address.Host == "" {
+		return nil, errors.New("invalid address: empty host")
+	}
+	timeout := 5 * time.Second
+	if value := address.Query().Get("timeout"); value != "" {
+		var err error
+		if timeout, err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %s", value)
+		}
+	}
+	return net.DialTimeout(address.Scheme, address.Host, timeout)
+}
+
+// pipeListener 进程内管道监听，pipe://name 连接到同名监听
+type pipeListener struct {
+	name   string
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+var (
+	pipesMutex sync.Mutex
+	pipes      = map[string]*pipeListener{}
+)
+
+type pipeAddr string
+
+func (addr pipeAddr) Network() string { return "pipe" }
+func (addr pipeAddr) String() string  { return string(addr) }
+
+// ListenPipe 监听进程内管道，设备端（模拟器、测试桩）通过 Accept 获取连接
+func ListenPipe(name string) (net.Listener, error) {
+	pipesMutex.Lock()
+	defer pipesMutex.Unlock()
+	if _, ok := pipes[name]; ok {
+		return nil, fmt.Errorf("pipe already listening: %s", name)
+	}
+	listener := &pipeListener{name: name, conns: make(chan net.Conn), closed: make(chan struct{})}
+	pipes[name] = listener
+	return listener, nil
+}
+
+func (listener *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-listener.conns:
+		return conn, nil
+	case <-listener.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (listener *pipeListener) Close() error {
+	listener.once.Do(func() {
+		pipesMutex.Lock()
+		delete(pipes, listener.name)
+		pipesMutex.Unlock()
+		close(listener.closed)
+	})
+	return nil
+}
+
+func (listener *pipeListener) Addr() net.Addr {
+	return pipeAddr(listener.name)
+}
+
+// openPipe pipe://name
+func openPipe(address *url.URL) (Transport, error) {
+	name := address.Host + address.Path
+	pipesMutex.Lock()
+	listener, ok := pipes[name]
+	pipesMutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("pipe not listening: %s", name)
+	}
+	client, server := net.Pipe()
+	select {
+	case listener.conns <- server:
+		return client, nil
+	case <-listener.closed:
+		client.Close()
+		server.Close()
+		return nil, net.ErrClosed
+	}
+}
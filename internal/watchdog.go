@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// watchdogPollInterval 看门狗轮询队列当前索引的间隔
+const watchdogPollInterval = 200 * time.Millisecond
+
+// WatchdogPolicy 链路看门狗策略，仅在队列指令执行期间检查
+type WatchdogPolicy struct {
+	HostTimeout time.Duration           // 调用方两次指令（或 Feed）的最长间隔，阻塞在 Wait 或 WaitAdvance 中的时间不计，0 不检查
+	LinkTimeout time.Duration           // 距最后一次收到应答的最长时间，0 不检查
+	MaxTimeouts int                     // 连续超时的请求数上限，0 不检查
+	ForceStop   bool                    // 使用 ForceStopExec 立即停止，否则 StopExec 在当前指令完成后停止
+	OnFault     func(fault *FaultError) // 触发时回调，在独立协程中调用
+}
+
+// watchdog 跟踪调用方与设备的活动，超过策略阈值时进入故障状态
+type watchdog struct {
+	mutex     sync.Mutex
+	policy    *WatchdogPolicy
+	lastHost  time.Time
+	lastReply time.Time
+	timeouts  int
+	queued    uint64 // 最后一条已发送队列指令的索引
+	executed  uint64 // 设备队列当前索引
+	paused    bool   // 队列已停止执行
+	storing   bool   // 正在下载离线程序，队列指令只存储不执行
+	fault     *FaultError
+	cancel    func() // 取消队列索引轮询
+}
+
+// moving 队列中仍有未执行的指令
+func (dog *watchdog) moving() bool {
+	return !dog.paused && dog.queued > dog.executed
+}
+
+// host 记录调用方发出的指令
+func (dog *watchdog) host(message *protocol.Message, now time.Time) {
+	dog.mutex.Lock()
+	defer dog.mutex.Unlock()
+	dog.lastHost = now
+	if !message.RW || message.IsQueued {
+		return
+	}
+	switch message.Id {
+	case protocol.ProtocolQueuedCmdStartExec:
+		dog.paused = false
+	case protocol.ProtocolQueuedCmdStopExec, protocol.ProtocolQueuedCmdForceStopExec:
+		dog.paused = true
+	case protocol.ProtocolQueuedCmdClear:
+		dog.queued = dog.executed
+	case protocol.ProtocolQueuedCmdStartDownload:
+		dog.storing = true
+	case protocol.ProtocolQueuedCmdStopDownload:
+		// 下载的指令不会在线执行，不计入待执行
+		dog.storing = false
+		dog.queued = dog.executed
+	}
+}
+
+// replied 记录收到的应答，reply 为调用方队列指令的应答时记录其索引
+func (dog *watchdog) replied(request, reply *protocol.Message, now time.Time) {
+	dog.mutex.Lock()
+	defer dog.mutex.Unlock()
+	dog.lastReply = now
+	dog.timeouts = 0
+	if request.IsQueued && !dog.storing {
+		dog.queued = max(dog.queued, reply.Uint64())
+	}
+}
+
+// timedOut 记录重发后仍无应答的请求
+func (dog *watchdog) timedOut() {
+	dog.mutex.Lock()
+	dog.timeouts++
+	dog.mutex.Unlock()
+}
+
+// refused 故障状态下拒绝的指令，返回 nil 表示允许发送
+func (dog *watchdog) refused(message *protocol.Message) *FaultError {
+	dog.mutex.Lock()
+	defer dog.mutex.Unlock()
+	if dog.fault == nil {
+		return nil
+	}
+	if refusedOnAlarm(message) || (message.RW && message.Id == protocol.ProtocolQueuedCmdStartExec) {
+		return dog.fault
+	}
+	return nil
+}
+
+// check 检查是否超过阈值，触发时进入故障状态并返回故障
+func (dog *watchdog) check(now time.Time) (*WatchdogPolicy, *FaultError) {
+	dog.mutex.Lock()
+	defer dog.mutex.Unlock()
+	policy := dog.policy
+	if policy == nil || dog.fault != nil || !dog.moving() {
+		return nil, nil
+	}
+	var cause error
+	switch {
+	case policy.HostTimeout > 0 && now.Sub(dog.lastHost) > policy.HostTimeout:
+		cause = ErrHostStalled
+	case policy.LinkTimeout > 0 && now.Sub(dog.lastReply) > policy.LinkTimeout:
+		cause = ErrLinkDegraded
+	case policy.MaxTimeouts > 0 && dog.timeouts >= policy.MaxTimeouts:
+		cause = ErrLinkDegraded
+	default:
+		return nil, nil
+	}
+	dog.fault = &FaultError{Cause: cause, At: now}
+	dog.paused = true
+	return policy, dog.fault
+}
+
+// SetWatchdog 设置链路看门狗策略，nil 关闭看门狗；需在连接建立后调用
+func (connector *Connector) SetWatchdog(policy *WatchdogPolicy) {
+	dog := &connector.watchdog
+	var cancel func()
+	if policy != nil && connector.scheduler != nil {
+		cancel = connector.Poll(protocol.ProtocolQueuedCmdCurrentIndex, watchdogPollInterval, func(message *protocol.Message) {
+			dog.mutex.Lock()
+			dog.executed = message.Uint64()
+			dog.mutex.Unlock()
+		})
+	}
+	now := time.Now()
+	dog.mutex.Lock()
+	previous := dog.cancel
+	dog.policy = policy
+	dog.cancel = cancel
+	dog.lastHost = now
+	dog.lastReply = now
+	dog.timeouts = 0
+	dog.mutex.Unlock()
+	if previous != nil {
+		previous()
+	}
+}
+
+// alive 记录调用方仍在工作
+func (dog *watchdog) alive(now time.Time) {
+	dog.mutex.Lock()
+	dog.lastHost = now
+	dog.mutex.Unlock()
+}
+
+// Feed 表明调用方仍在工作，长时间既不发指令也不在 Wait 中等待时（如等待用户输入、仅监听 Done 通道）
+// 定期调用以免触发看门狗
+func (connector *Connector) Feed() {
+	if connector == nil {
+		return
+	}
+	connector.watchdog.alive(time.Now())
+}
+
+// Fault 看门狗触发的故障，未触发时返回 nil
+func (connector *Connector) Fault() *FaultError {
+	if connector == nil {
+		return nil
+	}
+	connector.watchdog.mutex.Lock()
+	defer connector.watchdog.mutex.Unlock()
+	return connector.watchdog.fault
+}
+
+// Rearm 清除故障状态，恢复接受运动指令；队列需重新 StartExec 后执行
+func (connector *Connector) Rearm() {
+	if connector == nil {
+		return
+	}
+	now := time.Now()
+	dog := &connector.watchdog
+	dog.mutex.Lock()
+	dog.fault = nil
+	dog.timeouts = 0
+	dog.lastHost = now
+	dog.lastReply = now
+	dog.mutex.Unlock()
+}
+
+// watch 检查看门狗，触发时取消等待中的队列指令并停止设备队列执行，返回的错误表示连接已不可用
+func (connector *Connector) watch(now time.Time) error {
+	if connector.tracker.waiters.Load() > 0 {
+		// 调用方阻塞在 Wait 或 WaitAdvance 中等待执行，视为仍在工作
+		connector.watchdog.alive(now)
+	}
+	policy, fault := connector.watchdog.check(now)
+	if fault == nil {
+		return nil
+	}
+	if policy.OnFault != nil {
+		go policy.OnFault(fault)
+	}
+	connector.pipeline.rejectQueued(fault)
+	stop := &protocol.Message{Id: protocol.ProtocolQueuedCmdStopExec, RW: true, IsQueued: false}
+	if policy.ForceStop {
+		stop.Id = protocol.ProtocolQueuedCmdForceStopExec
+	}
+	return connector.transmit(&pending{message: stop, timeout: urgentTimeout})
+}
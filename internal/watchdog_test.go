@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// TestWatchdog 队列执行期间调用方停止发送指令超过 HostTimeout 时强制停止队列并拒绝运动指令，Rearm 后恢复
+func TestWatchdog(t *testing.T) {
+	connector, w := newTestConnector()
+	connector.pipeline.leftSpace = 5
+	faults := make(chan *FaultError, 1)
+	connector.SetWatchdog(&WatchdogPolicy{HostTimeout: 100 * time.Millisecond, ForceStop: true, OnFault: func(fault *FaultError) {
+		faults <- fault
+	}})
+	for _, outmsg := range []*outMessage{
+		request(protocol.ProtocolQueuedCmdStartExec, true, false),
+		request(protocol.ProtocolPTPCmd, true, true),
+	} {
+		if err := connector.submit(outmsg); err != nil {
+			t.Fatal(err)
+		}
+		if err := connector.dispatch(indexReply(outmsg.Id, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	if err := connector.watch(now.Add(50 * time.Millisecond)); err != nil || connector.Fault() != nil {
+		t.Fatalf("fault %v, error %v before the host timeout", connector.Fault(), err)
+	}
+	// 阻塞在 Wait 中的调用方视为仍在工作
+	connector.tracker.waiters.Add(1)
+	if err := connector.watch(now.Add(time.Second)); err != nil || connector.Fault() != nil {
+		t.Fatalf("fault %v, error %v while the host waits", connector.Fault(), err)
+	}
+	connector.tracker.waiters.Add(-1)
+
+	if err := connector.watch(now.Add(2 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	fault := connector.Fault()
+	if fault == nil || !errors.Is(fault, ErrHostStalled) {
+		t.Fatalf("fault %v, want host stalled", fault)
+	}
+	if got := w.count(protocol.ProtocolQueuedCmdForceStopExec); got != 1 {
+		t.Errorf("sent %d force stops, want 1", got)
+	}
+	select {
+	case got := <-faults:
+		if got != fault {
+			t.Errorf("OnFault got %v, want %v", got, fault)
+		}
+	case <-time.After(time.Second):
+		t.Error("OnFault not called")
+	}
+	// 故障期间拒绝运动与启动队列，读指令不受影响
+	for _, outmsg := range []*outMessage{
+		request(protocol.ProtocolPTPCmd, true, true),
+		request(protocol.ProtocolQueuedCmdStartExec, true, false),
+	} {
+		if err := connector.submit(outmsg); err != nil {
+			t.Fatal(err)
+		}
+		var faultErr *FaultError
+		if ack := answered(outmsg); ack == nil || !errors.As(ack.Error, &faultErr) {
+			t.Errorf("command %d answered with %+v during fault, want FaultError", outmsg.Id, ack)
+		}
+	}
+	pose := request(protocol.ProtocolGetPose, false, false)
+	if err := connector.submit(pose); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.count(protocol.ProtocolGetPose); got != 1 {
+		t.Errorf("pose query sent %d times during fault, want 1", got)
+	}
+
+	connector.Rearm()
+	if connector.Fault() != nil {
+		t.Fatal("fault kept after Rearm")
+	}
+	sent := w.count(protocol.ProtocolPTPCmd)
+	cmd := request(protocol.ProtocolPTPCmd, true, true)
+	if err := connector.submit(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if answered(cmd) != nil || w.count(protocol.ProtocolPTPCmd) != sent+1 {
+		t.Error("motion command not sent after Rearm")
+	}
+}
@@ -0,0 +1,43 @@
+package godobot
+
+import (
+	"context"
+
+	"github.com/zdypro888/godobot/kinematics"
+)
+
+// Position 位姿的笛卡尔部分，l 为滑轨位置
+func (pose *Pose) Position(l float32) kinematics.Position {
+	return kinematics.Position{X: float64(pose.X), Y: float64(pose.Y), Z: float64(pose.Z), R: float64(pose.R), L: float64(l)}
+}
+
+// Joints 位姿的关节部分，l 为滑轨位置
+func (pose *Pose) Joints(l float32) kinematics.Joints {
+	var joints kinematics.Joints
+	for i, angle := range pose.JointAngle {
+		joints.Angle[i] = float64(angle)
+	}
+	joints.L = float64(l)
+	return joints
+}
+
+// KinematicsModel 按设备当前的末端执行器偏移、机械臂方向与滑轨配置构造运动学模型
+func (dobot *Dobot) KinematicsModel(ctx context.Context) (*kinematics.Model, error) {
+	params, err := dobot.GetEndEffectorParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orientation, err := dobot.GetArmOrientation(ctx)
+	if err != nil {
+		return nil, err
+	}
+	withL, err := dobot.GetDeviceWithL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	model := kinematics.Magician()
+	model.Bias = [3]float64{float64(params.XBias), float64(params.YBias), float64(params.ZBias)}
+	model.Orientation = kinematics.Orientation(orientation)
+	model.Rail = withL
+	return model, nil
+}
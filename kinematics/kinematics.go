@@ -0,0 +1,209 @@
+// Package kinematics Dobot Magician 正逆运动学
+//
+// 关节 J1 为底座旋转，J2 为大臂与竖直方向的夹角，J3 为小臂与水平方向的夹角（平行四边形机构，
+// 与 J2 相互独立），J4 为末端旋转。角度单位为度，长度单位为毫米，L 为滑轨位置
+package kinematics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Magician 连杆长度与滑轨行程（毫米）
+const (
+	RearArmLength  = 135.0
+	FrontArmLength = 147.0
+	RailLength     = 1000.0
+)
+
+// 奇异判定阈值
+const (
+	singularRadius = 1.0  // 末端距底座轴线的最小距离（毫米）
+	singularCos    = 0.02 // 大小臂夹角余弦的最小值，约 1.1 度
+)
+
+var (
+	ErrUnreachable = errors.New("target out of reach")
+	ErrSingular    = errors.New("singular configuration")
+)
+
+// MagicianLimits Magician 关节限位（度）
+var MagicianLimits = [4][2]float64{
+	{-90, 90},
+	{0, 85},
+	{-10, 90},
+	{-90, 90},
+}
+
+// Orientation 肘部构型，与 ArmOrientation 取值一致
+type Orientation uint8
+
+const (
+	Lefty  Orientation = iota // 肘部朝上，Magician 的默认构型
+	Righty                    // 肘部朝下
+)
+
+// Joints 关节角（度）与滑轨位置（毫米）
+type Joints struct {
+	Angle [4]float64
+	L     float64
+}
+
+// Position 末端位姿：坐标（毫米）、末端旋转（度）与滑轨位置（毫米）
+type Position struct {
+	X, Y, Z, R float64
+	L          float64
+}
+
+// LimitError 超出关节或滑轨限位，Axis 0~3 为关节，4 为滑轨
+type LimitError struct {
+	Axis  int
+	Value float64
+	Min   float64
+	Max   float64
+}
+
+func (err *LimitError) Error() string {
+	if err.Axis == RailAxis {
+		return fmt.Sprintf("rail %.2f out of range [%g, %g]", err.Value, err.Min, err.Max)
+	}
+	return fmt.Sprintf("joint %d %.2f out of range [%g, %g]", err.Axis+1, err.Value, err.Min, err.Max)
+}
+
+// RailAxis LimitError 中滑轨的轴序号
+const RailAxis = 4
+
+// Model 运动学模型
+type Model struct {
+	RearArm     float64       // 大臂长度
+	FrontArm    float64       // 小臂长度
+	Bias        [3]float64    // 末端执行器偏移，对应 EndEffectorParams
+	Orientation Orientation   // 逆解构型，对应 ArmOrientation
+	Limits      [4][2]float64 // 关节限位
+	Rail        bool          // 是否安装滑轨，对应 DeviceWithL
+	RailLimits  [2]float64    // 滑轨行程
+}
+
+// Magician 默认模型：无末端偏移、Lefty 构型、无滑轨
+func Magician() *Model {
+	return &Model{
+		RearArm:    RearArmLength,
+		FrontArm:   FrontArmLength,
+		Limits:     MagicianLimits,
+		RailLimits: [2]float64{0, RailLength},
+	}
+}
+
+func deg2rad(deg float64) float64 { return deg * math.Pi / 180 }
+func rad2deg(rad float64) float64 { return rad * 180 / math.Pi }
+
+// radius 末端在水平面上距底座轴线的距离
+func (model *Model) radius(joints Joints) float64 {
+	j2, j3 := deg2rad(joints.Angle[1]), deg2rad(joints.Angle[2])
+	return model.RearArm*math.Sin(j2) + model.FrontArm*math.Cos(j3) + model.Bias[0]
+}
+
+// Forward 由关节角计算末端位姿
+func (model *Model) Forward(joints Joints) Position {
+	j1, j2, j3 := deg2rad(joints.Angle[0]), deg2rad(joints.Angle[1]), deg2rad(joints.Angle[2])
+	radius := model.radius(joints)
+	return Position{
+		X: radius*math.Cos(j1) - model.Bias[1]*math.Sin(j1),
+		Y: radius*math.Sin(j1) + model.Bias[1]*math.Cos(j1),
+		Z: model.RearArm*math.Cos(j2) - model.FrontArm*math.Sin(j3) + model.Bias[2],
+		R: joints.Angle[0] + joints.Angle[3],
+		L: joints.L,
+	}
+}
+
+// Inverse 由末端位姿计算关节角，不可达时返回 ErrUnreachable，目标位于底座轴线上（J1 不确定）时返回 ErrSingular，不检查限位
+func (model *Model) Inverse(position Position) (Joints, error) {
+	var joints Joints
+	// 末端 Y 偏移使腕部中心偏离过目标点的径向线
+	planar := math.Hypot(position.X, position.Y)
+	if planar < math.Abs(model.Bias[1]) {
+		return joints, ErrUnreachable
+	}
+	if planar == 0 {
+		return joints, ErrSingular
+	}
+	offset := math.Asin(model.Bias[1] / planar)
+	j1 := math.Atan2(position.Y, position.X) - offset
+	radius := planar*math.Cos(offset) - model.Bias[0]
+	height := position.Z - model.Bias[2]
+	distance := math.Hypot(radius, height)
+	if distance > model.RearArm+model.FrontArm || distance < math.Abs(model.RearArm-model.FrontArm) || distance == 0 {
+		return joints, ErrUnreachable
+	}
+	gamma := math.Atan2(height, radius)
+	beta := math.Acos((model.RearArm*model.RearArm + distance*distance - model.FrontArm*model.FrontArm) / (2 * model.RearArm * distance))
+	theta1 := gamma + beta
+	if model.Orientation == Righty {
+		theta1 = gamma - beta
+	}
+	theta2 := math.Atan2(height-model.RearArm*math.Sin(theta1), radius-model.RearArm*math.Cos(theta1))
+	joints.Angle[0] = rad2deg(j1)
+	joints.Angle[1] = 90 - rad2deg(theta1)
+	joints.Angle[2] = -rad2deg(theta2)
+	joints.Angle[3] = position.R - joints.Angle[0]
+	joints.L = position.L
+	return joints, nil
+}
+
+// Check 检查关节与滑轨限位，超限时返回 *LimitError
+func (model *Model) Check(joints Joints) error {
+	for axis, limit := range model.Limits {
+		if value := joints.Angle[axis]; value < limit[0] || value > limit[1] {
+			return &LimitError{Axis: axis, Value: value, Min: limit[0], Max: limit[1]}
+		}
+	}
+	if model.Rail && (joints.L < model.RailLimits[0] || joints.L > model.RailLimits[1]) {
+		return &LimitError{Axis: RailAxis, Value: joints.L, Min: model.RailLimits[0], Max: model.RailLimits[1]}
+	}
+	return nil
+}
+
+// Reachable 目标位姿可达且在限位内时返回关节角
+func (model *Model) Reachable(position Position) (Joints, error) {
+	joints, err := model.Inverse(position)
+	if err != nil {
+		return joints, err
+	}
+	return joints, model.Check(joints)
+}
+
+// Jacobian 末端坐标 (X, Y, Z) 对关节角 (J1, J2, J3) 的雅可比矩阵，单位为毫米/弧度
+func (model *Model) Jacobian(joints Joints) [3][3]float64 {
+	j1, j2, j3 := deg2rad(joints.Angle[0]), deg2rad(joints.Angle[1]), deg2rad(joints.Angle[2])
+	radius := model.radius(joints)
+	sin1, cos1 := math.Sin(j1), math.Cos(j1)
+	dRadius2 := model.RearArm * math.Cos(j2)
+	dRadius3 := -model.FrontArm * math.Sin(j3)
+	return [3][3]float64{
+		{-radius*sin1 - model.Bias[1]*cos1, cos1 * dRadius2, cos1 * dRadius3},
+		{radius*cos1 - model.Bias[1]*sin1, sin1 * dRadius2, sin1 * dRadius3},
+		{0, -model.RearArm * math.Sin(j2), -model.FrontArm * math.Cos(j3)},
+	}
+}
+
+// Manipulability 雅可比行列式的绝对值，趋近 0 时接近奇异
+func (model *Model) Manipulability(joints Joints) float64 {
+	m := model.Jacobian(joints)
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	return math.Abs(det)
+}
+
+// Singularity 末端位于底座轴线上或大小臂共线时返回 ErrSingular
+func (model *Model) Singularity(joints Joints) error {
+	if math.Abs(model.radius(joints)) < singularRadius {
+		return ErrSingular
+	}
+	// 雅可比行列式正比于 cos(J2-J3)，为 0 时大小臂共线
+	if math.Abs(math.Cos(deg2rad(joints.Angle[1]-joints.Angle[2]))) < singularCos {
+		return ErrSingular
+	}
+	return nil
+}
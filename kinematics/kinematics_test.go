@@ -0,0 +1,167 @@
+package kinematics
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+const tolerance = 1e-6
+
+func near(a, b Position) bool {
+	return math.Abs(a.X-b.X) < tolerance && math.Abs(a.Y-b.Y) < tolerance && math.Abs(a.Z-b.Z) < tolerance &&
+		math.Abs(math.Remainder(a.R-b.R, 360)) < tolerance && math.Abs(a.L-b.L) < tolerance
+}
+
+func models() map[string]*Model {
+	biased := Magician()
+	biased.Bias = [3]float64{59.7, 3, -12}
+	righty := Magician()
+	righty.Orientation = Righty
+	return map[string]*Model{"magician": Magician(), "biased": biased, "righty": righty}
+}
+
+// TestRoundTripWorkspace 工作空间网格上 Forward(Inverse(p)) 回到 p
+func TestRoundTripWorkspace(t *testing.T) {
+	for name, model := range models() {
+		solved := 0
+		for x := -100.0; x <= 320; x += 20 {
+			for y := -300.0; y <= 300; y += 20 {
+				for z := -120.0; z <= 200; z += 20 {
+					position := Position{X: x, Y: y, Z: z, R: 30, L: 100}
+					joints, err := model.Inverse(position)
+					if errors.Is(err, ErrUnreachable) || errors.Is(err, ErrSingular) {
+						continue
+					}
+					if err != nil {
+						t.Fatalf("%s: Inverse(%+v): %v", name, position, err)
+					}
+					solved++
+					if got := model.Forward(joints); !near(got, position) {
+						t.Errorf("%s: Forward(Inverse(%+v)) = %+v", name, position, got)
+					}
+				}
+			}
+		}
+		if solved < 500 {
+			t.Errorf("%s: only %d reachable grid points", name, solved)
+		}
+	}
+}
+
+// TestRoundTripJoints 限位内的关节角经正解、对应构型的逆解后回到原关节角：
+// J2-J3 小于 90 度时肘部在大小臂连线上方（Lefty），大于 90 度时在下方（Righty）
+func TestRoundTripJoints(t *testing.T) {
+	model := Magician()
+	for j1 := -90.0; j1 <= 90; j1 += 15 {
+		for j2 := 0.0; j2 <= 85; j2 += 5 {
+			for j3 := -10.0; j3 <= 90; j3 += 5 {
+				joints := Joints{Angle: [4]float64{j1, j2, j3, 20}}
+				if model.Singularity(joints) != nil {
+					continue
+				}
+				model.Orientation = Lefty
+				if j2-j3 > 90 {
+					model.Orientation = Righty
+				}
+				got, err := model.Inverse(model.Forward(joints))
+				if err != nil {
+					t.Fatalf("Inverse(Forward(%v)): %v", joints.Angle, err)
+				}
+				for axis := range joints.Angle {
+					if math.Abs(got.Angle[axis]-joints.Angle[axis]) > 1e-6 {
+						t.Fatalf("%v: Inverse(Forward(%v)) = %v", model.Orientation, joints.Angle, got.Angle)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	biased := Magician()
+	biased.Bias[1] = 20
+	tests := []struct {
+		name     string
+		model    *Model
+		position Position
+	}{
+		{"beyond full extension", Magician(), Position{X: RearArmLength + FrontArmLength + 1}},
+		{"far above", Magician(), Position{X: 100, Z: 300}},
+		{"inside inner radius", Magician(), Position{X: 5, Z: 5}},
+		{"inside tool offset", biased, Position{X: 10, Y: 5, Z: 50}},
+	}
+	for _, test := range tests {
+		if _, err := test.model.Inverse(test.position); !errors.Is(err, ErrUnreachable) {
+			t.Errorf("%s: Inverse(%+v) error = %v, want ErrUnreachable", test.name, test.position, err)
+		}
+	}
+	// 底座轴线上 J1 不确定
+	for name, model := range models() {
+		if _, err := model.Inverse(Position{Z: 50}); err == nil {
+			t.Errorf("%s: Inverse on base axis succeeded", name)
+		}
+	}
+	if _, err := Magician().Inverse(Position{Z: 50}); !errors.Is(err, ErrSingular) {
+		t.Errorf("Inverse on base axis error = %v, want ErrSingular", err)
+	}
+	// 可达但超出限位
+	joints, err := Magician().Reachable(Position{X: -200, Y: 0, Z: 0})
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Axis != 0 {
+		t.Errorf("Reachable behind base = %v, %v, want joint 1 limit error", joints.Angle, err)
+	}
+}
+
+func TestSingularity(t *testing.T) {
+	tests := []struct {
+		name     string
+		angle    [4]float64
+		singular bool
+	}{
+		{"home", [4]float64{0, 45, 45, 0}, false},
+		{"typical", [4]float64{30, 20, 40, 0}, false},
+		{"end on base axis", [4]float64{0, 0, 90, 0}, true},
+		{"arms collinear", [4]float64{0, 30, -60, 0}, true},
+		{"near collinear", [4]float64{0, 30, -59.5, 0}, true},
+	}
+	model := Magician()
+	for _, test := range tests {
+		joints := Joints{Angle: test.angle}
+		err := model.Singularity(joints)
+		if singular := errors.Is(err, ErrSingular); singular != test.singular {
+			t.Errorf("%s: Singularity(%v) = %v, want singular %v", test.name, test.angle, err, test.singular)
+		}
+		if test.name == "arms collinear" {
+			if m := model.Manipulability(joints); m > tolerance {
+				t.Errorf("%s: manipulability %g, want 0", test.name, m)
+			}
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	model := Magician()
+	model.Rail = true
+	tests := []struct {
+		joints Joints
+		axis   int // -1 表示在限位内
+	}{
+		{Joints{Angle: [4]float64{0, 45, 45, 0}, L: 500}, -1},
+		{Joints{Angle: [4]float64{95, 45, 45, 0}}, 0},
+		{Joints{Angle: [4]float64{0, -1, 45, 0}}, 1},
+		{Joints{Angle: [4]float64{0, 45, 91, 0}}, 2},
+		{Joints{Angle: [4]float64{0, 45, 45, 91}}, 3},
+		{Joints{Angle: [4]float64{0, 45, 45, 0}, L: RailLength + 1}, RailAxis},
+	}
+	for _, test := range tests {
+		err := model.Check(test.joints)
+		var limitErr *LimitError
+		switch {
+		case test.axis < 0 && err != nil:
+			t.Errorf("Check(%+v) = %v, want nil", test.joints, err)
+		case test.axis >= 0 && (!errors.As(err, &limitErr) || limitErr.Axis != test.axis):
+			t.Errorf("Check(%+v) = %v, want limit error on axis %d", test.joints, err, test.axis)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package godobot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MaxProgramLines 离线程序每次循环的最大行数，按控制器存储空间保守估计，固件不同时可调整
+var MaxProgramLines = 1000
+
+var (
+	ErrEmptyProgram     = errors.New("invalid params: empty program")
+	ErrProgramTooLarge  = errors.New("program too large")
+	ErrProgramIndex     = errors.New("offline program index not consecutive") // 控制器返回的队列索引不连续，可能有其它协程插入了指令
+	ErrProgramNotQueued = errors.New("program step is not a queued command")
+)
+
+// ProgramStep 离线程序的一行，须以 isQueued 为 true 调用一个队列指令
+type ProgramStep func(ctx context.Context, dobot *Dobot) (*QueuedCommand, error)
+
+// ProgramError 下载离线程序的第 Line 行（从 0 开始）失败
+type ProgramError struct {
+	Line int
+	Err  error
+}
+
+func (err *ProgramError) Error() string {
+	return fmt.Sprintf("program line %d: %v", err.Line, err.Err)
+}
+
+func (err *ProgramError) Unwrap() error {
+	return err.Err
+}
+
+// Program 由队列指令组成的离线程序，下载到控制器后可脱离主机按循环次数运行
+type Program struct {
+	Loops uint32 // 循环次数，0 视为 1
+	steps []ProgramStep
+}
+
+// NewProgram 创建循环 loops 次的离线程序
+func NewProgram(loops uint32) *Program {
+	return &Program{Loops: loops}
+}
+
+// Len 每次循环的行数
+func (program *Program) Len() int {
+	return len(program.steps)
+}
+
+// Add 追加一行
+func (program *Program) Add(step ProgramStep) *Program {
+	program.steps = append(program.steps, step)
+	return program
+}
+
+// PTP 追加点到点运动
+func (program *Program) PTP(cmd PTPCmd) *Program {
+	return program.Add(func(ctx context.Context, dobot *Dobot) (*QueuedCommand, error) {
+		return dobot.SetPTPCmd(ctx, &cmd, true)
+	})
+}
+
+// CP 追加连续轨迹运动
+func (program *Program) CP(cmd CPCmd) *Program {
+	return program.Add(func(ctx context.Context, dobot *Dobot) (*QueuedCommand, error) {
+		return dobot.SetCPCmd(ctx, &cmd, true)
+	})
+}
+
+// ARC 追加圆弧运动
+func (program *Program) ARC(cmd ARCCmd) *Program {
+	return program.Add(func(ctx context.Context, dobot *Dobot) (*QueuedCommand, error) {
+		return dobot.SetARCCmd(ctx, &cmd, true)
+	})
+}
+
+// Wait 追加等待，单位毫秒
+func (program *Program) Wait(timeout uint32) *Program {
+	return program.Add(func(ctx context.Context, dobot *Dobot) (*QueuedCommand, error) {
+		return dobot.SetWAITCmd(ctx, &WAITCmd{Timeout: timeout}, true)
+	})
+}
+
+// IODO 追加数字输出
+func (program *Program) IODO(address uint8, level uint8) *Program {
+	return program.Add(func(ctx context.Context, dobot *Dobot) (*QueuedCommand, error) {
+		return dobot.SetIODO(ctx, &IODO{Address: address, Level: level}, true)
+	})
+}
+
+// SuctionCup 追加吸盘开关
+func (program *Program) SuctionCup(suck bool) *Program {
+	return program.Add(func(ctx context.Context, dobot *Dobot) (*QueuedCommand, error) {
+		return dobot.SetEndEffectorSuctionCup(ctx, true, suck, true)
+	})
+}
+
+// Gripper 追加夹爪开合
+func (program *Program) Gripper(grip bool) *Program {
+	return program.Add(func(ctx context.Context, dobot *Dobot) (*QueuedCommand, error) {
+		return dobot.SetEndEffectorGripper(ctx, true, grip, true)
+	})
+}
+
+// Validate 检查程序行数
+func (program *Program) Validate() error {
+	if len(program.steps) == 0 {
+		return ErrEmptyProgram
+	}
+	if len(program.steps) > MaxProgramLines {
+		return fmt.Errorf("%w: %d lines, want <= %d", ErrProgramTooLarge, len(program.steps), MaxProgramLines)
+	}
+	return nil
+}
+
+// DownloadProgram 清空队列后将程序下载到控制器，断开主机后由控制器按循环次数离线运行。
+// 协议不支持读回已存储的程序内容，下载后读回设备的队列索引校验：清空后的队列索引为 base 时，
+// 第 i 行应得到索引 base+1+i，且下载期间设备没有执行任何指令。其它协程在下载期间发送的队列指令
+// 或一步发送了多条指令的程序行都会使索引错位，返回 ErrProgramIndex
+func (dobot *Dobot) DownloadProgram(ctx context.Context, program *Program) error {
+	if program == nil {
+		return errors.New("invalid params: program is nil")
+	}
+	if err := program.Validate(); err != nil {
+		return err
+	}
+	if err := dobot.SetQueuedCmdClear(ctx); err != nil {
+		return err
+	}
+	base, err := dobot.GetQueuedCmdCurrentIndex(ctx)
+	if err != nil {
+		return err
+	}
+	if err := dobot.SetQueuedCmdStartDownload(ctx, max(program.Loops, 1), uint32(len(program.steps))); err != nil {
+		return err
+	}
+	dobot.downloading.Store(true)
+	err = dobot.download(ctx, program, base)
+	dobot.downloading.Store(false)
+	// 下载的运动不会执行，规划器记录的位置已失效
+	dobot.workspace.Load().forget()
+	if stopErr := dobot.SetQueuedCmdStopDownload(context.WithoutCancel(ctx)); err == nil {
+		err = stopErr
+	}
+	if err != nil {
+		return err
+	}
+	current, err := dobot.GetQueuedCmdCurrentIndex(ctx)
+	if err != nil {
+		return err
+	}
+	if current != base {
+		return fmt.Errorf("%w: device executed up to index %d while downloading, want %d", ErrProgramIndex, current, base)
+	}
+	alarms, err := dobot.GetAlarmsState(ctx)
+	if err != nil {
+		return err
+	}
+	if !alarms.Empty() {
+		return &AlarmError{Alarms: alarms}
+	}
+	return nil
+}
+
+// download 逐行发送程序，检查控制器返回的队列索引是否紧接 base 连续
+func (dobot *Dobot) download(ctx context.Context, program *Program, base uint64) error {
+	for line, step := range program.steps {
+		cmd, err := dobot.QueuedSend(ctx, func(ctx context.Context) (*QueuedCommand, error) {
+			return step(ctx, dobot)
+		})
+		if err != nil {
+			return &ProgramError{Line: line, Err: err}
+		}
+		if cmd == nil {
+			return &ProgramError{Line: line, Err: ErrProgramNotQueued}
+		}
+		if want := base + 1 + uint64(line); cmd.Index() != want {
+			return &ProgramError{Line: line, Err: fmt.Errorf("%w: index %d, want %d", ErrProgramIndex, cmd.Index(), want)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package godobot_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot"
+)
+
+// TestDownloadProgram 下载后控制器存储的行数、循环次数与发送的一致，离线运行后执行完全部行
+func TestDownloadProgram(t *testing.T) {
+	sim, dobot := connectSimulator(t, "download-program", 100)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// 下载前队列中已有执行过的指令，索引不从 0 开始
+	if err := dobot.SetQueuedCmdStartExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := dobot.QueuedComplete(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+		return dobot.SetWAITCmd(ctx, &godobot.WAITCmd{Timeout: 1}, true)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	program := godobot.NewProgram(2).
+		PTP(godobot.PTPCmd{PTPMode: godobot.PTPMOVLXYZMode, X: 200, Y: 0, Z: 0}).
+		Wait(10).
+		PTP(godobot.PTPCmd{PTPMode: godobot.PTPMOVLXYZMode, X: 160, Y: 0, Z: 0})
+	if err := dobot.DownloadProgram(ctx, program); err != nil {
+		t.Fatal(err)
+	}
+	if loops, lines, commands := sim.Program(); loops != 2 || lines != 3 || commands != 3 {
+		t.Fatalf("device stored loops %d, lines %d, commands %d, want 2, 3, 3", loops, lines, commands)
+	}
+	start := sim.CurrentIndex()
+	if err := sim.RunProgram(); err != nil {
+		t.Fatal(err)
+	}
+	for sim.CurrentIndex() < start+6 {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("program stopped at index %d, want %d", sim.CurrentIndex(), start+6)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// TestDownloadProgramIndex 一行发送了多条队列指令时，控制器存储的行数与程序不符，应由队列索引发现
+func TestDownloadProgramIndex(t *testing.T) {
+	_, dobot := connectSimulator(t, "download-program-index", 100)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	program := godobot.NewProgram(1).Add(func(ctx context.Context, dobot *godobot.Dobot) (*godobot.QueuedCommand, error) {
+		if _, err := dobot.SetWAITCmd(ctx, &godobot.WAITCmd{Timeout: 1}, true); err != nil {
+			return nil, err
+		}
+		return dobot.SetWAITCmd(ctx, &godobot.WAITCmd{Timeout: 1}, true)
+	}).Wait(1)
+	err := dobot.DownloadProgram(ctx, program)
+	var programErr *godobot.ProgramError
+	if !errors.As(err, &programErr) || programErr.Line != 0 || !errors.Is(err, godobot.ErrProgramIndex) {
+		t.Errorf("DownloadProgram = %v, want ErrProgramIndex on line 0", err)
+	}
+}
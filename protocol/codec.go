@@ -0,0 +1,172 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	SyncByte       = 0xAA
+	MaxPayloadSize = SyncByte - 1       // 确保payload不大于SYNC_BYTE
+	MaxParamsSize  = MaxPayloadSize - 2 // payload 包含 id 与 ctrl
+	headerSize     = 3                  // 0xAA 0xAA len
+	minFrameSize   = headerSize + 2 + 1 // 头 + id ctrl + checksum
+)
+
+var (
+	ErrBadLength   = errors.New("protocol: bad frame length")
+	ErrBadChecksum = errors.New("protocol: bad checksum")
+	ErrTruncated   = errors.New("protocol: truncated frame")
+)
+
+// FrameError 帧错误，Data 为出错帧的原始字节
+type FrameError struct {
+	Err  error
+	Data []byte
+}
+
+func (err *FrameError) Error() string {
+	return fmt.Sprintf("%v: % x", err.Err, err.Data)
+}
+
+func (err *FrameError) Unwrap() error {
+	return err.Err
+}
+
+// checksum 计算 id、ctrl 与参数的校验和
+func checksum(data []byte) uint8 {
+	var sum uint8
+	for _, v := range data {
+		sum += v
+	}
+	return uint8(0) - sum
+}
+
+// Encode 编码一帧，参数超过 MaxParamsSize 时返回 nil
+func Encode(message *Message) []byte {
+	if len(message.Params) > MaxParamsSize {
+		return nil
+	}
+	frame := make([]byte, 0, minFrameSize+len(message.Params))
+	frame = append(frame, SyncByte, SyncByte, uint8(len(message.Params)+2), uint8(message.Id), message.Ctrl())
+	frame = append(frame, message.Params...)
+	return append(frame, checksum(frame[headerSize:]))
+}
+
+// Decoder 增量解码器，通过 Write 喂入任意分片的字节，Next 取出完整帧
+type Decoder struct {
+	buffer []byte
+}
+
+// NewDecoder 创建增量解码器
+func NewDecoder() *Decoder {
+	return &Decoder{}
+}
+
+// Write 喂入字节，总是全部接收
+func (decoder *Decoder) Write(data []byte) (int, error) {
+	decoder.buffer = append(decoder.buffer, data...)
+	return len(data), nil
+}
+
+// Buffered 尚未解码的字节数
+func (decoder *Decoder) Buffered() int {
+	return len(decoder.buffer)
+}
+
+// Next 取出下一帧
+//
+// 数据不足时返回 (nil, nil)；遇到长度或校验错误时返回 *FrameError，
+// 并从出错帧的下一个字节重新同步，可以继续调用 Next。
+func (decoder *Decoder) Next() (*Message, error) {
+	for {
+		// 跳到第一个同步字节
+		start := 0
+		for start < len(decoder.buffer) && decoder.buffer[start] != SyncByte {
+			start++
+		}
+		decoder.buffer = decoder.buffer[start:]
+		if len(decoder.buffer) < headerSize {
+			return nil, nil
+		}
+		if decoder.buffer[1] != SyncByte {
+			decoder.buffer = decoder.buffer[1:]
+			continue
+		}
+		length := decoder.buffer[2]
+		if length == SyncByte {
+			// 多余的同步字节
+			decoder.buffer = decoder.buffer[1:]
+			continue
+		}
+		if length < 2 || length > MaxPayloadSize {
+			data := append([]byte(nil), decoder.buffer[:headerSize]...)
+			decoder.buffer = decoder.buffer[1:]
+			return nil, &FrameError{Err: ErrBadLength, Data: data}
+		}
+		size := headerSize + int(length) + 1
+		if len(decoder.buffer) < size {
+			return nil, nil
+		}
+		frame := decoder.buffer[:size]
+		if checksum(frame[headerSize:size-1]) != frame[size-1] {
+			data := append([]byte(nil), frame...)
+			decoder.buffer = decoder.buffer[1:]
+			return nil, &FrameError{Err: ErrBadChecksum, Data: data}
+		}
+		message := &Message{Id: ProtocolId(frame[headerSize])}
+		message.SetCtrl(frame[headerSize+1])
+		message.Params = append([]byte{}, frame[headerSize+2:size-1]...)
+		decoder.buffer = decoder.buffer[size:]
+		return message, nil
+	}
+}
+
+// Flush 结束解码并清空缓冲区，应在 Next 返回 (nil, nil) 后调用；残留不完整帧时返回 ErrTruncated
+func (decoder *Decoder) Flush() error {
+	data := decoder.buffer
+	decoder.buffer = nil
+	for len(data) > 0 && data[0] != SyncByte {
+		data = data[1:]
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return &FrameError{Err: ErrTruncated, Data: data}
+}
+
+// Reader 从字节流中逐帧读取
+type Reader struct {
+	reader  io.Reader
+	decoder Decoder
+	buffer  []byte
+	err     error
+}
+
+// NewReader 创建帧读取器
+func NewReader(reader io.Reader) *Reader {
+	return &Reader{reader: reader, buffer: make([]byte, 512)}
+}
+
+// ReadMessage 读取下一帧
+//
+// 返回 *FrameError 时可以继续读取；字节流结束时残留的不完整帧返回 ErrTruncated，之后返回底层错误。
+func (reader *Reader) ReadMessage() (*Message, error) {
+	for {
+		if message, err := reader.decoder.Next(); message != nil || err != nil {
+			return message, err
+		}
+		if reader.err != nil {
+			if err := reader.decoder.Flush(); err != nil {
+				return nil, err
+			}
+			return nil, reader.err
+		}
+		n, err := reader.reader.Read(reader.buffer)
+		reader.decoder.Write(reader.buffer[:n])
+		if err != nil {
+			reader.err = err
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func frame(id ProtocolId, ctrl uint8, params ...byte) []byte {
+	message := &Message{Id: id, Params: params}
+	message.SetCtrl(ctrl)
+	return Encode(message)
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		message *Message
+		want    []byte
+	}{
+		{&Message{Id: ProtocolGetPose}, []byte{0xAA, 0xAA, 0x02, 0x0A, 0x00, 0xF6}},
+		{&Message{Id: ProtocolQueuedCmdStartExec, RW: true}, []byte{0xAA, 0xAA, 0x02, 0xF0, 0x01, 0x0F}},
+		{&Message{Id: ProtocolPTPCmd, RW: true, IsQueued: true, Params: []byte{1, 2}}, []byte{0xAA, 0xAA, 0x04, 0x54, 0x03, 0x01, 0x02, 0xA6}},
+		{&Message{Id: ProtocolGetPose, Params: make([]byte, MaxParamsSize+1)}, nil},
+	}
+	for _, test := range tests {
+		if got := Encode(test.message); !bytes.Equal(got, test.want) {
+			t.Errorf("Encode(%+v) = % X, want % X", test.message, got, test.want)
+		}
+	}
+}
+
+// decodeAll 按 chunks 分片喂入解码器，收集解出的帧与错误
+func decodeAll(chunks ...[]byte) ([]*Message, []error) {
+	decoder := NewDecoder()
+	var messages []*Message
+	var errs []error
+	for _, chunk := range chunks {
+		decoder.Write(chunk)
+		for {
+			message, err := decoder.Next()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if message == nil {
+				break
+			}
+			messages = append(messages, message)
+		}
+	}
+	if err := decoder.Flush(); err != nil {
+		errs = append(errs, err)
+	}
+	return messages, errs
+}
+
+func TestDecoder(t *testing.T) {
+	pose := frame(ProtocolGetPose, 0, 1, 2, 3, 4)
+	start := frame(ProtocolQueuedCmdStartExec, 1)
+	badSum := append([]byte(nil), pose...)
+	badSum[len(badSum)-1]++
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+	tests := []struct {
+		name   string
+		chunks [][]byte
+		ids    []ProtocolId
+		errs   []error
+	}{
+		{"single", [][]byte{pose}, []ProtocolId{ProtocolGetPose}, nil},
+		{"back to back", [][]byte{join(pose, start, pose)}, []ProtocolId{ProtocolGetPose, ProtocolQueuedCmdStartExec, ProtocolGetPose}, nil},
+		{"byte by byte", splitEvery(join(pose, start), 1), []ProtocolId{ProtocolGetPose, ProtocolQueuedCmdStartExec}, nil},
+		{"split header", [][]byte{pose[:2], pose[2:3], pose[3:]}, []ProtocolId{ProtocolGetPose}, nil},
+		{"leading garbage", [][]byte{join([]byte{0x00, 0x13, 0xFF}, pose)}, []ProtocolId{ProtocolGetPose}, nil},
+		{"lone sync byte", [][]byte{join([]byte{0xAA, 0x01}, pose)}, []ProtocolId{ProtocolGetPose}, nil},
+		{"extra sync bytes", [][]byte{join([]byte{0xAA, 0xAA}, pose)}, []ProtocolId{ProtocolGetPose}, nil},
+		{"bad checksum then good", [][]byte{join(badSum, start)}, []ProtocolId{ProtocolQueuedCmdStartExec}, []error{ErrBadChecksum}},
+		{"bad length then good", [][]byte{join([]byte{0xAA, 0xAA, 0x01}, start)}, []ProtocolId{ProtocolQueuedCmdStartExec}, []error{ErrBadLength}},
+		{"truncated tail", [][]byte{join(pose, start[:4])}, []ProtocolId{ProtocolGetPose}, []error{ErrTruncated}},
+		{"trailing garbage", [][]byte{join(pose, []byte{0x01, 0x02})}, []ProtocolId{ProtocolGetPose}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			messages, errs := decodeAll(test.chunks...)
+			var ids []ProtocolId
+			for _, message := range messages {
+				ids = append(ids, message.Id)
+			}
+			if !reflect.DeepEqual(ids, test.ids) {
+				t.Errorf("ids = %v, want %v", ids, test.ids)
+			}
+			if len(errs) != len(test.errs) {
+				t.Fatalf("errors = %v, want %v", errs, test.errs)
+			}
+			for i, err := range errs {
+				var frameErr *FrameError
+				if !errors.As(err, &frameErr) || !errors.Is(err, test.errs[i]) {
+					t.Errorf("error %d = %v, want FrameError %v", i, err, test.errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecoderMessage(t *testing.T) {
+	messages, errs := decodeAll(frame(ProtocolPTPCmd, 3, 9, 8, 7))
+	if len(errs) != 0 || len(messages) != 1 {
+		t.Fatalf("messages %v, errors %v", messages, errs)
+	}
+	want := &Message{Id: ProtocolPTPCmd, RW: true, IsQueued: true, Params: []byte{9, 8, 7}}
+	if !reflect.DeepEqual(messages[0], want) {
+		t.Errorf("message = %+v, want %+v", messages[0], want)
+	}
+}
+
+func TestReader(t *testing.T) {
+	pose := frame(ProtocolGetPose, 0, 1, 2)
+	data := bytes.Join([][]byte{{0x55}, pose, {0xAA, 0xAA, 0x02, 0x0A, 0x00, 0x00}, pose, pose[:3]}, nil)
+	reader := NewReader(&chunkReader{data: data, size: 3})
+	var ids []ProtocolId
+	var errs []error
+	for {
+		message, err := reader.ReadMessage()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var frameErr *FrameError
+			if !errors.As(err, &frameErr) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			errs = append(errs, frameErr.Err)
+			continue
+		}
+		ids = append(ids, message.Id)
+	}
+	if want := []ProtocolId{ProtocolGetPose, ProtocolGetPose}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	if want := []error{ErrBadChecksum, ErrTruncated}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors = %v, want %v", errs, want)
+	}
+}
+
+func splitEvery(data []byte, size int) [][]byte {
+	var chunks [][]byte
+	for len(data) > size {
+		chunks = append(chunks, data[:size])
+		data = data[size:]
+	}
+	return append(chunks, data)
+}
+
+// chunkReader 每次最多返回 size 字节，模拟串口分片
+type chunkReader struct {
+	data []byte
+	size int
+}
+
+func (reader *chunkReader) Read(p []byte) (int, error) {
+	if len(reader.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p[:min(len(p), reader.size)], reader.data)
+	reader.data = reader.data[n:]
+	return n, nil
+}
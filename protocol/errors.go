@@ -0,0 +1,17 @@
+package protocol
+
+import "fmt"
+
+// ProtocolError 指令交互失败，Id 为出错的指令
+type ProtocolError struct {
+	Id  ProtocolId
+	Err error
+}
+
+func (err *ProtocolError) Error() string {
+	return fmt.Sprintf("protocol %d: %v", err.Id, err.Err)
+}
+
+func (err *ProtocolError) Unwrap() error {
+	return err.Err
+}
@@ -0,0 +1,90 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Message 用于协议通信的消息结构
+type Message struct {
+	Id       ProtocolId // 原 id
+	RW       bool       // 原 rw
+	IsQueued bool       // 原 isQueued
+	Params   []byte
+}
+
+func (message *Message) Ctrl() uint8 {
+	ctrl := uint8(0)
+	if message.RW {
+		ctrl |= 0x01
+	}
+	if message.IsQueued {
+		ctrl |= 0x02
+	}
+	return ctrl
+}
+
+func (message *Message) SetCtrl(ctrl uint8) {
+	message.RW = ctrl&0x01 != 0
+	message.IsQueued = (ctrl>>1)&0x01 != 0
+}
+
+func (message *Message) Data() []byte {
+	return message.Params
+}
+
+func (message *Message) Reader() io.Reader {
+	return bytes.NewReader(message.Params)
+}
+
+// Read 按小端序解析参数，参数不足时返回 *ProtocolError
+func (message *Message) Read(data any) error {
+	if err := binary.Read(message.Reader(), binary.LittleEndian, data); err != nil {
+		return &ProtocolError{Id: message.Id, Err: err}
+	}
+	return nil
+}
+
+// Byte 读取第 index 个参数字节，越界时返回 0
+func (message *Message) Byte(index int) uint8 {
+	if index < 0 || index >= len(message.Params) {
+		return 0
+	}
+	return message.Params[index]
+}
+
+// fixed 返回至少 size 字节的参数，不足部分补 0
+func (message *Message) fixed(size int) []byte {
+	if len(message.Params) >= size {
+		return message.Params
+	}
+	params := make([]byte, size)
+	copy(params, message.Params)
+	return params
+}
+
+func (message *Message) Bool() bool {
+	return message.Byte(0) != 0
+}
+
+func (message *Message) Uint16() uint16 {
+	return binary.LittleEndian.Uint16(message.fixed(2))
+}
+
+func (message *Message) Uint32() uint32 {
+	return binary.LittleEndian.Uint32(message.fixed(4))
+}
+
+func (message *Message) Uint64() uint64 {
+	return binary.LittleEndian.Uint64(message.fixed(8))
+}
+
+func (message *Message) Float32() float32 {
+	return math.Float32frombits(binary.LittleEndian.Uint32(message.fixed(4)))
+}
+
+func (message *Message) Float64() float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(message.fixed(8)))
+}
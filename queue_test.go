@@ -0,0 +1,336 @@
+package godobot_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot"
+	"github.com/zdypro888/godobot/protocol"
+	"github.com/zdypro888/godobot/simulator"
+)
+
+// waitCmd 入队的 1 毫秒等待，用于占用队列空间
+var waitCmd = &godobot.WAITCmd{Timeout: 1}
+
+// fillQueue 以另一个连接向模拟器发送 count 条队列指令，dobot 的剩余空间估计不知道这些指令
+func fillQueue(t *testing.T, name string, count int) {
+	t.Helper()
+	port, err := godobot.OpenTransport("pipe://" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+	reader := protocol.NewReader(port)
+	frame := protocol.Encode(&protocol.Message{Id: protocol.ProtocolWAITCmd, RW: true, IsQueued: true, Params: binary.LittleEndian.AppendUint32(nil, 1)})
+	for i := 0; i < count; i++ {
+		if _, err := port.Write(frame); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := reader.ReadMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// waitFor 轮询直到 done 返回 true
+func waitFor(t *testing.T, timeout time.Duration, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestQueuedCommand 队列指令在设备执行到其索引时完成，未执行时不完成，清空队列时以 ErrStopped 结束
+func TestQueuedCommand(t *testing.T) {
+	sim, dobot := connectSimulator(t, "queued-command", 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := dobot.SetQueuedCmdStopExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	targets := [][3]float32{{200, 0, 0}, {200, 40, 0}, {180, 40, 20}}
+	var cmds []*godobot.QueuedCommand
+	for _, target := range targets {
+		cmd, err := dobot.SetPTPCmd(ctx, &godobot.PTPCmd{PTPMode: godobot.PTPMOVLXYZMode, X: target[0], Y: target[1], Z: target[2]}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	completed := make(chan uint64, len(cmds))
+	for _, cmd := range cmds {
+		dobot.OnComplete(cmd, func(err error) {
+			if err == nil {
+				completed <- sim.CurrentIndex()
+			}
+		})
+	}
+	time.Sleep(100 * time.Millisecond)
+	for i, cmd := range cmds {
+		if cmd.Err() != nil || len(completed) > 0 {
+			t.Fatalf("command %d finished before the queue started", i)
+		}
+		select {
+		case <-cmd.Done():
+			t.Fatalf("command %d done before the queue started", i)
+		default:
+		}
+	}
+	if err := dobot.SetQueuedCmdStartExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i, cmd := range cmds {
+		if err := cmd.Wait(ctx); err != nil {
+			t.Fatalf("command %d: %v", i, err)
+		}
+		if index := sim.CurrentIndex(); index < cmd.Index() {
+			t.Errorf("command %d done at device index %d, before its index %d", i, index, cmd.Index())
+		}
+	}
+	for range cmds {
+		if index := <-completed; index < cmds[0].Index() {
+			t.Errorf("callback ran at device index %d", index)
+		}
+	}
+	last := targets[len(targets)-1]
+	if pose := sim.Pose(); math.Abs(float64(pose.X-last[0])) > 0.01 || math.Abs(float64(pose.Y-last[1])) > 0.01 || math.Abs(float64(pose.Z-last[2])) > 0.01 {
+		t.Errorf("ended at %+v, want %v", pose, last)
+	}
+
+	if err := dobot.SetQueuedCmdStopExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := dobot.SetWAITCmd(ctx, waitCmd, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dobot.SetQueuedCmdClear(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(ctx); !errors.Is(err, godobot.ErrStopped) {
+		t.Errorf("cleared command: %v, want ErrStopped", err)
+	}
+}
+
+// jamQueue 另一个连接占满设备队列后，dobot 按过时的剩余空间估计再发出 8 条队列指令（在途上限），
+// 设备丢弃这些指令且不应答，在途表被占满直到超时；返回这些指令的结果
+func jamQueue(t *testing.T, name string) (*godobot.Dobot, <-chan error) {
+	t.Helper()
+	_, dobot := connectSimulator(t, name, 100)
+	ctx := context.Background()
+	if err := dobot.SetQueuedCmdStopExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dobot.SetWAITCmd(ctx, waitCmd, true); err != nil {
+		t.Fatal(err)
+	}
+	fillQueue(t, name, simulator.QueueCapacity-1)
+	sent := dobot.LinkStats().Sent
+	const jammed = 8
+	results := make(chan error, jammed)
+	for i := 0; i < jammed; i++ {
+		go func() {
+			_, err := dobot.SetWAITCmd(ctx, waitCmd, true)
+			results <- err
+		}()
+	}
+	waitFor(t, time.Second, "dropped commands to be sent", func() bool { return dobot.LinkStats().Sent >= sent+jammed })
+	return dobot, results
+}
+
+// TestUrgentLane 在途表被无应答的指令占满时普通请求等待，停止与清空队列仍立即发送
+func TestUrgentLane(t *testing.T) {
+	dobot, _ := jamQueue(t, "urgent-lane")
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err := dobot.GetPose(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetPose with full in-flight table: %v, want deadline exceeded", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := dobot.SetQueuedCmdForceStopExec(ctx); err != nil {
+		t.Errorf("force stop: %v", err)
+	}
+	if err := dobot.SetQueuedCmdClear(ctx); err != nil {
+		t.Errorf("clear: %v", err)
+	}
+}
+
+// TestLeftSpaceAfterTimeout 队列指令超时后无法确定设备是否收到，应重新查询剩余空间，
+// 而不是按原估计继续发送被设备丢弃
+func TestLeftSpaceAfterTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reply timeout")
+	}
+	dobot, results := jamQueue(t, "left-space-timeout")
+	for i := 0; i < cap(results); i++ {
+		if err := <-results; !errors.Is(err, godobot.ErrTimeout) {
+			t.Fatalf("dropped command: %v, want ErrTimeout", err)
+		}
+	}
+	stats := dobot.LinkStats()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	sent := make(chan error, 1)
+	go func() {
+		_, err := dobot.QueuedSend(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return dobot.SetWAITCmd(ctx, waitCmd, true)
+		})
+		sent <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := dobot.SetQueuedCmdStartExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 设备腾出空间后应在重发超时之前发出
+	if err := <-sent; err != nil {
+		t.Fatalf("queued after timeout: %v", err)
+	}
+	if got := dobot.LinkStats(); got.Retransmits != stats.Retransmits || got.Timeouts != stats.Timeouts {
+		t.Errorf("retransmits %d, timeouts %d after recovery, want %d, %d", got.Retransmits, got.Timeouts, stats.Retransmits, stats.Timeouts)
+	}
+}
+
+// heldTransport 推迟交付剩余空间查询的应答（及其后的所有数据），模拟应答在途时又有队列指令发出
+type heldTransport struct {
+	godobot.Transport
+	frames  chan heldFrame
+	queried chan struct{} // 写出剩余空间查询时通知
+	pending []byte
+}
+
+type heldFrame struct {
+	data []byte
+	due  time.Time
+}
+
+const heldDelay = 100 * time.Millisecond
+
+func newHeldTransport(port godobot.Transport) *heldTransport {
+	held := &heldTransport{Transport: port, frames: make(chan heldFrame, 1024), queried: make(chan struct{}, 16)}
+	go func() {
+		defer close(held.frames)
+		reader := protocol.NewReader(port)
+		var due time.Time
+		for {
+			message, err := reader.ReadMessage()
+			if err != nil {
+				return
+			}
+			if message.Id == protocol.ProtocolQueuedCmdLeftSpace {
+				due = time.Now().Add(heldDelay)
+			}
+			held.frames <- heldFrame{data: protocol.Encode(message), due: due}
+		}
+	}()
+	return held
+}
+
+func (held *heldTransport) Read(p []byte) (int, error) {
+	if len(held.pending) == 0 {
+		frame, ok := <-held.frames
+		if !ok {
+			return 0, errors.New("held transport closed")
+		}
+		time.Sleep(time.Until(frame.due))
+		held.pending = frame.data
+	}
+	n := copy(p, held.pending)
+	held.pending = held.pending[n:]
+	return n, nil
+}
+
+func (held *heldTransport) Write(p []byte) (int, error) {
+	if len(p) > 3 && protocol.ProtocolId(p[3]) == protocol.ProtocolQueuedCmdLeftSpace {
+		select {
+		case held.queried <- struct{}{}:
+		default:
+		}
+	}
+	return held.Transport.Write(p)
+}
+
+// TestLeftSpaceCorrection 剩余空间应答之前已发出的队列指令应从应答中扣除，
+// 否则估计偏大，之后的指令在设备队列已满时发出并被丢弃
+func TestLeftSpaceCorrection(t *testing.T) {
+	sim := simulator.New()
+	sim.SetTimeScale(100)
+	if _, err := sim.Listen("left-space-correction"); err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Close()
+	port, err := godobot.OpenTransport("pipe://left-space-correction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := newHeldTransport(port)
+	dobot := godobot.NewDobot()
+	if err := dobot.ConnectTransport(held); err != nil {
+		t.Fatal(err)
+	}
+	defer dobot.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := dobot.SetQueuedCmdStopExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dobot.SetWAITCmd(ctx, waitCmd, true); err != nil {
+		t.Fatal(err)
+	}
+	for len(held.queried) > 0 {
+		<-held.queried
+	}
+	// 调用方的查询在途时发出 10 条队列指令
+	query := make(chan error, 1)
+	go func() {
+		_, err := dobot.GetQueuedCmdLeftSpace(ctx)
+		query <- err
+	}()
+	<-held.queried
+	var group sync.WaitGroup
+	send := func(count int) {
+		for i := 0; i < count; i++ {
+			group.Add(1)
+			go func() {
+				defer group.Done()
+				if _, err := dobot.QueuedSend(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+					return dobot.SetWAITCmd(ctx, waitCmd, true)
+				}); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+	}
+	send(10)
+	if err := <-query; err != nil {
+		t.Fatal(err)
+	}
+	// 剩余 21 个空间，多出的 5 条应等待而不是发出
+	send(simulator.QueueCapacity - 11 + 5)
+	time.Sleep(300 * time.Millisecond)
+	if err := dobot.SetQueuedCmdStartExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		group.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued commands stuck after the queue started")
+	}
+	if stats := dobot.LinkStats(); stats.Retransmits != 0 || stats.Timeouts != 0 {
+		t.Errorf("retransmits %d, timeouts %d: commands were dropped by the full device queue", stats.Retransmits, stats.Timeouts)
+	}
+}
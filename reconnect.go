@@ -0,0 +1,9 @@
+package godobot
+
+import "github.com/zdypro888/godobot/internal"
+
+// ReconnectPolicy 断线自动重连策略
+type ReconnectPolicy = internal.ReconnectPolicy
+
+// ReconnectResult 断线重连结果
+type ReconnectResult = internal.ReconnectResult
@@ -0,0 +1,75 @@
+package godobot
+
+import (
+	"io"
+
+	"github.com/zdypro888/godobot/internal"
+)
+
+var (
+	ErrBadRecording   = internal.ErrBadRecording   // 记录文件损坏
+	ErrReplayMismatch = internal.ErrReplayMismatch // 严格回放时发送内容与记录不一致
+)
+
+// RecordDirection 记录方向
+type RecordDirection = internal.RecordDirection
+
+const (
+	RecordTx = internal.RecordTx
+	RecordRx = internal.RecordRx
+)
+
+// Record 一条收发记录
+type Record = internal.Record
+
+// Recording 一次会话的记录
+type Recording = internal.Recording
+
+// ReplayTransport 回放记录的传输层，也可通过 replay:///path/to/session.rec?strict=1&speed=1 打开
+type ReplayTransport = internal.ReplayTransport
+
+// ReadRecording 读取 StartRecording 写入的记录文件
+func ReadRecording(r io.Reader) (*Recording, error) {
+	return internal.ReadRecording(r)
+}
+
+// NewReplayTransport 创建回放传输层，strict 时校验发送内容，speed 大于 0 时按记录的时间间隔回放
+func NewReplayTransport(recording *Recording, strict bool, speed float64) *ReplayTransport {
+	return internal.NewReplayTransport(recording, strict, speed)
+}
+
+// NewRawReplayTransport 创建原样回放传输层，按记录顺序读出接收的数据，包括没有对应请求的应答，
+// 也可通过 replay:///path/to/session.rec?raw=1 打开
+func NewRawReplayTransport(recording *Recording, strict bool, speed float64) *ReplayTransport {
+	return internal.NewRawReplayTransport(recording, strict, speed)
+}
+
+// StartRecording 将之后收发的所有数据带时间戳记录到 w，可在连接前调用，重连后继续记录
+func (dobot *Dobot) StartRecording(w io.Writer) error {
+	recorder, err := internal.NewRecorder(w)
+	if err != nil {
+		return err
+	}
+	if err = dobot.StopRecording(); err != nil {
+		recorder.Close()
+		return err
+	}
+	dobot.recorder = recorder
+	if dobot.conn != nil {
+		dobot.conn.SetRecorder(recorder)
+	}
+	return nil
+}
+
+// StopRecording 停止记录，w 实现 io.Closer 时一并关闭
+func (dobot *Dobot) StopRecording() error {
+	recorder := dobot.recorder
+	if recorder == nil {
+		return nil
+	}
+	dobot.recorder = nil
+	if dobot.conn != nil {
+		dobot.conn.SetRecorder(nil)
+	}
+	return recorder.Close()
+}
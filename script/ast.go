@@ -0,0 +1,105 @@
+package script
+
+// Program 解析后的脚本
+type Program struct {
+	Statements []*Statement
+	Subs       map[string]*Statement // 子程序定义，键为名称
+}
+
+// Statement 一条语句
+type Statement struct {
+	Line int
+	Op   string       // 大写关键字
+	Name string       // VAR、POINT、SET、SUB、CALL 的名称
+	Args []Expr       // 参数，VAR、POINT、SET 为等号右侧
+	Body []*Statement // LOOP、SUB 的语句块
+}
+
+// Expr 表达式
+type Expr interface {
+	expr()
+}
+
+type (
+	// Number 数值常量
+	Number struct {
+		Value float64
+	}
+	// Ident 变量或点位名称
+	Ident struct {
+		Name string
+	}
+	// Member 点位分量 p.x
+	Member struct {
+		Name  string
+		Field string
+	}
+	// Unary 取负
+	Unary struct {
+		Op byte
+		X  Expr
+	}
+	// Binary 四则运算
+	Binary struct {
+		Op   byte
+		X, Y Expr
+	}
+	// Vector 点位常量 [x, y, z, r]
+	Vector struct {
+		Elems []Expr
+	}
+)
+
+func (*Number) expr() {}
+func (*Ident) expr()  {}
+func (*Member) expr() {}
+func (*Unary) expr()  {}
+func (*Binary) expr() {}
+func (*Vector) expr() {}
+
+// kind 表达式类型
+type kind uint8
+
+const (
+	invalid kind = iota
+	number
+	point
+)
+
+func (k kind) String() string {
+	switch k {
+	case number:
+		return "number"
+	case point:
+		return "point"
+	}
+	return "invalid"
+}
+
+// signature 语句的参数类型，nil 表示无参数；optional 为可省略的尾部参数个数
+type signature struct {
+	args     []kind
+	optional int
+}
+
+var signatures = map[string]signature{
+	"SPEED":   {args: []kind{number, number}, optional: 1},
+	"MOVJ":    {args: []kind{point}},
+	"MOVL":    {args: []kind{point}},
+	"JUMP":    {args: []kind{point}},
+	"ARC":     {args: []kind{point, point}},
+	"CIRCLE":  {args: []kind{point, point, number}},
+	"WAIT":    {args: []kind{number}},
+	"DO":      {args: []kind{number, number}},
+	"WAITDI":  {args: []kind{number, number}},
+	"SUCTION": {args: []kind{number}},
+	"GRIPPER": {args: []kind{number}},
+	"LASER":   {args: []kind{number}},
+	"LOOP":    {args: []kind{number}},
+	"VAR":     {},
+	"POINT":   {},
+	"SET":     {},
+	"SUB":     {},
+	"CALL":    {},
+	"END":     {},
+}
@@ -0,0 +1,278 @@
+package script
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// checker 静态检查状态
+type checker struct {
+	program *Program
+	decls   map[string]*Statement // VAR、POINT 声明，名称全局唯一
+	errs    ErrorList
+}
+
+// Check 静态检查名称、类型、参数个数、子程序递归与常量取值，返回的错误为 ErrorList
+func (program *Program) Check() error {
+	c := &checker{program: program, decls: map[string]*Statement{}}
+	subs := program.subs()
+	c.declare(program.Statements)
+	for _, sub := range subs {
+		c.declare(sub.Body)
+	}
+	c.block(program.Statements)
+	for _, sub := range subs {
+		c.block(sub.Body)
+	}
+	c.recursion(subs)
+	slices.SortStableFunc(c.errs, func(a, b *Error) int { return a.Line - b.Line })
+	return c.errs.err()
+}
+
+// subs 按行排列的子程序
+func (program *Program) subs() []*Statement {
+	var subs []*Statement
+	for _, sub := range program.Subs {
+		subs = append(subs, sub)
+	}
+	slices.SortFunc(subs, func(a, b *Statement) int { return a.Line - b.Line })
+	return subs
+}
+
+func (c *checker) fail(line int, kind error, format string, args ...any) {
+	c.errs = append(c.errs, errorf(line, kind, format, args...))
+}
+
+// declare 收集声明
+func (c *checker) declare(stmts []*Statement) {
+	for _, stmt := range stmts {
+		switch stmt.Op {
+		case "VAR", "POINT":
+			if prev, ok := c.decls[stmt.Name]; ok {
+				c.fail(stmt.Line, ErrRedeclared, "%s, previous declaration at line %d", stmt.Name, prev.Line)
+				continue
+			}
+			c.decls[stmt.Name] = stmt
+		case "LOOP":
+			c.declare(stmt.Body)
+		}
+	}
+}
+
+// kindOf 声明的类型
+func (c *checker) kindOf(name string) kind {
+	decl, ok := c.decls[name]
+	switch {
+	case !ok:
+		return invalid
+	case decl.Op == "POINT":
+		return point
+	}
+	return number
+}
+
+func (c *checker) block(stmts []*Statement) {
+	for _, stmt := range stmts {
+		c.statement(stmt)
+	}
+}
+
+func (c *checker) statement(stmt *Statement) {
+	switch stmt.Op {
+	case "VAR", "POINT", "SET":
+		want := number
+		if stmt.Op == "POINT" {
+			want = point
+		}
+		if stmt.Op == "SET" {
+			if _, ok := c.decls[stmt.Name]; !ok {
+				c.fail(stmt.Line, ErrUndefined, "%s", stmt.Name)
+				return
+			}
+			want = c.kindOf(stmt.Name)
+		}
+		if got := c.expr(stmt.Line, stmt.Args[0]); got != invalid && got != want {
+			c.fail(stmt.Line, ErrType, "%s %s is %s, got %s", stmt.Op, stmt.Name, want, got)
+		}
+		return
+	case "CALL":
+		if _, ok := c.program.Subs[stmt.Name]; !ok {
+			c.fail(stmt.Line, ErrUndefined, "SUB %s", stmt.Name)
+		}
+		return
+	}
+	sig := signatures[stmt.Op]
+	if len(stmt.Args) < len(sig.args)-sig.optional || len(stmt.Args) > len(sig.args) {
+		want := fmt.Sprint(len(sig.args))
+		if sig.optional > 0 {
+			want = fmt.Sprintf("%d~%d", len(sig.args)-sig.optional, len(sig.args))
+		}
+		c.fail(stmt.Line, ErrSyntax, "%s takes %s arguments, got %d", stmt.Op, want, len(stmt.Args))
+		return
+	}
+	for i, arg := range stmt.Args {
+		if got := c.expr(stmt.Line, arg); got != invalid && got != sig.args[i] {
+			c.fail(stmt.Line, ErrType, "%s argument %d is %s, got %s", stmt.Op, i+1, sig.args[i], got)
+		}
+	}
+	c.values(stmt)
+	if stmt.Op == "LOOP" {
+		c.block(stmt.Body)
+	}
+}
+
+// values 检查常量参数的取值
+func (c *checker) values(stmt *Statement) {
+	check := func(i int, rule string, valid func(float64) bool) {
+		if i >= len(stmt.Args) {
+			return
+		}
+		if value, ok := constant(stmt.Args[i]); ok && !valid(value) {
+			c.fail(stmt.Line, ErrValue, "%s argument %d = %g, want %s", stmt.Op, i+1, value, rule)
+		}
+	}
+	switch stmt.Op {
+	case "SPEED":
+		check(0, "0~100", percent)
+		check(1, "0~100", percent)
+	case "CIRCLE":
+		check(2, "integer >= 1", func(v float64) bool { return v >= 1 && v == math.Trunc(v) })
+	case "WAIT":
+		check(0, ">= 0", func(v float64) bool { return v >= 0 })
+	case "LOOP":
+		check(0, "integer >= 0", count)
+	case "DO", "WAITDI":
+		check(0, "port 1~20", port)
+		check(1, "0 or 1", level)
+	case "SUCTION", "GRIPPER", "LASER":
+		check(0, "ON or OFF", level)
+	}
+}
+
+func percent(v float64) bool { return v >= 0 && v <= 100 }
+func count(v float64) bool   { return v >= 0 && v == math.Trunc(v) }
+func port(v float64) bool    { return v >= 1 && v <= 20 && v == math.Trunc(v) }
+func level(v float64) bool   { return v == 0 || v == 1 }
+
+// expr 推导表达式类型，出错时返回 invalid
+func (c *checker) expr(line int, x Expr) kind {
+	switch x := x.(type) {
+	case *Number:
+		return number
+	case *Ident:
+		if _, ok := c.decls[x.Name]; !ok {
+			c.fail(line, ErrUndefined, "%s", x.Name)
+			return invalid
+		}
+		return c.kindOf(x.Name)
+	case *Member:
+		switch got := c.kindOf(x.Name); {
+		case got == invalid:
+			c.fail(line, ErrUndefined, "%s", x.Name)
+			return invalid
+		case got != point:
+			c.fail(line, ErrType, "%s.%s: %s is not a point", x.Name, x.Field, x.Name)
+			return invalid
+		case !strings.Contains("xyzr", x.Field) || len(x.Field) != 1:
+			c.fail(line, ErrUndefined, "point field %s", x.Field)
+			return invalid
+		}
+		return number
+	case *Unary:
+		return c.expr(line, x.X)
+	case *Binary:
+		left, right := c.expr(line, x.X), c.expr(line, x.Y)
+		if left == invalid || right == invalid {
+			return invalid
+		}
+		switch {
+		case left == number && right == number:
+			return number
+		case (x.Op == '+' || x.Op == '-') && left == point && right == point:
+			return point
+		case x.Op == '*' && left != right, x.Op == '/' && left == point && right == number:
+			return point
+		}
+		c.fail(line, ErrType, "%s %c %s", left, x.Op, right)
+		return invalid
+	case *Vector:
+		if len(x.Elems) != 3 && len(x.Elems) != 4 {
+			c.fail(line, ErrType, "point has %d elements, want 3 or 4", len(x.Elems))
+			return invalid
+		}
+		for _, elem := range x.Elems {
+			if got := c.expr(line, elem); got != invalid && got != number {
+				c.fail(line, ErrType, "point element is %s", got)
+			}
+		}
+		return point
+	}
+	return invalid
+}
+
+// recursion 检查子程序之间的循环调用
+func (c *checker) recursion(subs []*Statement) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var visit func(sub *Statement)
+	visit = func(sub *Statement) {
+		state[sub.Name] = visiting
+		walk(sub.Body, func(stmt *Statement) {
+			callee, ok := c.program.Subs[stmt.Name]
+			if stmt.Op != "CALL" || !ok {
+				return
+			}
+			switch state[callee.Name] {
+			case visiting:
+				c.fail(stmt.Line, ErrRecursion, "SUB %s calls %s", sub.Name, callee.Name)
+			case unvisited:
+				visit(callee)
+			}
+		})
+		state[sub.Name] = visited
+	}
+	for _, sub := range subs {
+		if state[sub.Name] == unvisited {
+			visit(sub)
+		}
+	}
+}
+
+// walk 遍历语句及 LOOP 内的语句
+func walk(stmts []*Statement, fn func(*Statement)) {
+	for _, stmt := range stmts {
+		fn(stmt)
+		if stmt.Op == "LOOP" {
+			walk(stmt.Body, fn)
+		}
+	}
+}
+
+// constant 计算不含名称的数值表达式
+func constant(x Expr) (float64, bool) {
+	switch x := x.(type) {
+	case *Number:
+		return x.Value, true
+	case *Unary:
+		value, ok := constant(x.X)
+		return -value, ok
+	case *Binary:
+		left, ok := constant(x.X)
+		if !ok {
+			return 0, false
+		}
+		right, ok := constant(x.Y)
+		if !ok {
+			return 0, false
+		}
+		value, err := arith(x.Op, left, right)
+		return value, err == nil
+	}
+	return 0, false
+}
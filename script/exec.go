@@ -0,0 +1,363 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/zdypro888/godobot"
+)
+
+// executor 执行状态，变量与点位全局共享
+type executor struct {
+	dobot     *godobot.Dobot
+	program   *Program
+	numbers   map[string]float64
+	points    map[string][4]float64
+	kinds     map[string]kind              // 声明的类型
+	multiplex map[uint8]godobot.IOFunction // 本次执行已设置的 IO 复用
+	last      *godobot.QueuedCommand
+}
+
+// Run 检查并执行脚本，每条运动与 IO 语句作为队列指令发送，全部执行完成后返回。
+// 调用前需 SetQueuedCmdStartExec
+func Run(ctx context.Context, dobot *godobot.Dobot, program *Program) error {
+	if err := program.Check(); err != nil {
+		return err
+	}
+	exec := &executor{
+		dobot:     dobot,
+		program:   program,
+		numbers:   map[string]float64{},
+		points:    map[string][4]float64{},
+		kinds:     map[string]kind{},
+		multiplex: map[uint8]godobot.IOFunction{},
+	}
+	declare := func(stmt *Statement) {
+		switch stmt.Op {
+		case "VAR":
+			exec.kinds[stmt.Name] = number
+		case "POINT":
+			exec.kinds[stmt.Name] = point
+		}
+	}
+	walk(program.Statements, declare)
+	for _, sub := range program.Subs {
+		walk(sub.Body, declare)
+	}
+	if err := exec.block(ctx, program.Statements); err != nil {
+		return err
+	}
+	return exec.last.Wait(ctx)
+}
+
+func (exec *executor) block(ctx context.Context, stmts []*Statement) error {
+	for _, stmt := range stmts {
+		if err := exec.statement(ctx, stmt); err != nil {
+			if _, ok := err.(*Error); ok {
+				return err
+			}
+			return &Error{Line: stmt.Line, Err: err}
+		}
+	}
+	return nil
+}
+
+// queue 发送队列指令，队列满时等待
+func (exec *executor) queue(ctx context.Context, command godobot.QueuedCommander) error {
+	cmd, err := exec.dobot.QueuedSend(ctx, command)
+	if err != nil {
+		return err
+	}
+	exec.last = cmd
+	return nil
+}
+
+func (exec *executor) statement(ctx context.Context, stmt *Statement) error {
+	switch stmt.Op {
+	case "VAR", "SET", "POINT":
+		if exec.kinds[stmt.Name] == point {
+			pos, err := exec.point(stmt.Args[0])
+			if err != nil {
+				return err
+			}
+			exec.points[stmt.Name] = pos
+			return nil
+		}
+		value, err := exec.number(stmt.Args[0])
+		if err != nil {
+			return err
+		}
+		exec.numbers[stmt.Name] = value
+		return nil
+	case "LOOP":
+		n, err := exec.integer(stmt.Args[0], "integer >= 0", count)
+		if err != nil {
+			return err
+		}
+		for range n {
+			if err := exec.block(ctx, stmt.Body); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "CALL":
+		return exec.block(ctx, exec.program.Subs[stmt.Name].Body)
+	case "SPEED":
+		velocity, err := exec.number(stmt.Args[0])
+		if err != nil {
+			return err
+		}
+		acceleration := velocity
+		if len(stmt.Args) > 1 {
+			if acceleration, err = exec.number(stmt.Args[1]); err != nil {
+				return err
+			}
+		}
+		params := &godobot.PTPCommonParams{VelocityRatio: float32(velocity), AccelerationRatio: float32(acceleration)}
+		return exec.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return exec.dobot.SetPTPCommonParams(ctx, params, true)
+		})
+	case "MOVJ", "MOVL", "JUMP":
+		pos, err := exec.point(stmt.Args[0])
+		if err != nil {
+			return err
+		}
+		mode := map[string]godobot.PTPMode{"MOVJ": godobot.PTPMOVJXYZMode, "MOVL": godobot.PTPMOVLXYZMode, "JUMP": godobot.PTPJUMPXYZMode}[stmt.Op]
+		cmd := &godobot.PTPCmd{PTPMode: mode, X: float32(pos[0]), Y: float32(pos[1]), Z: float32(pos[2]), R: float32(pos[3])}
+		return exec.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return exec.dobot.SetPTPCmd(ctx, cmd, true)
+		})
+	case "ARC", "CIRCLE":
+		cir, err := exec.point(stmt.Args[0])
+		if err != nil {
+			return err
+		}
+		to, err := exec.point(stmt.Args[1])
+		if err != nil {
+			return err
+		}
+		if stmt.Op == "ARC" {
+			cmd := &godobot.ARCCmd{}
+			cmd.CirPoint.X, cmd.CirPoint.Y, cmd.CirPoint.Z, cmd.CirPoint.R = float32(cir[0]), float32(cir[1]), float32(cir[2]), float32(cir[3])
+			cmd.ToPoint.X, cmd.ToPoint.Y, cmd.ToPoint.Z, cmd.ToPoint.R = float32(to[0]), float32(to[1]), float32(to[2]), float32(to[3])
+			return exec.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+				return exec.dobot.SetARCCmd(ctx, cmd, true)
+			})
+		}
+		n, err := exec.integer(stmt.Args[2], "integer >= 1", func(v float64) bool { return v >= 1 && v == math.Trunc(v) })
+		if err != nil {
+			return err
+		}
+		cmd := &godobot.CircleCmd{Count: uint32(n)}
+		cmd.CirPoint.X, cmd.CirPoint.Y, cmd.CirPoint.Z, cmd.CirPoint.R = float32(cir[0]), float32(cir[1]), float32(cir[2]), float32(cir[3])
+		cmd.ToPoint.X, cmd.ToPoint.Y, cmd.ToPoint.Z, cmd.ToPoint.R = float32(to[0]), float32(to[1]), float32(to[2]), float32(to[3])
+		return exec.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return exec.dobot.SetCircleCmd(ctx, cmd, true)
+		})
+	case "WAIT":
+		timeout, err := exec.integer(stmt.Args[0], ">= 0", func(v float64) bool { return v >= 0 })
+		if err != nil {
+			return err
+		}
+		return exec.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return exec.dobot.SetWAITCmd(ctx, &godobot.WAITCmd{Timeout: uint32(timeout)}, true)
+		})
+	case "DO", "WAITDI":
+		address, err := exec.integer(stmt.Args[0], "port 1~20", port)
+		if err != nil {
+			return err
+		}
+		value, err := exec.integer(stmt.Args[1], "0 or 1", level)
+		if err != nil {
+			return err
+		}
+		if stmt.Op == "DO" {
+			if err := exec.setMultiplex(ctx, uint8(address), godobot.IOFunctionDO); err != nil {
+				return err
+			}
+			return exec.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+				return exec.dobot.SetIODO(ctx, &godobot.IODO{Address: uint8(address), Level: uint8(value)}, true)
+			})
+		}
+		if err := exec.setMultiplex(ctx, uint8(address), godobot.IOFunctionDI); err != nil {
+			return err
+		}
+		// 由设备端触发指令等待，不占用主机
+		cmd := &godobot.TRIGCmd{Address: uint8(address), Mode: uint8(godobot.TRIGInputIOMode), Condition: uint8(godobot.TRIGInputIOEqual), Threshold: float32(value)}
+		return exec.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return exec.dobot.SetTRIGCmd(ctx, cmd, true)
+		})
+	case "SUCTION", "GRIPPER", "LASER":
+		on, err := exec.integer(stmt.Args[0], "ON or OFF", level)
+		if err != nil {
+			return err
+		}
+		setter := map[string]func(ctx context.Context, enableCtrl bool, on bool, isQueued bool) (*godobot.QueuedCommand, error){
+			"SUCTION": exec.dobot.SetEndEffectorSuctionCup,
+			"GRIPPER": exec.dobot.SetEndEffectorGripper,
+			"LASER":   exec.dobot.SetEndEffectorLaser,
+		}[stmt.Op]
+		return exec.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return setter(ctx, true, on == 1, true)
+		})
+	}
+	return fmt.Errorf("%w: unknown statement %s", ErrSyntax, stmt.Op)
+}
+
+// isPoint 表达式是否为点位，静态检查已保证类型正确
+func (exec *executor) isPoint(x Expr) bool {
+	switch x := x.(type) {
+	case *Vector:
+		return true
+	case *Ident:
+		return exec.kinds[x.Name] == point
+	case *Unary:
+		return exec.isPoint(x.X)
+	case *Binary:
+		if x.Op == '*' {
+			return exec.isPoint(x.X) || exec.isPoint(x.Y)
+		}
+		return exec.isPoint(x.X)
+	}
+	return false
+}
+
+// setMultiplex 首次使用 IO 时设置复用功能
+func (exec *executor) setMultiplex(ctx context.Context, address uint8, function godobot.IOFunction) error {
+	if exec.multiplex[address] == function {
+		return nil
+	}
+	err := exec.queue(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+		return exec.dobot.SetIOMultiplexing(ctx, &godobot.IOMultiplexing{Address: address, Multiplex: uint8(function)}, true)
+	})
+	if err == nil {
+		exec.multiplex[address] = function
+	}
+	return err
+}
+
+// integer 计算取值需满足 valid 的整数参数
+func (exec *executor) integer(x Expr, rule string, valid func(float64) bool) (int, error) {
+	value, err := exec.number(x)
+	if err != nil {
+		return 0, err
+	}
+	if !valid(value) {
+		return 0, fmt.Errorf("%w: %g, want %s", ErrValue, value, rule)
+	}
+	return int(value), nil
+}
+
+// number 计算数值表达式
+func (exec *executor) number(x Expr) (float64, error) {
+	switch x := x.(type) {
+	case *Number:
+		return x.Value, nil
+	case *Ident:
+		value, ok := exec.numbers[x.Name]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s used before assignment", ErrUndefined, x.Name)
+		}
+		return value, nil
+	case *Member:
+		pos, ok := exec.points[x.Name]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s used before assignment", ErrUndefined, x.Name)
+		}
+		return pos[map[string]int{"x": 0, "y": 1, "z": 2, "r": 3}[x.Field]], nil
+	case *Unary:
+		value, err := exec.number(x.X)
+		return -value, err
+	case *Binary:
+		left, err := exec.number(x.X)
+		if err != nil {
+			return 0, err
+		}
+		right, err := exec.number(x.Y)
+		if err != nil {
+			return 0, err
+		}
+		return arith(x.Op, left, right)
+	}
+	return 0, fmt.Errorf("%w: expected number", ErrType)
+}
+
+// point 计算点位表达式
+func (exec *executor) point(x Expr) ([4]float64, error) {
+	var pos [4]float64
+	switch x := x.(type) {
+	case *Ident:
+		pos, ok := exec.points[x.Name]
+		if !ok {
+			return pos, fmt.Errorf("%w: %s used before assignment", ErrUndefined, x.Name)
+		}
+		return pos, nil
+	case *Vector:
+		for i, elem := range x.Elems {
+			value, err := exec.number(elem)
+			if err != nil {
+				return pos, err
+			}
+			pos[i] = value
+		}
+		return pos, nil
+	case *Unary:
+		pos, err := exec.point(x.X)
+		for i := range pos {
+			pos[i] = -pos[i]
+		}
+		return pos, err
+	case *Binary:
+		if x.Op == '+' || x.Op == '-' {
+			left, err := exec.point(x.X)
+			if err != nil {
+				return pos, err
+			}
+			right, err := exec.point(x.Y)
+			if err != nil {
+				return pos, err
+			}
+			for i := range pos {
+				pos[i], _ = arith(x.Op, left[i], right[i])
+			}
+			return pos, nil
+		}
+		// 点位与数值相乘除，乘法的点位可在任一侧
+		vector, scalar := x.X, x.Y
+		if !exec.isPoint(vector) {
+			vector, scalar = scalar, vector
+		}
+		base, err := exec.point(vector)
+		if err != nil {
+			return pos, err
+		}
+		factor, err := exec.number(scalar)
+		if err != nil {
+			return pos, err
+		}
+		for i := range pos {
+			if pos[i], err = arith(x.Op, base[i], factor); err != nil {
+				return pos, err
+			}
+		}
+		return pos, nil
+	}
+	return pos, fmt.Errorf("%w: expected point", ErrType)
+}
+
+// arith 四则运算，除数为 0 时返回 ErrValue
+func arith(op byte, left, right float64) (float64, error) {
+	switch op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	}
+	if right == 0 {
+		return 0, fmt.Errorf("%w: division by zero", ErrValue)
+	}
+	return left / right, nil
+}
@@ -0,0 +1,291 @@
+package script
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// token 词法单元，punct 为 0 时是名称或数值
+type token struct {
+	punct byte
+	text  string
+	value float64
+	isNum bool
+}
+
+// scan 切分一行，# 之后为注释
+func scan(line int, text string) ([]token, *Error) {
+	if i := strings.IndexByte(text, '#'); i >= 0 {
+		text = text[:i]
+	}
+	var tokens []token
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.IndexByte("=+-*/()[],", c) >= 0:
+			tokens = append(tokens, token{punct: c, text: string(c)})
+			i++
+		case c == '.' && (i+1 >= len(text) || !isDigit(text[i+1])):
+			tokens = append(tokens, token{punct: c, text: "."})
+			i++
+		case isDigit(c) || c == '.':
+			j := i
+			for j < len(text) && (isDigit(text[j]) || text[j] == '.') {
+				j++
+			}
+			value, err := strconv.ParseFloat(text[i:j], 64)
+			if err != nil {
+				return nil, errorf(line, ErrSyntax, "bad number %q", text[i:j])
+			}
+			tokens = append(tokens, token{text: text[i:j], value: value, isNum: true})
+			i = j
+		default:
+			// 名称以字母或下划线开头，字母按 UTF-8 解码，可使用中文等非 ASCII 字母
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if !isNameRune(r, false) {
+				return nil, errorf(line, ErrSyntax, "unexpected %q", text[i:i+size])
+			}
+			j := i + size
+			for j < len(text) {
+				r, size := utf8.DecodeRuneInString(text[j:])
+				if !isNameRune(r, true) {
+					break
+				}
+				j += size
+			}
+			tokens = append(tokens, token{text: text[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// isNameRune 是否可用于名称，digit 为 true 时允许 ASCII 数字；无效的 UTF-8 字节解码为 RuneError，不是字母
+func isNameRune(r rune, digit bool) bool {
+	return r == '_' || unicode.IsLetter(r) || digit && r < utf8.RuneSelf && isDigit(byte(r))
+}
+
+// isName 是否为名称（非数值、非符号）
+func (tok token) isName() bool {
+	return tok.punct == 0 && !tok.isNum
+}
+
+// Parse 解析脚本，返回的错误为 ErrorList
+func Parse(src string) (*Program, error) {
+	program := &Program{Subs: map[string]*Statement{}}
+	var errs ErrorList
+	// blocks 尚未 END 的 LOOP、SUB
+	var blocks []*Statement
+	for i, text := range strings.Split(src, "\n") {
+		line := i + 1
+		tokens, err := scan(line, text)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+		stmt, err := parseStatement(line, tokens)
+		if err != nil {
+			errs = append(errs, err)
+			// 块语句出错时仍需入栈，避免后续 END 错配
+			if stmt == nil {
+				continue
+			}
+		}
+		if stmt.Op == "END" {
+			if len(blocks) == 0 {
+				errs = append(errs, errorf(line, ErrSyntax, "END without LOOP or SUB"))
+				continue
+			}
+			blocks = blocks[:len(blocks)-1]
+			continue
+		}
+		switch {
+		case stmt.Op == "SUB" && len(blocks) > 0:
+			errs = append(errs, errorf(line, ErrSyntax, "SUB must be defined at top level"))
+		case stmt.Op == "SUB":
+			if prev, ok := program.Subs[stmt.Name]; ok {
+				errs = append(errs, errorf(line, ErrRedeclared, "SUB %s, previous definition at line %d", stmt.Name, prev.Line))
+			}
+			program.Subs[stmt.Name] = stmt
+		case len(blocks) > 0:
+			block := blocks[len(blocks)-1]
+			block.Body = append(block.Body, stmt)
+		default:
+			program.Statements = append(program.Statements, stmt)
+		}
+		if stmt.Op == "LOOP" || stmt.Op == "SUB" {
+			blocks = append(blocks, stmt)
+		}
+	}
+	for _, block := range blocks {
+		errs = append(errs, errorf(block.Line, ErrSyntax, "%s without END", block.Op))
+	}
+	slices.SortStableFunc(errs, func(a, b *Error) int { return a.Line - b.Line })
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+// parseStatement 解析一行语句，出错时若能识别语句仍返回 stmt
+func parseStatement(line int, tokens []token) (*Statement, *Error) {
+	if !tokens[0].isName() {
+		return nil, errorf(line, ErrSyntax, "expected keyword, found %q", tokens[0].text)
+	}
+	stmt := &Statement{Line: line, Op: strings.ToUpper(tokens[0].text)}
+	if _, ok := signatures[stmt.Op]; !ok {
+		return nil, errorf(line, ErrSyntax, "unknown statement %s", tokens[0].text)
+	}
+	p := &parser{line: line, tokens: tokens, pos: 1}
+	switch stmt.Op {
+	case "END":
+	case "SUB", "CALL":
+		stmt.Name = p.name()
+	case "VAR", "POINT", "SET":
+		stmt.Name = p.name()
+		p.expect('=')
+		stmt.Args = append(stmt.Args, p.expr())
+	default:
+		if p.pos < len(p.tokens) {
+			stmt.Args = append(stmt.Args, p.expr())
+			for p.accept(',') {
+				stmt.Args = append(stmt.Args, p.expr())
+			}
+		}
+	}
+	if p.err == nil && p.pos < len(p.tokens) {
+		p.fail("unexpected %q", p.tokens[p.pos].text)
+	}
+	return stmt, p.err
+}
+
+// parser 递归下降解析一行中的表达式，只记录第一个错误
+type parser struct {
+	line   int
+	tokens []token
+	pos    int
+	err    *Error
+}
+
+func (p *parser) fail(format string, args ...any) {
+	if p.err == nil {
+		p.err = errorf(p.line, ErrSyntax, format, args...)
+	}
+	p.pos = len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return token{punct: '\n', text: "end of line"}
+}
+
+func (p *parser) accept(punct byte) bool {
+	if p.peek().punct == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct byte) {
+	if !p.accept(punct) {
+		p.fail("expected %q, found %q", punct, p.peek().text)
+	}
+}
+
+func (p *parser) name() string {
+	tok := p.peek()
+	if !tok.isName() {
+		p.fail("expected name, found %q", tok.text)
+		return ""
+	}
+	p.pos++
+	return tok.text
+}
+
+// expr 加减
+func (p *parser) expr() Expr {
+	x := p.term()
+	for {
+		op := p.peek().punct
+		if op != '+' && op != '-' {
+			return x
+		}
+		p.pos++
+		x = &Binary{Op: op, X: x, Y: p.term()}
+	}
+}
+
+// term 乘除
+func (p *parser) term() Expr {
+	x := p.unary()
+	for {
+		op := p.peek().punct
+		if op != '*' && op != '/' {
+			return x
+		}
+		p.pos++
+		x = &Binary{Op: op, X: x, Y: p.unary()}
+	}
+}
+
+func (p *parser) unary() Expr {
+	if p.accept('-') {
+		return &Unary{Op: '-', X: p.unary()}
+	}
+	if p.accept('+') {
+		return p.unary()
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() Expr {
+	tok := p.peek()
+	switch {
+	case tok.isNum:
+		p.pos++
+		return &Number{Value: tok.value}
+	case tok.isName():
+		p.pos++
+		switch strings.ToUpper(tok.text) {
+		case "ON":
+			return &Number{Value: 1}
+		case "OFF":
+			return &Number{Value: 0}
+		}
+		if p.accept('.') {
+			return &Member{Name: tok.text, Field: strings.ToLower(p.name())}
+		}
+		return &Ident{Name: tok.text}
+	case tok.punct == '(':
+		p.pos++
+		x := p.expr()
+		p.expect(')')
+		return x
+	case tok.punct == '[':
+		p.pos++
+		vector := &Vector{}
+		vector.Elems = append(vector.Elems, p.expr())
+		for p.accept(',') {
+			vector.Elems = append(vector.Elems, p.expr())
+		}
+		p.expect(']')
+		return vector
+	}
+	p.fail("unexpected %q", tok.text)
+	return &Number{}
+}
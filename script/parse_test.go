@@ -0,0 +1,123 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/zdypro888/godobot/internal/golden"
+)
+
+// TestGolden 解析并检查 testdata 中的脚本，与 .golden 文件中的语法树或错误列表比较
+func TestGolden(t *testing.T) {
+	golden.Run(t, "*.script", func(t *testing.T, file string) string {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dump(string(src))
+	})
+}
+
+// dump 解析失败时为错误列表，否则为语法树与静态检查结果
+func dump(src string) string {
+	var out strings.Builder
+	program, err := Parse(src)
+	if err != nil {
+		out.WriteString("parse:\n")
+		writeErrors(&out, err)
+		return out.String()
+	}
+	for _, stmt := range program.Statements {
+		writeStatement(&out, stmt, "")
+	}
+	for _, sub := range program.subs() {
+		writeStatement(&out, sub, "")
+	}
+	if err := program.Check(); err != nil {
+		out.WriteString("check:\n")
+		writeErrors(&out, err)
+	} else {
+		out.WriteString("check: ok\n")
+	}
+	return out.String()
+}
+
+func writeErrors(out *strings.Builder, err error) {
+	var list ErrorList
+	if !errors.As(err, &list) {
+		fmt.Fprintf(out, "\tnot an ErrorList: %v\n", err)
+		return
+	}
+	for _, e := range list {
+		fmt.Fprintf(out, "\t%v\n", e)
+	}
+}
+
+func writeStatement(out *strings.Builder, stmt *Statement, indent string) {
+	fmt.Fprintf(out, "%s%d %s", indent, stmt.Line, stmt.Op)
+	if stmt.Name != "" {
+		fmt.Fprintf(out, " %s", stmt.Name)
+	}
+	for i, arg := range stmt.Args {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		fmt.Fprintf(out, " %s", formatExpr(arg))
+	}
+	out.WriteByte('\n')
+	for _, child := range stmt.Body {
+		writeStatement(out, child, indent+"\t")
+	}
+}
+
+// formatExpr 完全加括号的表达式
+func formatExpr(x Expr) string {
+	switch x := x.(type) {
+	case *Number:
+		return strconv.FormatFloat(x.Value, 'g', -1, 64)
+	case *Ident:
+		return x.Name
+	case *Member:
+		return x.Name + "." + x.Field
+	case *Unary:
+		return fmt.Sprintf("(%c%s)", x.Op, formatExpr(x.X))
+	case *Binary:
+		return fmt.Sprintf("(%s %c %s)", formatExpr(x.X), x.Op, formatExpr(x.Y))
+	case *Vector:
+		elems := make([]string, len(x.Elems))
+		for i, elem := range x.Elems {
+			elems[i] = formatExpr(elem)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+	return fmt.Sprintf("%T", x)
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		src  string
+		kind error
+	}{
+		{"MOVJ [1, 2, 3", ErrSyntax},
+		{"VAR \xff = 1", ErrSyntax},
+		{"MOVJ p", ErrUndefined},
+		{"VAR a = 1\nVAR a = 2", ErrRedeclared},
+		{"VAR a = [1, 2, 3]", ErrType},
+		{"SUB a\nCALL a\nEND", ErrRecursion},
+		{"WAIT -5", ErrValue},
+	}
+	for _, test := range tests {
+		program, err := Parse(test.src)
+		if err == nil {
+			err = program.Check()
+		}
+		var scriptErr *Error
+		if !errors.Is(err, test.kind) || !errors.As(err, &scriptErr) {
+			t.Errorf("%q: error = %v, want %v", test.src, err, test.kind)
+		}
+	}
+}
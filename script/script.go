@@ -0,0 +1,95 @@
+// Package script 行式运动脚本：解析、静态检查并通过指令队列在 Dobot 上执行
+//
+// 每行一条语句，# 之后为注释，关键字不区分大小写：
+//
+//	VAR n = 3                 声明数值变量
+//	POINT p = [200, 0, 50, 0] 声明点位，R 可省略
+//	SET n = n - 1             赋值
+//	SPEED 50, 50              PTP 速度与加速度百分比，加速度可省略
+//	MOVJ p + [0, 0, 20]       关节插补运动
+//	MOVL p                    直线运动
+//	JUMP p                    门型运动
+//	ARC p1, p2                经过 p1 到 p2 的圆弧
+//	CIRCLE p1, p2, 2          经过 p1、p2 的整圆，重复 2 次
+//	WAIT 500                  等待毫秒
+//	DO 17, 1                  数字输出
+//	WAITDI 18, 1              等待数字输入到达电平
+//	SUCTION ON                吸盘，GRIPPER、LASER 同理
+//	LOOP n ... END            循环
+//	SUB name ... END          子程序，只能在顶层定义
+//	CALL name                 调用子程序
+//
+// 表达式支持 + - * / 与括号，点位可加减、与数值相乘除，p.x、p.y、p.z、p.r 取分量，ON、OFF 即 1、0
+package script
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrSyntax     = errors.New("syntax error")
+	ErrUndefined  = errors.New("undefined")
+	ErrRedeclared = errors.New("redeclared")
+	ErrType       = errors.New("type mismatch")
+	ErrRecursion  = errors.New("recursive call")
+	ErrValue      = errors.New("invalid value")
+)
+
+// Error 脚本第 Line 行的错误
+type Error struct {
+	Line int
+	Err  error
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("line %d: %v", err.Line, err.Err)
+}
+
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+// ErrorList 解析或静态检查发现的全部错误，按行排列
+type ErrorList []*Error
+
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "no errors"
+	case 1:
+		return list[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", list[0], len(list)-1)
+}
+
+func (list ErrorList) Unwrap() []error {
+	errs := make([]error, len(list))
+	for i, err := range list {
+		errs[i] = err
+	}
+	return errs
+}
+
+// err 无错误时返回 nil
+func (list ErrorList) err() error {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
+
+// errorf 构造第 line 行的错误，kind 为错误类别
+func errorf(line int, kind error, format string, args ...any) *Error {
+	return &Error{Line: line, Err: fmt.Errorf("%w: %s", kind, fmt.Sprintf(format, args...))}
+}
+
+// Load 读取并解析脚本文件
+func Load(path string) (*Program, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data))
+}
@@ -0,0 +1,230 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/zdypro888/godobot"
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// handle 处理一个请求，返回应答参数；第二个返回值为 false 时不应答
+func (sim *Simulator) handle(request *protocol.Message) ([]byte, bool) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	if request.RW && request.IsQueued {
+		cmd := &command{message: request}
+		if sim.downloading {
+			sim.queueIndex++
+			cmd.index = sim.queueIndex
+			sim.program = append(sim.program, cmd)
+			return encode(cmd.index), true
+		}
+		if len(sim.queue) >= QueueCapacity {
+			// 队列已满，固件丢弃指令且不应答
+			return nil, false
+		}
+		sim.queueIndex++
+		cmd.index = sim.queueIndex
+		sim.queue = append(sim.queue, cmd)
+		return encode(cmd.index), true
+	}
+	if request.RW {
+		sim.apply(request, 0)
+		return nil, true
+	}
+	return sim.read(request), true
+}
+
+// read 处理读请求
+func (sim *Simulator) read(request *protocol.Message) []byte {
+	var address uint8
+	if len(request.Params) > 0 {
+		address = request.Params[0]
+	}
+	now := sim.clock()
+	switch request.Id {
+	case protocol.ProtocolDeviceTime:
+		return encode(uint32(now / time.Millisecond))
+	case protocol.ProtocolDeviceInfo:
+		return encode(&godobot.DeviceCountInfo{DeviceRunTime: uint64(now / time.Second), DevicePowerOn: 1})
+	case protocol.ProtocolGetPose:
+		pose := sim.currentPose()
+		return encode(&pose)
+	case protocol.ProtocolGetKinematics:
+		var velocity float32
+		if sim.active != nil && sim.active.duration > 0 {
+			velocity = float32(sim.active.pathLength() / sim.active.duration.Seconds())
+		}
+		return encode(velocity, float32(0))
+	case protocol.ProtocolGetPoseL:
+		return encode(float32(sim.rail))
+	case protocol.ProtocolAlarmsState:
+		return append([]byte(nil), sim.alarms[:]...)
+	case protocol.ProtocolHHTTrigOutput:
+		output := false
+		if sim.registers[protocol.ProtocolHHTTrigOutputEnabled][0] != 0 {
+			if godobot.HHTTrigMode(sim.registers[protocol.ProtocolHHTTrigMode][0]) == godobot.TriggeredOnPeriodicInterval {
+				if now-sim.hhtAt >= 500*time.Millisecond {
+					sim.hhtAt = now
+					output = true
+				}
+			} else {
+				output = sim.hhtKey
+			}
+		}
+		sim.hhtKey = false
+		return encode(output)
+	case protocol.ProtocolIOMultiplexing, protocol.ProtocolIODO, protocol.ProtocolIODI:
+		return sim.getAddressed(request.Id, address, 2)
+	case protocol.ProtocolIOADC:
+		return sim.getAddressed(request.Id, address, 3)
+	case protocol.ProtocolQueuedCmdCurrentIndex:
+		return encode(sim.currentIndex)
+	case protocol.ProtocolQueuedCmdLeftSpace:
+		return encode(uint32(QueueCapacity - len(sim.queue)))
+	case protocol.ProtocolQueuedCmdMotionFinish:
+		return encode(sim.active == nil && len(sim.queue) == 0 && sim.jog == 0)
+	}
+	return append([]byte(nil), sim.registers[request.Id]...)
+}
+
+// apply 执行写指令，index 为队列索引（非队列指令为 0）
+func (sim *Simulator) apply(message *protocol.Message, index uint64) {
+	params := message.Params
+	reader := bytes.NewReader(params)
+	switch message.Id {
+	case protocol.ProtocolQueuedCmdStartExec:
+		sim.executing = true
+	case protocol.ProtocolQueuedCmdStopExec:
+		sim.executing = false
+	case protocol.ProtocolQueuedCmdForceStopExec:
+		sim.executing = false
+		sim.jog = 0
+		if sim.active != nil {
+			sim.finish()
+		}
+	case protocol.ProtocolQueuedCmdStartDownload:
+		binary.Read(reader, binary.LittleEndian, &sim.programLoops)
+		binary.Read(reader, binary.LittleEndian, &sim.programLines)
+		sim.downloading = true
+		sim.program = nil
+	case protocol.ProtocolQueuedCmdStopDownload:
+		sim.downloading = false
+	case protocol.ProtocolQueuedCmdClear:
+		sim.queue = nil
+		sim.active = nil
+		sim.currentIndex = sim.queueIndex
+	case protocol.ProtocolAlarmsState:
+		sim.alarms = [16]uint8{}
+	case protocol.ProtocolResetPose:
+		var manual uint8
+		var rear, front float32
+		binary.Read(reader, binary.LittleEndian, &manual)
+		binary.Read(reader, binary.LittleEndian, &rear)
+		binary.Read(reader, binary.LittleEndian, &front)
+		if manual != 0 {
+			sim.joints[1] = float64(rear)
+			sim.joints[2] = float64(front)
+		}
+		sim.updatePose()
+	case protocol.ProtocolHOMECmd:
+		sim.start(sim.planHome(), index)
+	case protocol.ProtocolJOGCmd:
+		var cmd godobot.JOGCmd
+		binary.Read(reader, binary.LittleEndian, &cmd)
+		sim.registers[message.Id] = []byte{cmd.IsJoint, cmd.Cmd}
+		sim.jog = cmd.Cmd
+		sim.jogAt = sim.clock()
+	case protocol.ProtocolPTPCmd, protocol.ProtocolPTPPOCmd:
+		var cmd godobot.PTPCmd
+		binary.Read(reader, binary.LittleEndian, &cmd)
+		if message.Id == protocol.ProtocolPTPPOCmd {
+			sim.applyParallelOutputs(reader)
+		}
+		sim.startPTP(sim.planPTP(&cmd), index)
+	case protocol.ProtocolPTPWithLCmd, protocol.ProtocolPTPPOWithLCmd:
+		var cmd godobot.PTPWithLCmd
+		binary.Read(reader, binary.LittleEndian, &cmd)
+		if message.Id == protocol.ProtocolPTPPOWithLCmd {
+			sim.applyParallelOutputs(reader)
+		}
+		m := sim.planPTP(&godobot.PTPCmd{PTPMode: cmd.PTPMode, X: cmd.X, Y: cmd.Y, Z: cmd.Z, R: cmd.R})
+		if m != nil {
+			m.railTo = float64(cmd.L)
+		}
+		sim.startPTP(m, index)
+	case protocol.ProtocolCPCmd:
+		var cmd godobot.CPCmd
+		binary.Read(reader, binary.LittleEndian, &cmd)
+		sim.start(sim.planCP(cmd.CPMode, cmd.X, cmd.Y, cmd.Z, cmd.Velocity), index)
+	case protocol.ProtocolCPLECmd:
+		var mode uint8
+		var x, y, z, power float32
+		binary.Read(reader, binary.LittleEndian, &mode)
+		binary.Read(reader, binary.LittleEndian, &x)
+		binary.Read(reader, binary.LittleEndian, &y)
+		binary.Read(reader, binary.LittleEndian, &z)
+		binary.Read(reader, binary.LittleEndian, &power)
+		sim.laser = power
+		sim.start(sim.planCP(godobot.CPMode(mode), x, y, z, 0), index)
+	case protocol.ProtocolARCCmd:
+		var cmd godobot.ARCCmd
+		binary.Read(reader, binary.LittleEndian, &cmd)
+		cir := [4]float64{float64(cmd.CirPoint.X), float64(cmd.CirPoint.Y), float64(cmd.CirPoint.Z), float64(cmd.CirPoint.R)}
+		to := [4]float64{float64(cmd.ToPoint.X), float64(cmd.ToPoint.Y), float64(cmd.ToPoint.Z), float64(cmd.ToPoint.R)}
+		sim.start(sim.planARC(cir, to, 0), index)
+	case protocol.ProtocolCircleCmd:
+		var cmd godobot.CircleCmd
+		binary.Read(reader, binary.LittleEndian, &cmd)
+		cir := [4]float64{float64(cmd.CirPoint.X), float64(cmd.CirPoint.Y), float64(cmd.CirPoint.Z), float64(cmd.CirPoint.R)}
+		to := [4]float64{float64(cmd.ToPoint.X), float64(cmd.ToPoint.Y), float64(cmd.ToPoint.Z), float64(cmd.ToPoint.R)}
+		sim.start(sim.planARC(cir, to, max(cmd.Count, 1)), index)
+	case protocol.ProtocolWAITCmd:
+		var cmd godobot.WAITCmd
+		binary.Read(reader, binary.LittleEndian, &cmd)
+		sim.start(&motion{duration: time.Duration(cmd.Timeout) * time.Millisecond, railFrom: sim.rail, railTo: sim.rail}, index)
+	case protocol.ProtocolTRIGCmd:
+		cmd := &godobot.TRIGCmd{}
+		binary.Read(reader, binary.LittleEndian, cmd)
+		if !sim.triggered(cmd) {
+			sim.start(&motion{trigger: cmd}, index)
+		}
+	case protocol.ProtocolIOMultiplexing, protocol.ProtocolIODO, protocol.ProtocolIOPWM, protocol.ProtocolEMotor, protocol.ProtocolEMotorS:
+		sim.setAddressed(message.Id, params)
+	case protocol.ProtocolEndEffectorParams:
+		sim.registers[message.Id] = append([]byte(nil), params...)
+		sim.updatePose()
+	case protocol.ProtocolColorSensor, protocol.ProtocolIRSwitch, protocol.ProtocolLostStepDetect,
+		protocol.ProtocolFirmwareSwitch, protocol.ProtocolFunctionPulseMode, protocol.ProtocolAutoLeveling:
+		// 外设使能、检测类指令不改变可读状态
+	default:
+		if len(params) > 0 {
+			sim.registers[message.Id] = append([]byte(nil), params...)
+		}
+	}
+}
+
+// startPTP 开始点到点运动并记录运动时间
+func (sim *Simulator) startPTP(m *motion, index uint64) {
+	if m != nil {
+		sim.registers[protocol.ProtocolPTPTime] = encode(float32(m.duration.Milliseconds()))
+	}
+	sim.start(m, index)
+}
+
+// applyParallelOutputs 并行输出在运动开始时生效
+func (sim *Simulator) applyParallelOutputs(reader *bytes.Reader) {
+	count, err := reader.ReadByte()
+	if err != nil {
+		return
+	}
+	outputs := make([]godobot.ParallelOutputCmd, count)
+	if err := binary.Read(reader, binary.LittleEndian, outputs); err != nil {
+		return
+	}
+	for _, output := range outputs {
+		sim.setAddressed(protocol.ProtocolIODO, []byte{uint8(output.Address), output.Level})
+	}
+}
@@ -0,0 +1,35 @@
+package simulator
+
+import (
+	"errors"
+
+	"github.com/zdypro888/godobot/kinematics"
+)
+
+// model 以末端执行器偏移构造运动学模型
+func model(bias [3]float64) *kinematics.Model {
+	m := kinematics.Magician()
+	m.Bias = bias
+	return m
+}
+
+// forward 由关节角计算末端坐标，bias 为末端执行器偏移
+func forward(joints [4]float64, bias [3]float64) (x, y, z, r float64) {
+	position := model(bias).Forward(kinematics.Joints{Angle: joints})
+	return position.X, position.Y, position.Z, position.R
+}
+
+// inverse 由末端坐标计算关节角，不可达时返回 false
+func inverse(x, y, z, r float64, bias [3]float64) ([4]float64, bool) {
+	joints, err := model(bias).Inverse(kinematics.Position{X: x, Y: y, Z: z, R: r})
+	return joints.Angle, err == nil
+}
+
+// limitViolation 返回超出限位的关节序号及方向，未超限返回 -1
+func limitViolation(joints [4]float64) (int, bool) {
+	var limitErr *kinematics.LimitError
+	if errors.As(kinematics.Magician().Check(kinematics.Joints{Angle: joints}), &limitErr) {
+		return limitErr.Axis, limitErr.Value > limitErr.Max
+	}
+	return -1, false
+}
@@ -0,0 +1,479 @@
+package simulator
+
+import (
+	"math"
+	"time"
+
+	"github.com/zdypro888/godobot"
+	"github.com/zdypro888/godobot/protocol"
+)
+
+const alarmMoveInvCalc = 0x21 // 运动：插补点不可达
+
+// motion 正在执行的运动
+type motion struct {
+	index    uint64
+	start    time.Duration
+	duration time.Duration
+	joint    bool         // true 时 points 为关节角，否则为笛卡尔坐标
+	points   [][4]float64 // 路径点，第一个为起点
+	railFrom float64
+	railTo   float64
+	trigger  *godobot.TRIGCmd
+}
+
+// profile 梯形速度曲线下走完 distance 所需时间
+func profile(distance, velocity, acceleration float64) time.Duration {
+	if distance <= 0 {
+		return 0
+	}
+	if velocity <= 0 {
+		velocity = 1
+	}
+	var seconds float64
+	if acceleration <= 0 || distance > velocity*velocity/acceleration {
+		seconds = distance / velocity
+		if acceleration > 0 {
+			seconds += velocity / acceleration
+		}
+	} else {
+		seconds = 2 * math.Sqrt(distance/acceleration)
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func distance3(a, b [4]float64) float64 {
+	return math.Sqrt((a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1]) + (a[2]-b[2])*(a[2]-b[2]))
+}
+
+func jointDistance(a, b [4]float64) float64 {
+	var distance float64
+	for i := range a {
+		distance = math.Max(distance, math.Abs(a[i]-b[i]))
+	}
+	return distance
+}
+
+// pathLength 路径长度，关节路径使用最大关节转角
+func (m *motion) pathLength() float64 {
+	var length float64
+	for i := 1; i < len(m.points); i++ {
+		if m.joint {
+			length += jointDistance(m.points[i-1], m.points[i])
+		} else {
+			length += distance3(m.points[i-1], m.points[i])
+		}
+	}
+	return length
+}
+
+// at 按进度插值路径点
+func (m *motion) at(progress float64) [4]float64 {
+	if len(m.points) == 1 || progress <= 0 {
+		return m.points[0]
+	}
+	total := m.pathLength()
+	if total == 0 || progress >= 1 {
+		// 仅旋转时线性插值 R
+		var point [4]float64
+		first, last := m.points[0], m.points[len(m.points)-1]
+		for i := range point {
+			point[i] = first[i] + (last[i]-first[i])*math.Min(progress, 1)
+		}
+		return point
+	}
+	target := total * progress
+	for i := 1; i < len(m.points); i++ {
+		var segment float64
+		if m.joint {
+			segment = jointDistance(m.points[i-1], m.points[i])
+		} else {
+			segment = distance3(m.points[i-1], m.points[i])
+		}
+		if target <= segment || i == len(m.points)-1 {
+			ratio := 1.0
+			if segment > 0 {
+				ratio = math.Min(target/segment, 1)
+			}
+			var point [4]float64
+			for k := range point {
+				point[k] = m.points[i-1][k] + (m.points[i][k]-m.points[i-1][k])*ratio
+			}
+			return point
+		}
+		target -= segment
+	}
+	return m.points[len(m.points)-1]
+}
+
+// solve 计算笛卡尔目标点的关节角，失败时触发报警
+func (sim *Simulator) solve(target [4]float64) ([4]float64, bool) {
+	joints, ok := inverse(target[0], target[1], target[2], target[3], sim.bias())
+	if !ok {
+		sim.raiseAlarm(alarmPlanInvCalc)
+		return joints, false
+	}
+	if axis, _ := limitViolation(joints); axis >= 0 {
+		sim.raiseAlarm(alarmPlanInvLimit)
+		return joints, false
+	}
+	return joints, true
+}
+
+func (sim *Simulator) ratio(id protocol.ProtocolId) (velocity, acceleration float64) {
+	var params godobot.PTPCommonParams
+	sim.param(id, &params)
+	return float64(params.VelocityRatio) / 100, float64(params.AccelerationRatio) / 100
+}
+
+// cartesianMotion 笛卡尔直线路径
+func (sim *Simulator) cartesianMotion(points [][4]float64, velocity, acceleration float64) *motion {
+	for _, point := range points[1:] {
+		if _, ok := sim.solve(point); !ok {
+			return nil
+		}
+	}
+	m := &motion{points: points, railFrom: sim.rail, railTo: sim.rail}
+	m.duration = profile(m.pathLength(), velocity, acceleration)
+	return m
+}
+
+// jointMotion 关节插补路径
+func (sim *Simulator) jointMotion(target [4]float64) *motion {
+	if axis, _ := limitViolation(target); axis >= 0 {
+		sim.raiseAlarm(alarmPlanInvLimit)
+		return nil
+	}
+	var params godobot.PTPJointParams
+	sim.param(protocol.ProtocolPTPJointParams, &params)
+	velocityRatio, accelerationRatio := sim.ratio(protocol.ProtocolPTPCommonParams)
+	m := &motion{joint: true, points: [][4]float64{sim.joints, target}, railFrom: sim.rail, railTo: sim.rail}
+	for i := range target {
+		duration := profile(math.Abs(target[i]-sim.joints[i]), float64(params.Velocity[i])*velocityRatio, float64(params.Acceleration[i])*accelerationRatio)
+		if duration > m.duration {
+			m.duration = duration
+		}
+	}
+	return m
+}
+
+// planPTP 规划点到点运动
+func (sim *Simulator) planPTP(cmd *godobot.PTPCmd) *motion {
+	target := [4]float64{float64(cmd.X), float64(cmd.Y), float64(cmd.Z), float64(cmd.R)}
+	var coordinate godobot.PTPCoordinateParams
+	sim.param(protocol.ProtocolPTPCoordinateParams, &coordinate)
+	velocityRatio, accelerationRatio := sim.ratio(protocol.ProtocolPTPCommonParams)
+	velocity := float64(coordinate.XYZVelocity) * velocityRatio
+	acceleration := float64(coordinate.XYZAcceleration) * accelerationRatio
+	switch cmd.PTPMode {
+	case godobot.PTPMOVJXYZINCMode, godobot.PTPMOVLXYZINCMode:
+		for i := range target {
+			target[i] += sim.pose[i]
+		}
+	case godobot.PTPMOVJANGLEINCMode:
+		for i := range target {
+			target[i] += sim.joints[i]
+		}
+	}
+	switch cmd.PTPMode {
+	case godobot.PTPMOVJXYZMode, godobot.PTPMOVJXYZINCMode:
+		joints, ok := sim.solve(target)
+		if !ok {
+			return nil
+		}
+		return sim.jointMotion(joints)
+	case godobot.PTPMOVLXYZMode, godobot.PTPMOVLXYZINCMode:
+		return sim.cartesianMotion([][4]float64{sim.pose, target}, velocity, acceleration)
+	case godobot.PTPJUMPXYZMode, godobot.PTPJUMPMOVLXYZMode:
+		var jump godobot.PTPJumpParams
+		sim.param(protocol.ProtocolPTPJumpParams, &jump)
+		top := math.Max(sim.pose[2], target[2]) + float64(jump.JumpHeight)
+		if jump.ZLimit > 0 {
+			top = math.Min(top, float64(jump.ZLimit))
+		}
+		top = math.Max(top, math.Max(sim.pose[2], target[2]))
+		up := [4]float64{sim.pose[0], sim.pose[1], top, sim.pose[3]}
+		over := [4]float64{target[0], target[1], top, target[3]}
+		return sim.cartesianMotion([][4]float64{sim.pose, up, over, target}, velocity, acceleration)
+	case godobot.PTPJUMPANGLEMode, godobot.PTPMOVJANGLEMode, godobot.PTPMOVLANGLEMode, godobot.PTPMOVJANGLEINCMode:
+		return sim.jointMotion(target)
+	}
+	return nil
+}
+
+// planCP 规划连续轨迹运动
+func (sim *Simulator) planCP(mode godobot.CPMode, x, y, z, velocity float32) *motion {
+	target := [4]float64{float64(x), float64(y), float64(z), sim.pose[3]}
+	if mode == godobot.CPRelativeMode {
+		target[0] += sim.pose[0]
+		target[1] += sim.pose[1]
+		target[2] += sim.pose[2]
+	}
+	speed := float64(velocity)
+	if speed <= 0 {
+		var params godobot.CPParams
+		sim.param(protocol.ProtocolCPParams, &params)
+		speed = float64(params.JuncitionVel)
+	}
+	return sim.cartesianMotion([][4]float64{sim.pose, target}, speed, 0)
+}
+
+// planARC 规划圆弧运动，count 为 0 时为经过 cir 到达 to 的圆弧，否则为经过三点的整圆，共 count 圈后回到起点
+func (sim *Simulator) planARC(cir, to [4]float64, count uint32) *motion {
+	var params godobot.ARCParams
+	sim.param(protocol.ProtocolARCParams, &params)
+	velocityRatio, accelerationRatio := sim.ratio(protocol.ProtocolARCCommonParams)
+	points := arcPoints(sim.pose, cir, to, count)
+	return sim.cartesianMotion(points, float64(params.XYZVelocity)*velocityRatio, float64(params.XYZAcceleration)*accelerationRatio)
+}
+
+// arcPoints 沿 start、cir、to 三点的外接圆每度取一点，R 沿路径线性变化；三点共线时退化为折线
+func arcPoints(start, cir, to [4]float64, count uint32) [][4]float64 {
+	sub := func(a, b [4]float64) [3]float64 { return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]} }
+	dot := func(a, b [3]float64) float64 { return a[0]*b[0] + a[1]*b[1] + a[2]*b[2] }
+	cross := func(a, b [3]float64) [3]float64 {
+		return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+	}
+	end := to
+	if count > 0 {
+		end = start
+	}
+	u, v := sub(cir, start), sub(to, start)
+	w := cross(u, v)
+	ww := dot(w, w)
+	if ww <= 1e-9*dot(u, u)*dot(v, v) {
+		if count > 0 {
+			return [][4]float64{start, cir, to, start}
+		}
+		return [][4]float64{start, cir, to}
+	}
+	// 外接圆圆心 center = start + ((|u|²v − |v|²u) × w) / (2|w|²)
+	uu, vv := dot(u, u), dot(v, v)
+	offset := cross([3]float64{uu*v[0] - vv*u[0], uu*v[1] - vv*u[1], uu*v[2] - vv*u[2]}, w)
+	var center [3]float64
+	for i := range center {
+		center[i] = start[i] + offset[i]/(2*ww)
+	}
+	// 以起点方向为 e1，绕法向 w 逆时针为正，start→cir→to 的顺序即逆时针方向
+	radius := math.Sqrt(dot(offset, offset)) / (2 * ww)
+	var e1, e2 [3]float64
+	norm := math.Sqrt(ww)
+	for i := range e1 {
+		e1[i] = (start[i] - center[i]) / radius
+	}
+	normal := [3]float64{w[0] / norm, w[1] / norm, w[2] / norm}
+	e2 = cross(normal, e1)
+	angle := func(p [4]float64) float64 {
+		d := [3]float64{p[0] - center[0], p[1] - center[1], p[2] - center[2]}
+		theta := math.Atan2(dot(d, e2), dot(d, e1))
+		if theta < 0 {
+			theta += 2 * math.Pi
+		}
+		return theta
+	}
+	sweep := angle(to)
+	if count > 0 {
+		sweep = 2 * math.Pi * float64(count)
+	}
+	steps := max(1, int(math.Ceil(sweep/(math.Pi/180))))
+	points := make([][4]float64, 0, steps+1)
+	for k := 0; k <= steps; k++ {
+		if k == steps {
+			points = append(points, end)
+			break
+		}
+		t := float64(k) / float64(steps)
+		cos, sin := math.Cos(sweep*t), math.Sin(sweep*t)
+		var point [4]float64
+		for i := 0; i < 3; i++ {
+			point[i] = center[i] + radius*(cos*e1[i]+sin*e2[i])
+		}
+		point[3] = start[3] + (end[3]-start[3])*t
+		points = append(points, point)
+	}
+	return points
+}
+
+// planHome 回零运动
+func (sim *Simulator) planHome() *motion {
+	var params godobot.HOMEParams
+	sim.param(protocol.ProtocolHOMEParams, &params)
+	joints, ok := sim.solve([4]float64{float64(params.X), float64(params.Y), float64(params.Z), float64(params.R)})
+	if !ok {
+		return nil
+	}
+	m := &motion{joint: true, points: [][4]float64{sim.joints, joints}, railFrom: sim.rail, railTo: 0}
+	m.duration = profile(jointDistance(sim.joints, joints), 30, 60) + time.Second
+	return m
+}
+
+// triggered 判断触发条件是否满足
+func (sim *Simulator) triggered(cmd *godobot.TRIGCmd) bool {
+	switch godobot.TRIGMode(cmd.Mode) {
+	case godobot.TRIGInputIOMode:
+		level := float32(sim.getAddressed(protocol.ProtocolIODI, cmd.Address, 2)[1])
+		if godobot.TRIGInputIOCondition(cmd.Condition) == godobot.TRIGInputIOEqual {
+			return level == cmd.Threshold
+		}
+		return level != cmd.Threshold
+	case godobot.TRIGADCMode:
+		var adc godobot.IOADC
+		adc.Address = cmd.Address
+		if value := sim.getAddressed(protocol.ProtocolIOADC, cmd.Address, 3); len(value) >= 3 {
+			adc.Value = uint16(value[1]) | uint16(value[2])<<8
+		}
+		value := float32(adc.Value)
+		switch godobot.TRIGADCCondition(cmd.Condition) {
+		case godobot.TRIGADCLT:
+			return value < cmd.Threshold
+		case godobot.TRIGADCLE:
+			return value <= cmd.Threshold
+		case godobot.TRIGADCGE:
+			return value >= cmd.Threshold
+		case godobot.TRIGADCGT:
+			return value > cmd.Threshold
+		}
+	}
+	return true
+}
+
+// start 开始运动，m 为 nil 时（规划失败）立即完成
+func (sim *Simulator) start(m *motion, index uint64) {
+	if m == nil {
+		return
+	}
+	m.index = index
+	m.start = sim.clock()
+	sim.active = m
+}
+
+// finish 结束当前运动
+func (sim *Simulator) finish() {
+	if sim.active.index > sim.currentIndex {
+		sim.currentIndex = sim.active.index
+	}
+	sim.active = nil
+}
+
+// moveTo 将机械臂移动到运动路径上的进度点
+func (sim *Simulator) moveTo(m *motion, progress float64) bool {
+	point := m.at(progress)
+	if m.joint {
+		sim.joints = point
+		sim.updatePose()
+	} else {
+		joints, ok := inverse(point[0], point[1], point[2], point[3], sim.bias())
+		if !ok {
+			sim.raiseAlarm(alarmMoveInvCalc)
+			return false
+		}
+		sim.joints = joints
+		sim.pose = point
+	}
+	sim.rail = m.railFrom + (m.railTo-m.railFrom)*math.Min(progress, 1)
+	return true
+}
+
+// jogStep 点动一个时间片
+func (sim *Simulator) jogStep(now time.Duration) {
+	elapsed := (now - sim.jogAt).Seconds()
+	sim.jogAt = now
+	if sim.jog == 0 || elapsed <= 0 {
+		return
+	}
+	axis := int(sim.jog-1) / 2
+	sign := 1.0
+	if sim.jog%2 == 0 {
+		sign = -1
+	}
+	var common godobot.JOGCommonParams
+	sim.param(protocol.ProtocolJOGCommonParams, &common)
+	ratio := float64(common.VelocityRatio) / 100
+	if axis == 4 {
+		var params godobot.JOGLParams
+		sim.param(protocol.ProtocolJOGLParams, &params)
+		sim.rail += sign * float64(params.Velocity) * ratio * elapsed
+		return
+	}
+	if sim.registers[protocol.ProtocolJOGCmd][0] != 0 {
+		var params godobot.JOGJointParams
+		sim.param(protocol.ProtocolJOGJointParams, &params)
+		joints := sim.joints
+		joints[axis] += sign * float64(params.Velocity[axis]) * ratio * elapsed
+		if violated, positive := limitViolation(joints); violated >= 0 {
+			code := uint8(alarmLimitBase + violated*2)
+			if !positive {
+				code++
+			}
+			sim.raiseAlarm(code)
+			sim.jog = 0
+			return
+		}
+		sim.joints = joints
+		sim.updatePose()
+		return
+	}
+	var params godobot.JOGCoordinateParams
+	sim.param(protocol.ProtocolJOGCoordinateParams, &params)
+	pose := sim.pose
+	pose[axis] += sign * float64(params.Velocity[axis]) * ratio * elapsed
+	joints, ok := inverse(pose[0], pose[1], pose[2], pose[3], sim.bias())
+	if violated, _ := limitViolation(joints); !ok || violated >= 0 {
+		sim.raiseAlarm(alarmMoveInvCalc)
+		sim.jog = 0
+		return
+	}
+	sim.joints = joints
+	sim.pose = pose
+}
+
+// step 推进一个时间片：运动插补、点动与队列调度
+func (sim *Simulator) step() {
+	now := sim.clock()
+	if m := sim.active; m != nil {
+		if m.trigger != nil {
+			if sim.triggered(m.trigger) {
+				sim.finish()
+			}
+		} else {
+			progress := 1.0
+			if m.duration > 0 {
+				progress = float64(now-m.start) / float64(m.duration)
+			}
+			if len(m.points) > 0 && !sim.moveTo(m, math.Min(progress, 1)) {
+				sim.executing = false
+				sim.finish()
+			} else if progress >= 1 {
+				sim.finish()
+			}
+		}
+	}
+	if sim.active == nil {
+		sim.jogStep(now)
+	} else {
+		sim.jogAt = now
+	}
+	for sim.active == nil && sim.executing && len(sim.queue) > 0 {
+		cmd := sim.queue[0]
+		sim.queue = sim.queue[1:]
+		sim.apply(cmd.message, cmd.index)
+		if sim.active == nil && cmd.index > sim.currentIndex {
+			sim.currentIndex = cmd.index
+		}
+	}
+}
+
+func (sim *Simulator) runGoRoutine() {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sim.closed:
+			return
+		case <-ticker.C:
+			sim.mutex.Lock()
+			sim.step()
+			sim.mutex.Unlock()
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package simulator
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// OpenPTY 创建伪终端并在主设备端提供服务，返回从设备路径，客户端使用 serial:///dev/pts/N 连接
+func (sim *Simulator) OpenPTY() (string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return "", err
+	}
+	var unlock int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		master.Close()
+		return "", errno
+	}
+	var number uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&number))); errno != 0 {
+		master.Close()
+		return "", errno
+	}
+	go func() {
+		defer master.Close()
+		sim.Serve(master)
+	}()
+	go func() {
+		<-sim.closed
+		master.Close()
+	}()
+	return fmt.Sprintf("/dev/pts/%d", number), nil
+}
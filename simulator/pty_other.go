@@ -0,0 +1,10 @@
+//go:build !linux
+
+package simulator
+
+import "errors"
+
+// OpenPTY 伪终端仅支持 Linux
+func (sim *Simulator) OpenPTY() (string, error) {
+	return "", errors.New("pty is not supported on this platform")
+}
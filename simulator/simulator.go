@@ -0,0 +1,354 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/zdypro888/godobot"
+	"github.com/zdypro888/godobot/internal"
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// QueueCapacity 指令队列容量
+const QueueCapacity = 32
+
+// 模拟器使用到的报警码
+const (
+	alarmPlanInvCalc  = 0x11 // 规划：目标点不可达
+	alarmPlanInvLimit = 0x12 // 规划：目标点超出关节限位
+	alarmLimitBase    = 0x40 // 关节限位，0x40 + 关节*2 + 反向
+)
+
+// command 队列中的指令
+type command struct {
+	index   uint64
+	message *protocol.Message
+}
+
+// Simulator Dobot Magician 固件模拟器
+//
+// 实现设备端的 0xAA 0xAA 帧协议，维护指令队列、报警、位姿与运动时序，
+// 通过 Listen 的进程内管道（pipe://name）或 OpenPTY 的伪终端（serial:///dev/pts/N）连接。
+type Simulator struct {
+	mutex     sync.Mutex
+	started   time.Time
+	timeScale float64
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	registers map[protocol.ProtocolId][]byte
+	addressed map[protocol.ProtocolId]map[uint8][]byte
+	alarms    [16]uint8
+
+	joints [4]float64
+	pose   [4]float64
+	rail   float64
+
+	queue        []*command
+	queueIndex   uint64
+	currentIndex uint64
+	executing    bool
+	downloading  bool
+	program      []*command
+	programLoops uint32
+	programLines uint32
+
+	active *motion
+	jog    uint8
+	jogAt  time.Duration
+	hhtAt  time.Duration
+	hhtKey bool
+	laser  float32
+}
+
+// New 创建模拟器并启动运动调度
+func New() *Simulator {
+	sim := &Simulator{
+		started:   time.Now(),
+		timeScale: 1,
+		closed:    make(chan struct{}),
+		registers: map[protocol.ProtocolId][]byte{},
+		addressed: map[protocol.ProtocolId]map[uint8][]byte{},
+	}
+	sim.reset()
+	go sim.runGoRoutine()
+	return sim
+}
+
+func encode(values ...any) []byte {
+	writer := &bytes.Buffer{}
+	for _, value := range values {
+		binary.Write(writer, binary.LittleEndian, value)
+	}
+	return writer.Bytes()
+}
+
+func cstring(value string) []byte {
+	return append([]byte(value), 0)
+}
+
+// reset 恢复出厂参数
+func (sim *Simulator) reset() {
+	defaults := map[protocol.ProtocolId][]byte{
+		protocol.ProtocolDeviceSN:                   cstring("DOBOTSIM0001"),
+		protocol.ProtocolDeviceName:                 cstring("Magician"),
+		protocol.ProtocolDeviceVersion:              {3, 7, 0, 1},
+		protocol.ProtocolDeviceWithL:                {0, 0},
+		protocol.ProtocolHOMEParams:                 encode(&godobot.HOMEParams{X: 200, Y: 0, Z: 0, R: 0}),
+		protocol.ProtocolAutoLeveling:               encode(float32(0)),
+		protocol.ProtocolHHTTrigMode:                {uint8(godobot.TriggeredOnKeyReleased)},
+		protocol.ProtocolHHTTrigOutputEnabled:       {0},
+		protocol.ProtocolArmOrientation:             {uint8(godobot.LeftyArmOrientation)},
+		protocol.ProtocolEndEffectorParams:          encode(&godobot.EndEffectorParams{XBias: 59.7}),
+		protocol.ProtocolEndEffectorLaser:           {0, 0},
+		protocol.ProtocolEndEffectorSuctionCup:      {0, 0},
+		protocol.ProtocolEndEffectorGripper:         {0, 0},
+		protocol.ProtocolJOGJointParams:             encode(&godobot.JOGJointParams{Velocity: [4]float32{15, 15, 15, 30}, Acceleration: [4]float32{50, 50, 50, 50}}),
+		protocol.ProtocolJOGCoordinateParams:        encode(&godobot.JOGCoordinateParams{Velocity: [4]float32{60, 60, 60, 60}, Acceleration: [4]float32{60, 60, 60, 60}}),
+		protocol.ProtocolJOGCommonParams:            encode(&godobot.JOGCommonParams{VelocityRatio: 50, AccelerationRatio: 50}),
+		protocol.ProtocolJOGLParams:                 encode(&godobot.JOGLParams{Velocity: 50, Acceleration: 50}),
+		protocol.ProtocolPTPJointParams:             encode(&godobot.PTPJointParams{Velocity: [4]float32{200, 200, 200, 200}, Acceleration: [4]float32{200, 200, 200, 200}}),
+		protocol.ProtocolPTPCoordinateParams:        encode(&godobot.PTPCoordinateParams{XYZVelocity: 200, RVelocity: 200, XYZAcceleration: 200, RAcceleration: 200}),
+		protocol.ProtocolPTPJumpParams:              encode(&godobot.PTPJumpParams{JumpHeight: 20, ZLimit: 150}),
+		protocol.ProtocolPTPCommonParams:            encode(&godobot.PTPCommonParams{VelocityRatio: 50, AccelerationRatio: 50}),
+		protocol.ProtocolPTPLParams:                 encode(&godobot.PTPLParams{Velocity: 100, Acceleration: 100}),
+		protocol.ProtocolPTPJump2Params:             encode(&godobot.PTPJump2Params{StartJumpHeight: 20, EndJumpHeight: 20, ZLimit: 150}),
+		protocol.ProtocolCPParams:                   encode(&godobot.CPParams{PlanAcc: 100, JuncitionVel: 100, AccOrPeriod: 100}),
+		protocol.ProtocolCPRHoldEnable:              {0},
+		protocol.ProtocolCPCommonParams:             encode(&godobot.CPCommonParams{VelocityRatio: 50, AccelerationRatio: 50}),
+		protocol.ProtocolARCParams:                  encode(&godobot.ARCParams{XYZVelocity: 100, RVelocity: 100, XYZAcceleration: 100, RAcceleration: 100}),
+		protocol.ProtocolARCCommonParams:            encode(&godobot.ARCCommonParams{VelocityRatio: 50, AccelerationRatio: 50}),
+		protocol.ProtocolColorSensor:                {0, 0, 0},
+		protocol.ProtocolIRSwitch:                   {0},
+		protocol.ProtocolAngleSensorStaticError:     encode(float32(0), float32(0)),
+		protocol.ProtocolAngleSensorCoef:            encode(float32(1), float32(1)),
+		protocol.ProtocolBaseDecoderStaticError:     encode(float32(0)),
+		protocol.ProtocolLRHandCalibrateValue:       encode(float32(0)),
+		protocol.ProtocolWIFIConfigMode:             {0},
+		protocol.ProtocolWIFISSID:                   cstring(""),
+		protocol.ProtocolWIFIPassword:               cstring(""),
+		protocol.ProtocolWIFIIPAddress:              {0, 0, 0, 0, 0},
+		protocol.ProtocolWIFINetmask:                {0, 0, 0, 0},
+		protocol.ProtocolWIFIGateway:                {0, 0, 0, 0},
+		protocol.ProtocolWIFIDNS:                    {0, 0, 0, 0},
+		protocol.ProtocolWIFIConnectStatus:          {0},
+		protocol.ProtocolFirmwareMode:               {uint8(godobot.FirmwareModeNormal), 0, 0},
+		protocol.ProtocolLostStepSet:                encode(float32(5)),
+		protocol.ProtocolCheckUART4PeripheralsModel: {0},
+		protocol.ProtocolUART4PeripheralsEnabled:    {0},
+		protocol.ProtocolUserParams:                 make([]byte, 32),
+		protocol.ProtocolPTPTime:                    encode(float32(0)),
+	}
+	for id, value := range defaults {
+		sim.registers[id] = value
+	}
+	sim.joints = [4]float64{0, 45, 45, 0}
+	sim.updatePose()
+}
+
+// SetTimeScale 设置模拟时间倍率，大于 1 时运动更快完成
+func (sim *Simulator) SetTimeScale(scale float64) {
+	if scale <= 0 {
+		scale = 1
+	}
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	now := sim.clock()
+	sim.started = time.Now()
+	sim.timeScale = scale
+	sim.started = sim.started.Add(-time.Duration(float64(now) / scale))
+}
+
+// clock 模拟时间（已按倍率缩放）
+func (sim *Simulator) clock() time.Duration {
+	return time.Duration(float64(time.Since(sim.started)) * sim.timeScale)
+}
+
+// Close 停止模拟器
+func (sim *Simulator) Close() error {
+	sim.closeOnce.Do(func() { close(sim.closed) })
+	return nil
+}
+
+// Listen 在进程内管道上监听，客户端使用 pipe://name 连接
+func (sim *Simulator) Listen(name string) (net.Listener, error) {
+	listener, err := internal.ListenPipe(name)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				sim.Serve(conn)
+			}()
+		}
+	}()
+	go func() {
+		<-sim.closed
+		listener.Close()
+	}()
+	return listener, nil
+}
+
+// Serve 在给定的字节流上处理协议帧，直到读取出错或模拟器关闭
+func (sim *Simulator) Serve(conn io.ReadWriter) error {
+	reader := protocol.NewReader(conn)
+	for {
+		select {
+		case <-sim.closed:
+			return nil
+		default:
+		}
+		request, err := reader.ReadMessage()
+		if err != nil {
+			var frameErr *protocol.FrameError
+			if errors.As(err, &frameErr) {
+				// 校验失败的帧固件直接丢弃
+				continue
+			}
+			return err
+		}
+		params, ok := sim.handle(request)
+		if !ok {
+			continue
+		}
+		reply := &protocol.Message{Id: request.Id, RW: request.RW, IsQueued: request.IsQueued, Params: params}
+		if _, err = conn.Write(protocol.Encode(reply)); err != nil {
+			return err
+		}
+	}
+}
+
+// Pose 当前位姿
+func (sim *Simulator) Pose() godobot.Pose {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	return sim.currentPose()
+}
+
+func (sim *Simulator) currentPose() godobot.Pose {
+	pose := godobot.Pose{X: float32(sim.pose[0]), Y: float32(sim.pose[1]), Z: float32(sim.pose[2]), R: float32(sim.pose[3])}
+	for i, joint := range sim.joints {
+		pose.JointAngle[i] = float32(joint)
+	}
+	return pose
+}
+
+// RaiseAlarm 触发报警
+func (sim *Simulator) RaiseAlarm(code uint8) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.raiseAlarm(code)
+}
+
+func (sim *Simulator) raiseAlarm(code uint8) {
+	if int(code/8) < len(sim.alarms) {
+		sim.alarms[code/8] |= 1 << (code % 8)
+	}
+}
+
+// Alarms 当前报警位图
+func (sim *Simulator) Alarms() []uint8 {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	return append([]uint8(nil), sim.alarms[:]...)
+}
+
+// PressHHTKey 模拟按下并释放手持示教按键
+func (sim *Simulator) PressHHTKey() {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.hhtKey = true
+}
+
+// SetIODI 设置数字输入电平
+func (sim *Simulator) SetIODI(address uint8, level uint8) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.setAddressed(protocol.ProtocolIODI, []byte{address, level})
+}
+
+// SetIOADC 设置模拟输入值
+func (sim *Simulator) SetIOADC(address uint8, value uint16) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.setAddressed(protocol.ProtocolIOADC, encode(address, value))
+}
+
+// CurrentIndex 已执行完成的指令索引
+func (sim *Simulator) CurrentIndex() uint64 {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	return sim.currentIndex
+}
+
+// Program 最近一次下载的离线程序，返回循环次数与每次循环的行数
+func (sim *Simulator) Program() (loops uint32, lines uint32, commands int) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	return sim.programLoops, sim.programLines, len(sim.program)
+}
+
+// RunProgram 模拟按下离线运行按键，将下载的离线程序按循环次数放入队列并开始执行
+func (sim *Simulator) RunProgram() error {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	if len(sim.program) == 0 {
+		return errors.New("no offline program")
+	}
+	for loop := uint32(0); loop < max(sim.programLoops, 1); loop++ {
+		for _, cmd := range sim.program {
+			sim.queueIndex++
+			sim.queue = append(sim.queue, &command{index: sim.queueIndex, message: cmd.message})
+		}
+	}
+	sim.executing = true
+	return nil
+}
+
+func (sim *Simulator) setAddressed(id protocol.ProtocolId, params []byte) {
+	if len(params) == 0 {
+		return
+	}
+	values, ok := sim.addressed[id]
+	if !ok {
+		values = map[uint8][]byte{}
+		sim.addressed[id] = values
+	}
+	values[params[0]] = append([]byte(nil), params...)
+}
+
+func (sim *Simulator) getAddressed(id protocol.ProtocolId, address uint8, size int) []byte {
+	if value, ok := sim.addressed[id][address]; ok {
+		return value
+	}
+	value := make([]byte, size)
+	value[0] = address
+	return value
+}
+
+// param 读取寄存器中的参数结构
+func (sim *Simulator) param(id protocol.ProtocolId, data any) {
+	binary.Read(bytes.NewReader(sim.registers[id]), binary.LittleEndian, data)
+}
+
+func (sim *Simulator) bias() [3]float64 {
+	var params godobot.EndEffectorParams
+	sim.param(protocol.ProtocolEndEffectorParams, &params)
+	return [3]float64{float64(params.XBias), float64(params.YBias), float64(params.ZBias)}
+}
+
+func (sim *Simulator) updatePose() {
+	x, y, z, r := forward(sim.joints, sim.bias())
+	sim.pose = [4]float64{x, y, z, r}
+}
@@ -0,0 +1,198 @@
+package simulator
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot"
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// connect 启动模拟器并连接，测试结束时关闭
+func connect(t *testing.T, name string, scale float64) (*Simulator, *godobot.Dobot) {
+	t.Helper()
+	sim := New()
+	sim.SetTimeScale(scale)
+	if _, err := sim.Listen(name); err != nil {
+		t.Fatal(err)
+	}
+	dobot := godobot.NewDobot()
+	if err := dobot.Connect("pipe://" + name); err != nil {
+		sim.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		dobot.Close()
+		sim.Close()
+	})
+	return sim, dobot
+}
+
+// TestQueueFull 队列存满 QueueCapacity 条后固件丢弃新的队列指令且不应答，非队列指令照常应答
+func TestQueueFull(t *testing.T) {
+	sim := New()
+	if _, err := sim.Listen("queue-full"); err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Close()
+	port, err := godobot.OpenTransport("pipe://queue-full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+	reader := protocol.NewReader(port)
+	exchange := func(message *protocol.Message) *protocol.Message {
+		t.Helper()
+		if _, err := port.Write(protocol.Encode(message)); err != nil {
+			t.Fatal(err)
+		}
+		reply, err := reader.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reply
+	}
+	wait := &protocol.Message{Id: protocol.ProtocolWAITCmd, RW: true, IsQueued: true, Params: binary.LittleEndian.AppendUint32(nil, 1)}
+	// 未开始执行，指令全部留在队列中
+	for i := 1; i <= QueueCapacity; i++ {
+		if reply := exchange(wait); reply.Id != protocol.ProtocolWAITCmd || reply.Uint64() != uint64(i) {
+			t.Fatalf("command %d: reply %+v, want index %d", i, reply, i)
+		}
+	}
+	if _, err := port.Write(protocol.Encode(wait)); err != nil {
+		t.Fatal(err)
+	}
+	// 被丢弃的指令没有应答，读到的第一个应答属于之后的查询
+	reply := exchange(&protocol.Message{Id: protocol.ProtocolQueuedCmdLeftSpace})
+	if reply.Id != protocol.ProtocolQueuedCmdLeftSpace || reply.Uint32() != 0 {
+		t.Fatalf("reply %+v, want left space 0", reply)
+	}
+	exchange(&protocol.Message{Id: protocol.ProtocolQueuedCmdClear, RW: true})
+	if reply := exchange(wait); reply.Uint64() != QueueCapacity+1 {
+		t.Errorf("after clear: index %d, want %d", reply.Uint64(), QueueCapacity+1)
+	}
+}
+
+// TestArcPoints 路径点都在三点的外接圆上，经过中间点并停在终点；整圆回到起点
+func TestArcPoints(t *testing.T) {
+	start, cir, to := [4]float64{200, 0, 0, 0}, [4]float64{240, 40, 0, 0}, [4]float64{200, 80, 0, 10}
+	center := [3]float64{200, 40, 0}
+	for _, count := range []uint32{0, 1} {
+		points := arcPoints(start, cir, to, count)
+		nearest := math.Inf(1)
+		for _, point := range points {
+			radius := math.Hypot(point[0]-center[0], point[1]-center[1])
+			if math.Abs(radius-40) > 1e-9 || point[2] != 0 {
+				t.Fatalf("count %d: point %v off the circumcircle", count, point)
+			}
+			nearest = min(nearest, math.Hypot(point[0]-cir[0], point[1]-cir[1]))
+		}
+		if nearest > 0.5 {
+			t.Errorf("count %d: path misses the circle point by %g", count, nearest)
+		}
+		end := to
+		if count > 0 {
+			end = start
+		}
+		if last := points[len(points)-1]; last != end {
+			t.Errorf("count %d: ends at %v, want %v", count, last, end)
+		}
+	}
+}
+
+// TestARC 模拟器执行 ARC 指令时位姿沿外接圆运动
+func TestARC(t *testing.T) {
+	sim, dobot := connect(t, "arc", 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := dobot.SetQueuedCmdStartExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := dobot.QueuedComplete(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+		return dobot.SetPTPCmd(ctx, &godobot.PTPCmd{PTPMode: godobot.PTPMOVLXYZMode, X: 200}, true)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	arc := &godobot.ARCCmd{}
+	arc.CirPoint.X, arc.CirPoint.Y = 240, 40
+	arc.ToPoint.X, arc.ToPoint.Y = 200, 80
+	cmd, err := dobot.SetARCCmd(ctx, arc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait(ctx) }()
+	samples := 0
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			if samples < 5 {
+				t.Errorf("only %d samples during the arc", samples)
+			}
+			if pose := sim.Pose(); math.Abs(float64(pose.X)-200) > 0.01 || math.Abs(float64(pose.Y)-80) > 0.01 {
+				t.Errorf("arc ended at %+v, want (200, 80)", pose)
+			}
+			return
+		case <-time.After(5 * time.Millisecond):
+		}
+		pose := sim.Pose()
+		if radius := math.Hypot(float64(pose.X)-200, float64(pose.Y)-40); math.Abs(radius-40) > 0.05 || math.Abs(float64(pose.Z)) > 0.01 {
+			t.Fatalf("pose %+v off the circumcircle, radius %g", pose, radius)
+		}
+		samples++
+	}
+}
+
+// TestAlarms 不可达的目标触发规划报警且不运动；RaiseAlarm 的报警可读出，清除后为空
+func TestAlarms(t *testing.T) {
+	sim, dobot := connect(t, "alarms", 100)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := dobot.SetQueuedCmdStartExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	before := sim.Pose()
+	cmd, err := dobot.SetPTPCmd(ctx, &godobot.PTPCmd{PTPMode: godobot.PTPMOVLXYZMode, X: 500}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for sim.CurrentIndex() < cmd.Index() {
+		select {
+		case <-ctx.Done():
+			t.Fatal("unreachable command never finished")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	alarms, err := dobot.GetAlarmsState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !alarms.Has(alarmPlanInvCalc) {
+		t.Errorf("alarms %v, want plan inverse calculation alarm", alarms)
+	}
+	if pose := sim.Pose(); pose.X != before.X || pose.Y != before.Y || pose.Z != before.Z {
+		t.Errorf("moved to %+v on unreachable target", pose)
+	}
+
+	sim.RaiseAlarm(alarmLimitBase + 1)
+	if alarms, err = dobot.GetAlarmsState(ctx); err != nil || !alarms.Has(alarmLimitBase+1) || !alarms.Has(alarmPlanInvCalc) {
+		t.Errorf("alarms %v, error %v, want raised alarm kept with plan alarm", alarms, err)
+	}
+	if err := dobot.ClearAllAlarmsState(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if alarms, err = dobot.GetAlarmsState(ctx); err != nil || !alarms.Empty() {
+		t.Errorf("after clear: alarms %v, error %v", alarms, err)
+	}
+	for _, b := range sim.Alarms() {
+		if b != 0 {
+			t.Fatalf("simulator alarms %v after clear", sim.Alarms())
+		}
+	}
+}
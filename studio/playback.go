@@ -0,0 +1,364 @@
+// Package studio DobotStudio 示教再现文件的导入与导出
+//
+// 示教再现文件为 XML：根元素下 DobotType 记录机型，row0、row1…… 每行一个示教点，
+// 行内 item_0、item_1…… 依次为各列，列的含义见 Layout
+package studio
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zdypro888/godobot"
+)
+
+var ErrFormat = errors.New("bad playback file")
+
+// RowError 第 Row 行（从 0 开始）解析失败
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (err *RowError) Error() string {
+	return fmt.Sprintf("playback row %d: %v", err.Row, err.Err)
+}
+
+func (err *RowError) Unwrap() error {
+	return err.Err
+}
+
+// Layout 各列对应的 item 序号，负数表示文件中没有该列
+type Layout struct {
+	Motion     int // 运动方式：MOVJ、MOVL、JUMP 或对应的 PTPMode 数值 1、2、0
+	Name       int // 示教点名称
+	X, Y, Z, R int // 坐标
+	Pause      int // 到达后暂停时间（秒）
+	SuctionCup int // 吸盘状态，0 关 1 开
+	Gripper    int // 夹爪状态，0 松开 1 夹紧
+	Laser      int // 激光状态，0 关 1 开
+}
+
+// DefaultLayout DobotStudio Magician 示教再现文件的列顺序
+var DefaultLayout = Layout{
+	Motion:     0,
+	Name:       1,
+	X:          2,
+	Y:          3,
+	Z:          4,
+	R:          5,
+	Pause:      6,
+	SuctionCup: 7,
+	Gripper:    8,
+	Laser:      9,
+}
+
+// motions 文件中的运动方式，数值写法为 PTPMode 的值
+var motions = []struct {
+	name string
+	mode godobot.PTPMode
+}{
+	{"MOVJ", godobot.PTPMOVJXYZMode},
+	{"MOVL", godobot.PTPMOVLXYZMode},
+	{"JUMP", godobot.PTPJUMPXYZMode},
+}
+
+// Row 一个示教点
+type Row struct {
+	Motion     godobot.PTPMode
+	Name       string
+	X, Y, Z, R float32
+	Pause      time.Duration
+	SuctionCup bool
+	Gripper    bool
+	Laser      bool
+}
+
+// Playback 示教再现程序
+type Playback struct {
+	DobotType string // 机型，导出时为空则写 Magician
+	Rows      []Row
+}
+
+// Read 按 layout 解析示教再现文件，layout 为 nil 时使用 DefaultLayout
+func Read(r io.Reader, layout *Layout) (*Playback, error) {
+	if layout == nil {
+		layout = &DefaultLayout
+	}
+	playback := &Playback{}
+	decoder := xml.NewDecoder(r)
+	// path 当前元素路径，根元素为 path[0]
+	var path []string
+	var text strings.Builder
+	var items map[int]string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			path = append(path, token.Name.Local)
+			text.Reset()
+			if len(path) == 2 && strings.HasPrefix(token.Name.Local, "row") {
+				items = map[int]string{}
+			}
+		case xml.CharData:
+			text.Write(token)
+		case xml.EndElement:
+			switch {
+			case len(path) == 2 && path[1] == "DobotType":
+				playback.DobotType = strings.TrimSpace(text.String())
+			case len(path) == 2 && items != nil:
+				row, err := layout.row(items)
+				if err != nil {
+					return nil, &RowError{Row: len(playback.Rows), Err: err}
+				}
+				playback.Rows = append(playback.Rows, row)
+				items = nil
+			case len(path) == 3 && items != nil && strings.HasPrefix(path[2], "item_"):
+				index, err := strconv.Atoi(strings.TrimPrefix(path[2], "item_"))
+				if err != nil {
+					return nil, &RowError{Row: len(playback.Rows), Err: fmt.Errorf("%w: element %s", ErrFormat, path[2])}
+				}
+				items[index] = strings.TrimSpace(text.String())
+			}
+			path = path[:len(path)-1]
+			text.Reset()
+		}
+	}
+	if len(path) != 0 {
+		return nil, fmt.Errorf("%w: unexpected end of file", ErrFormat)
+	}
+	return playback, nil
+}
+
+// row 由各列文本构造示教点
+func (layout *Layout) row(items map[int]string) (Row, error) {
+	var row Row
+	var err error
+	text := func(column int) string {
+		if column < 0 {
+			return ""
+		}
+		return items[column]
+	}
+	number := func(column int, name string) float64 {
+		value := text(column)
+		if value == "" || err != nil {
+			return 0
+		}
+		parsed, parseErr := strconv.ParseFloat(value, 64)
+		if parseErr != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+			err = fmt.Errorf("%w: %s %q", ErrFormat, name, value)
+		}
+		return parsed
+	}
+	state := func(column int, name string) bool {
+		value := number(column, name)
+		if err == nil && value != 0 && value != 1 {
+			err = fmt.Errorf("%w: %s %q", ErrFormat, name, text(column))
+		}
+		return value == 1
+	}
+	motion := strings.ToUpper(text(layout.Motion))
+	row.Motion = motions[0].mode
+	if motion != "" {
+		found := false
+		for _, m := range motions {
+			if motion == m.name || motion == strconv.Itoa(int(m.mode)) {
+				row.Motion, found = m.mode, true
+			}
+		}
+		if !found {
+			return row, fmt.Errorf("%w: motion style %q", ErrFormat, text(layout.Motion))
+		}
+	}
+	row.Name = text(layout.Name)
+	row.X = float32(number(layout.X, "X"))
+	row.Y = float32(number(layout.Y, "Y"))
+	row.Z = float32(number(layout.Z, "Z"))
+	row.R = float32(number(layout.R, "R"))
+	row.Pause = time.Duration(number(layout.Pause, "pause time") * float64(time.Second))
+	row.SuctionCup = state(layout.SuctionCup, "suction cup")
+	row.Gripper = state(layout.Gripper, "gripper")
+	row.Laser = state(layout.Laser, "laser")
+	if err == nil && row.Pause < 0 {
+		err = fmt.Errorf("%w: negative pause time", ErrFormat)
+	}
+	return row, err
+}
+
+// Write 按 layout 导出示教再现文件，layout 为 nil 时使用 DefaultLayout
+func (playback *Playback) Write(w io.Writer, layout *Layout) error {
+	if layout == nil {
+		layout = &DefaultLayout
+	}
+	dobotType := playback.DobotType
+	if dobotType == "" {
+		dobotType = "Magician"
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	root := xml.StartElement{Name: xml.Name{Local: "root"}}
+	if err := encoder.EncodeToken(root); err != nil {
+		return err
+	}
+	if err := encoder.EncodeElement(dobotType, xml.StartElement{Name: xml.Name{Local: "DobotType"}}); err != nil {
+		return err
+	}
+	for i, row := range playback.Rows {
+		start := xml.StartElement{Name: xml.Name{Local: fmt.Sprintf("row%d", i)}}
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		for index, value := range layout.items(&row) {
+			if err := encoder.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: fmt.Sprintf("item_%d", index)}}); err != nil {
+				return err
+			}
+		}
+		if err := encoder.EncodeToken(start.End()); err != nil {
+			return err
+		}
+	}
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// items 示教点各列的文本，按 item 序号排列，未映射的列为空
+func (layout *Layout) items(row *Row) []string {
+	columns := []int{layout.Motion, layout.Name, layout.X, layout.Y, layout.Z, layout.R, layout.Pause, layout.SuctionCup, layout.Gripper, layout.Laser}
+	motion := motions[0].name
+	for _, m := range motions {
+		if m.mode == row.Motion {
+			motion = m.name
+		}
+	}
+	values := []string{
+		motion,
+		row.Name,
+		formatFloat(float64(row.X)),
+		formatFloat(float64(row.Y)),
+		formatFloat(float64(row.Z)),
+		formatFloat(float64(row.R)),
+		formatFloat(row.Pause.Seconds()),
+		formatBool(row.SuctionCup),
+		formatBool(row.Gripper),
+		formatBool(row.Laser),
+	}
+	var items []string
+	for i, column := range columns {
+		if column < 0 {
+			continue
+		}
+		for len(items) <= column {
+			items = append(items, "")
+		}
+		items[column] = values[i]
+	}
+	return items
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 32)
+}
+
+func formatBool(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
+
+// FromPoses 由采集到的位姿（如 draw.Robot.Capture 的结果）构造示教再现程序
+func FromPoses(poses []*godobot.Pose, motion godobot.PTPMode) *Playback {
+	playback := &Playback{}
+	for _, pose := range poses {
+		if pose == nil {
+			continue
+		}
+		playback.Rows = append(playback.Rows, Row{Motion: motion, X: pose.X, Y: pose.Y, Z: pose.Z, R: pose.R})
+	}
+	return playback
+}
+
+// Steps 将示教点转换为队列指令：运动到示教点，末端执行器状态变化时切换，再按暂停时间等待。
+// 第一行之前末端执行器视为全部关闭
+func (playback *Playback) Steps() []godobot.ProgramStep {
+	var steps []godobot.ProgramStep
+	var prev Row
+	for _, row := range playback.Rows {
+		cmd := &godobot.PTPCmd{PTPMode: row.Motion, X: row.X, Y: row.Y, Z: row.Z, R: row.R}
+		steps = append(steps, func(ctx context.Context, dobot *godobot.Dobot) (*godobot.QueuedCommand, error) {
+			return dobot.SetPTPCmd(ctx, cmd, true)
+		})
+		if row.SuctionCup != prev.SuctionCup {
+			suck := row.SuctionCup
+			steps = append(steps, func(ctx context.Context, dobot *godobot.Dobot) (*godobot.QueuedCommand, error) {
+				return dobot.SetEndEffectorSuctionCup(ctx, true, suck, true)
+			})
+		}
+		if row.Gripper != prev.Gripper {
+			grip := row.Gripper
+			steps = append(steps, func(ctx context.Context, dobot *godobot.Dobot) (*godobot.QueuedCommand, error) {
+				return dobot.SetEndEffectorGripper(ctx, true, grip, true)
+			})
+		}
+		if row.Laser != prev.Laser {
+			on := row.Laser
+			steps = append(steps, func(ctx context.Context, dobot *godobot.Dobot) (*godobot.QueuedCommand, error) {
+				return dobot.SetEndEffectorLaser(ctx, true, on, true)
+			})
+		}
+		if row.Pause > 0 {
+			wait := &godobot.WAITCmd{Timeout: uint32(row.Pause.Milliseconds())}
+			steps = append(steps, func(ctx context.Context, dobot *godobot.Dobot) (*godobot.QueuedCommand, error) {
+				return dobot.SetWAITCmd(ctx, wait, true)
+			})
+		}
+		prev = row
+	}
+	return steps
+}
+
+// Run 依次发送示教点对应的队列指令并等待执行完成，调用前需 SetQueuedCmdStartExec
+func (playback *Playback) Run(ctx context.Context, dobot *godobot.Dobot) error {
+	var last *godobot.QueuedCommand
+	for i, step := range playback.Steps() {
+		cmd, err := dobot.QueuedSend(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return step(ctx, dobot)
+		})
+		if err != nil {
+			return fmt.Errorf("playback step %d: %w", i, err)
+		}
+		last = cmd
+	}
+	return last.Wait(ctx)
+}
+
+// Program 转换为离线程序，可通过 DownloadProgram 下载到控制器
+func (playback *Playback) Program(loops uint32) *godobot.Program {
+	program := godobot.NewProgram(loops)
+	for _, step := range playback.Steps() {
+		program.Add(step)
+	}
+	return program
+}
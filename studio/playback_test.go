@@ -0,0 +1,84 @@
+package studio
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot"
+)
+
+func TestReadStudioExport(t *testing.T) {
+	file, err := os.Open("testdata/magician.playback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	playback, err := Read(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Playback{
+		DobotType: "Magician",
+		Rows: []Row{
+			{Motion: godobot.PTPMOVJXYZMode, Name: "home", X: 200, Z: 50},
+			{Motion: godobot.PTPJUMPXYZMode, Name: "pick", X: 237.4263, Y: -53.8732, Z: -24.9436, R: -12.9336, Pause: 500 * time.Millisecond, SuctionCup: true},
+			{Motion: godobot.PTPMOVLXYZMode, X: 180.125, Y: 120.5, Z: 10, R: 33.75, Pause: 1250 * time.Millisecond, Laser: true},
+		},
+	}
+	if !reflect.DeepEqual(playback, want) {
+		t.Fatalf("Read = %+v, want %+v", playback, want)
+	}
+	// 导出后重新读入应得到相同的示教点
+	var buffer bytes.Buffer
+	if err := playback.Write(&buffer, nil); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Read(&buffer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, playback) {
+		t.Fatalf("round trip = %+v, want %+v", again, playback)
+	}
+}
+
+func TestMotionStyle(t *testing.T) {
+	tests := []struct {
+		text string
+		want godobot.PTPMode
+		err  bool
+	}{
+		{"MOVJ", godobot.PTPMOVJXYZMode, false},
+		{"movl", godobot.PTPMOVLXYZMode, false},
+		{"JUMP", godobot.PTPJUMPXYZMode, false},
+		{"", godobot.PTPMOVJXYZMode, false},
+		{"0", godobot.PTPJUMPXYZMode, false},
+		{"1", godobot.PTPMOVJXYZMode, false},
+		{"2", godobot.PTPMOVLXYZMode, false},
+		{"3", 0, true},
+		{"ARC", 0, true},
+	}
+	for _, test := range tests {
+		xml := "<root><row0><item_0>" + test.text + "</item_0></row0></root>"
+		playback, err := Read(strings.NewReader(xml), nil)
+		if test.err {
+			var rowErr *RowError
+			if !errors.As(err, &rowErr) || !errors.Is(err, ErrFormat) {
+				t.Errorf("%q: err = %v, want row format error", test.text, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.text, err)
+			continue
+		}
+		if got := playback.Rows[0].Motion; got != test.want {
+			t.Errorf("%q: motion = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package teach
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zdypro888/godobot"
+)
+
+// ReplayOptions 回放参数
+type ReplayOptions struct {
+	Motion    Motion  // 统一使用的运动方式，为 0 时按各点记录的方式
+	TimeScale float64 // 大于 0 时按记录的时间回放，2 为两倍速；为 0 时按运动参数连续执行
+	Velocity  float64 // CP 运动的速度（毫米/秒），按时间回放时为速度上限，默认 50
+	Smooth    int     // 轨迹平滑的窗口点数，见 Trajectory.Smoothed
+}
+
+// Replay 回放示教轨迹并等待执行完成，调用前需 SetQueuedCmdStartExec。
+// 第一点总以 MOVJ 到达，之后 PTP 点按记录时间发送；CP 点相对最后发送的位置移动超过 0.1 毫米或转过 0.1 度时才发送，
+// 速度由移动距离与所用时间决定且不超过 Velocity，停留时间以等待补足；CP 指令不含 R 轴，R 有变化的点改以 MOVL 发送，
+// 按 PTP 参数的速度运动；记录了末端执行器状态时在状态变化处切换，开始前视为全部关闭
+func (trajectory *Trajectory) Replay(ctx context.Context, dobot *godobot.Dobot, options ReplayOptions) error {
+	if options.TimeScale < 0 || options.Velocity < 0 {
+		return fmt.Errorf("%w: time scale %g, velocity %g", godobot.ErrInvalidParams, options.TimeScale, options.Velocity)
+	}
+	if options.Velocity == 0 {
+		options.Velocity = 50
+	}
+	points := trajectory.Points
+	if options.Smooth > 1 {
+		points = trajectory.Smoothed(options.Smooth).Points
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	var last *godobot.QueuedCommand
+	queue := func(command godobot.QueuedCommander) error {
+		cmd, err := dobot.QueuedSend(ctx, command)
+		if err != nil {
+			return err
+		}
+		last = cmd
+		return nil
+	}
+	// scaled 记录时间换算为回放时间
+	scaled := func(d time.Duration) time.Duration {
+		return time.Duration(float64(d) / options.TimeScale)
+	}
+	// dwell 以等待补足 CP 回放中已记录但尚未回放的停留时间
+	var accounted, held time.Duration // 已回放到的记录时间、最后一个仍停留在 sent 附近的点的时间
+	dwell := func() error {
+		if options.TimeScale == 0 {
+			return nil
+		}
+		wait := scaled(held - accounted)
+		accounted = held
+		if wait < time.Millisecond {
+			return nil
+		}
+		cmd := &godobot.WAITCmd{Timeout: uint32(wait.Milliseconds())}
+		return queue(func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return dobot.SetWAITCmd(ctx, cmd, true)
+		})
+	}
+	var start time.Time
+	var prev, sent Point // 上一个记录点、最后发送的位置
+	for i, point := range points {
+		motion := point.Motion
+		if options.Motion != 0 {
+			motion = options.Motion
+		}
+		if i == 0 {
+			motion = MOVJ
+		}
+		switch {
+		case motion != CP:
+			if i > 0 && options.TimeScale > 0 {
+				// 按时间发送，运动快于记录时保持原有节奏
+				timer := time.NewTimer(time.Until(start.Add(scaled(point.Time - points[0].Time))))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+			cmd := &godobot.PTPCmd{PTPMode: motion.ptpMode(), X: point.X, Y: point.Y, Z: point.Z, R: point.R}
+			if err := queue(func(ctx context.Context) (*godobot.QueuedCommand, error) {
+				return dobot.SetPTPCmd(ctx, cmd, true)
+			}); err != nil {
+				return err
+			}
+			sent, accounted, held = point, point.Time, point.Time
+		case options.TimeScale > 0 && point.distance(&sent) < 0.1 && !point.rotated(&sent):
+			// 相对最后发送的位置移动不足 0.1 毫米且未旋转时暂不发送，慢速移动累积到阈值后再运动
+			held = point.Time
+		case options.TimeScale > 0:
+			// 离开前的停留以等待回放，其余时间用于运动
+			if err := dwell(); err != nil {
+				return err
+			}
+			velocity := options.Velocity
+			if elapsed := scaled(point.Time - held); elapsed > 0 {
+				velocity = min(point.distance(&sent)/elapsed.Seconds(), options.Velocity)
+			}
+			if err := queueCP(&point, &sent, velocity, queue, dobot); err != nil {
+				return err
+			}
+			sent, accounted, held = point, point.Time, point.Time
+		default:
+			if err := queueCP(&point, &sent, options.Velocity, queue, dobot); err != nil {
+				return err
+			}
+			sent = point
+		}
+		if trajectory.Effectors && !point.sameEffectors(&prev) {
+			if err := dwell(); err != nil {
+				return err
+			}
+			if err := switchEffectors(&prev, &point, queue, dobot); err != nil {
+				return err
+			}
+		}
+		if i == 0 && options.TimeScale > 0 {
+			// 到达第一点后开始计时
+			if err := last.Wait(ctx); err != nil {
+				return err
+			}
+			start = time.Now()
+		}
+		prev = point
+	}
+	return last.Wait(ctx)
+}
+
+// queueCP 以 CP 运动到示教点；CP 指令不含 R 轴，R 相对 sent 有变化时改以 MOVL 运动
+func queueCP(point, sent *Point, velocity float64, queue func(godobot.QueuedCommander) error, dobot *godobot.Dobot) error {
+	if point.rotated(sent) {
+		ptp := &godobot.PTPCmd{PTPMode: godobot.PTPMOVLXYZMode, X: point.X, Y: point.Y, Z: point.Z, R: point.R}
+		return queue(func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return dobot.SetPTPCmd(ctx, ptp, true)
+		})
+	}
+	cmd := &godobot.CPCmd{CPMode: godobot.CPAbsoluteMode, X: point.X, Y: point.Y, Z: point.Z, Velocity: float32(velocity)}
+	return queue(func(ctx context.Context) (*godobot.QueuedCommand, error) {
+		return dobot.SetCPCmd(ctx, cmd, true)
+	})
+}
+
+// switchEffectors 切换状态发生变化的末端执行器
+func switchEffectors(prev, point *Point, queue func(godobot.QueuedCommander) error, dobot *godobot.Dobot) error {
+	if point.SuctionCup != prev.SuctionCup {
+		if err := queue(func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return dobot.SetEndEffectorSuctionCup(ctx, true, point.SuctionCup, true)
+		}); err != nil {
+			return err
+		}
+	}
+	if point.Gripper != prev.Gripper {
+		if err := queue(func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return dobot.SetEndEffectorGripper(ctx, true, point.Gripper, true)
+		}); err != nil {
+			return err
+		}
+	}
+	if point.Laser != prev.Laser {
+		if err := queue(func(ctx context.Context) (*godobot.QueuedCommand, error) {
+			return dobot.SetEndEffectorLaser(ctx, true, point.Laser, true)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
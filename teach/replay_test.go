@@ -0,0 +1,121 @@
+package teach
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot"
+	"github.com/zdypro888/godobot/protocol"
+	"github.com/zdypro888/godobot/simulator"
+)
+
+// moveTo 以 MOVL 运动到目标并等待完成
+func moveTo(ctx context.Context, dobot *godobot.Dobot, x, y, z, r float32) error {
+	cmd := &godobot.PTPCmd{PTPMode: godobot.PTPMOVLXYZMode, X: x, Y: y, Z: z, R: r}
+	return dobot.QueuedComplete(ctx, func(ctx context.Context) (*godobot.QueuedCommand, error) {
+		return dobot.SetPTPCmd(ctx, cmd, true)
+	})
+}
+
+// TestRoundTrip 在模拟器上周期采集一段含旋转的运动，写出再读入后回放，应回到采集的终点且 R 轴一致，
+// 发送的 CP 速度不超过 Velocity
+func TestRoundTrip(t *testing.T) {
+	sim := simulator.New()
+	sim.SetTimeScale(10)
+	if _, err := sim.Listen("teach-round-trip"); err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Close()
+	dobot := godobot.NewDobot()
+	if err := dobot.Connect("pipe://teach-round-trip"); err != nil {
+		t.Fatal(err)
+	}
+	defer dobot.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := dobot.SetQueuedCmdStartExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	recordCtx, stopRecord := context.WithCancel(ctx)
+	moved := make(chan error, 1)
+	go func() {
+		defer stopRecord()
+		time.Sleep(100 * time.Millisecond)
+		for _, target := range [][4]float32{{200, 0, 0, 0}, {200, 60, 20, 30}, {180, 60, 20, 30}} {
+			if err := moveTo(ctx, dobot, target[0], target[1], target[2], target[3]); err != nil {
+				moved <- err
+				return
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		moved <- nil
+	}()
+	recorded, err := Record(recordCtx, dobot, Options{Mode: godobot.TriggeredOnPeriodicInterval})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-moved; err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded.Points) < 3 {
+		t.Fatalf("recorded %d points, want at least 3", len(recorded.Points))
+	}
+
+	var file bytes.Buffer
+	if err := recorded.Write(&file); err != nil {
+		t.Fatal(err)
+	}
+	trajectory, err := Read(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(trajectory, recorded) {
+		t.Fatalf("read back %+v, want %+v", trajectory, recorded)
+	}
+
+	if err := moveTo(ctx, dobot, 160, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	var link bytes.Buffer
+	if err := dobot.StartRecording(&link); err != nil {
+		t.Fatal(err)
+	}
+	const velocity = 40
+	if err := trajectory.Replay(ctx, dobot, ReplayOptions{TimeScale: 1, Velocity: velocity}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dobot.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+	end := trajectory.Points[len(trajectory.Points)-1]
+	pose := sim.Pose()
+	if math.Abs(float64(pose.X-end.X)) > 0.01 || math.Abs(float64(pose.Y-end.Y)) > 0.01 || math.Abs(float64(pose.Z-end.Z)) > 0.01 || math.Abs(float64(pose.R-end.R)) > 0.01 {
+		t.Errorf("replay ended at %+v, want %+v", pose, end)
+	}
+
+	session, err := godobot.ReadRecording(&link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range session.Records {
+		decoder := protocol.NewDecoder()
+		decoder.Write(record.Data)
+		message, _ := decoder.Next()
+		if record.Direction != godobot.RecordTx || message == nil || message.Id != protocol.ProtocolCPCmd {
+			continue
+		}
+		var cmd godobot.CPCmd
+		if err := binary.Read(bytes.NewReader(message.Params), binary.LittleEndian, &cmd); err != nil {
+			t.Fatal(err)
+		}
+		if cmd.Velocity > velocity {
+			t.Errorf("CP velocity %g, want <= %d", cmd.Velocity, velocity)
+		}
+	}
+}
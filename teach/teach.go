@@ -0,0 +1,290 @@
+// Package teach 手持示教的采集与再现
+//
+// Record 由 HHT 按键或周期触发采集位姿、时间与末端执行器状态，Trajectory 可保存为 JSON 文件，
+// Replay 以 PTP 或 CP 回放，支持按记录的时间缩放与轨迹平滑
+package teach
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/zdypro888/godobot"
+	"github.com/zdypro888/godobot/studio"
+)
+
+var ErrFormat = errors.New("bad teach file")
+
+// Motion 到达示教点的运动方式
+type Motion uint8
+
+const (
+	MOVJ Motion = iota + 1 // 关节插补
+	MOVL                   // 直线插补
+	JUMP                   // 门型
+	CP                     // 连续轨迹
+)
+
+var motionNames = map[Motion]string{MOVJ: "MOVJ", MOVL: "MOVL", JUMP: "JUMP", CP: "CP"}
+
+func (motion Motion) String() string {
+	if name, ok := motionNames[motion]; ok {
+		return name
+	}
+	return fmt.Sprintf("Motion(%d)", uint8(motion))
+}
+
+func (motion Motion) MarshalText() ([]byte, error) {
+	if _, ok := motionNames[motion]; !ok {
+		return nil, fmt.Errorf("%w: motion %d", ErrFormat, motion)
+	}
+	return []byte(motion.String()), nil
+}
+
+func (motion *Motion) UnmarshalText(text []byte) error {
+	for m, name := range motionNames {
+		if name == string(text) {
+			*motion = m
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: motion %q", ErrFormat, text)
+}
+
+// ptpMode 对应的 PTP 模式，CP 按直线插补
+func (motion Motion) ptpMode() godobot.PTPMode {
+	switch motion {
+	case MOVL, CP:
+		return godobot.PTPMOVLXYZMode
+	case JUMP:
+		return godobot.PTPJUMPXYZMode
+	}
+	return godobot.PTPMOVJXYZMode
+}
+
+// Point 一个示教点
+type Point struct {
+	Time       time.Duration `json:"time"` // 相对采集开始的时间（纳秒）
+	Motion     Motion        `json:"motion"`
+	X          float32       `json:"x"`
+	Y          float32       `json:"y"`
+	Z          float32       `json:"z"`
+	R          float32       `json:"r"`
+	SuctionCup bool          `json:"suction_cup,omitempty"`
+	Gripper    bool          `json:"gripper,omitempty"`
+	Laser      bool          `json:"laser,omitempty"`
+}
+
+// distance 两点的空间距离（毫米）
+func (point *Point) distance(other *Point) float64 {
+	return math.Sqrt(float64((point.X-other.X)*(point.X-other.X) + (point.Y-other.Y)*(point.Y-other.Y) + (point.Z-other.Z)*(point.Z-other.Z)))
+}
+
+// rotated R 轴相对另一点是否转过 0.1 度以上
+func (point *Point) rotated(other *Point) bool {
+	return math.Abs(float64(point.R-other.R)) >= 0.1
+}
+
+// sameEffectors 末端执行器状态是否相同
+func (point *Point) sameEffectors(other *Point) bool {
+	return point.SuctionCup == other.SuctionCup && point.Gripper == other.Gripper && point.Laser == other.Laser
+}
+
+// Trajectory 示教轨迹
+type Trajectory struct {
+	Effectors bool    `json:"effectors"` // 是否记录了末端执行器状态，否则回放时不控制末端执行器
+	Points    []Point `json:"points"`
+}
+
+// Load 读取示教文件
+func Load(path string) (*Trajectory, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+// Read 解析 Write 写出的示教文件，时间须单调不减
+func Read(r io.Reader) (*Trajectory, error) {
+	var trajectory Trajectory
+	if err := json.NewDecoder(r).Decode(&trajectory); err != nil {
+		if errors.Is(err, ErrFormat) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	for i := range trajectory.Points {
+		point := &trajectory.Points[i]
+		if point.Motion == 0 {
+			return nil, fmt.Errorf("%w: point %d: missing motion", ErrFormat, i)
+		}
+		if i > 0 && point.Time < trajectory.Points[i-1].Time {
+			return nil, fmt.Errorf("%w: point %d: time goes backwards", ErrFormat, i)
+		}
+	}
+	return &trajectory, nil
+}
+
+// Save 保存为示教文件
+func (trajectory *Trajectory) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := trajectory.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Write 以 JSON 写出
+func (trajectory *Trajectory) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(trajectory)
+}
+
+// Smoothed 对位置做窗口为 window 个点的滑动平均，首尾点保持不变，window 小于 2 时原样复制；
+// 旋转角先按相邻点展开为连续角度再平均，避免跨越 ±180° 时平均到 0° 附近
+func (trajectory *Trajectory) Smoothed(window int) *Trajectory {
+	points := append([]Point(nil), trajectory.Points...)
+	unwrapped := make([]float64, len(points))
+	for i, p := range points {
+		unwrapped[i] = float64(p.R)
+		if i > 0 {
+			delta := math.Remainder(float64(p.R)-float64(points[i-1].R), 360)
+			unwrapped[i] = unwrapped[i-1] + delta
+		}
+	}
+	half := window / 2
+	for i := 1; half > 0 && i < len(points)-1; i++ {
+		from, to := max(i-half, 0), min(i+half, len(points)-1)
+		var x, y, z float32
+		var r float64
+		for j, p := range trajectory.Points[from : to+1] {
+			x, y, z, r = x+p.X, y+p.Y, z+p.Z, r+unwrapped[from+j]
+		}
+		n := float32(to - from + 1)
+		points[i].X, points[i].Y, points[i].Z = x/n, y/n, z/n
+		points[i].R = float32(math.Remainder(r/float64(n), 360))
+	}
+	return &Trajectory{Effectors: trajectory.Effectors, Points: points}
+}
+
+// Playback 转换为 DobotStudio 示教再现程序，CP 点按 MOVL 导出，时间信息不保留
+func (trajectory *Trajectory) Playback() *studio.Playback {
+	playback := &studio.Playback{}
+	for _, point := range trajectory.Points {
+		playback.Rows = append(playback.Rows, studio.Row{
+			Motion:     point.Motion.ptpMode(),
+			X:          point.X,
+			Y:          point.Y,
+			Z:          point.Z,
+			R:          point.R,
+			SuctionCup: point.SuctionCup,
+			Gripper:    point.Gripper,
+			Laser:      point.Laser,
+		})
+	}
+	return playback
+}
+
+// Options 采集参数
+type Options struct {
+	Mode        godobot.HHTTrigMode // 按键释放或周期触发
+	Motion      Motion              // 记录的运动方式，为 0 时按键模式记为 MOVJ、周期模式记为 CP
+	Interval    time.Duration       // 查询触发状态的间隔，默认 20ms
+	Effectors   bool                // 同时记录吸盘、夹爪与激光状态
+	MinDistance float64             // 与上一点距离小于此值（毫米）且末端执行器状态未变时忽略，用于去除周期模式下静止时的重复点
+	OnPoint     func(Point)         // 每记录一点回调，如用于界面提示
+}
+
+// Record 设置 HHT 触发模式并采集示教点，直到 ctx 结束时返回已采集的轨迹；
+// 出错时返回出错前已采集的轨迹与错误。结束后关闭 HHT 触发输出
+func Record(ctx context.Context, dobot *godobot.Dobot, options Options) (*Trajectory, error) {
+	if options.Interval <= 0 {
+		options.Interval = 20 * time.Millisecond
+	}
+	if options.Motion == 0 {
+		options.Motion = MOVJ
+		if options.Mode == godobot.TriggeredOnPeriodicInterval {
+			options.Motion = CP
+		}
+	}
+	if _, ok := motionNames[options.Motion]; !ok {
+		return nil, fmt.Errorf("%w: motion %d", godobot.ErrInvalidParams, options.Motion)
+	}
+	if err := dobot.SetHHTTrigMode(ctx, options.Mode); err != nil {
+		return nil, err
+	}
+	if err := dobot.SetHHTTrigOutputEnabled(ctx, true); err != nil {
+		return nil, err
+	}
+	defer dobot.SetHHTTrigOutputEnabled(context.WithoutCancel(ctx), false)
+	trajectory := &Trajectory{Effectors: options.Effectors}
+	start := time.Now()
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return trajectory, nil
+		case <-ticker.C:
+		}
+		point, err := sample(ctx, dobot, options.Effectors)
+		if ctx.Err() != nil {
+			return trajectory, nil
+		}
+		if err != nil {
+			return trajectory, err
+		}
+		if point == nil {
+			continue
+		}
+		point.Time = time.Since(start)
+		point.Motion = options.Motion
+		if n := len(trajectory.Points); n > 0 && options.MinDistance > 0 {
+			last := &trajectory.Points[n-1]
+			if point.distance(last) < options.MinDistance && point.sameEffectors(last) {
+				continue
+			}
+		}
+		trajectory.Points = append(trajectory.Points, *point)
+		if options.OnPoint != nil {
+			options.OnPoint(*point)
+		}
+	}
+}
+
+// sample 查询触发状态，已触发时读取位姿与末端执行器状态，未触发时返回 nil
+func sample(ctx context.Context, dobot *godobot.Dobot, effectors bool) (*Point, error) {
+	triggered, err := dobot.GetHHTTrigOutput(ctx)
+	if err != nil || !triggered {
+		return nil, err
+	}
+	pose, err := dobot.GetPose(ctx)
+	if err != nil {
+		return nil, err
+	}
+	point := &Point{X: pose.X, Y: pose.Y, Z: pose.Z, R: pose.R}
+	if effectors {
+		if _, point.SuctionCup, err = dobot.GetEndEffectorSuctionCup(ctx); err != nil {
+			return nil, err
+		}
+		if _, point.Gripper, err = dobot.GetEndEffectorGripper(ctx); err != nil {
+			return nil, err
+		}
+		if _, point.Laser, err = dobot.GetEndEffectorLaser(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return point, nil
+}
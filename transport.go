@@ -0,0 +1,44 @@
+package godobot
+
+import (
+	"net"
+
+	"github.com/zdypro888/godobot/internal"
+)
+
+// Transport 传输层，任何可读写关闭的字节流都可以承载协议帧
+type Transport = internal.Transport
+
+// TransportOpener 根据地址打开传输层
+type TransportOpener = internal.TransportOpener
+
+// RegisterTransport 注册自定义 scheme 的传输层
+//
+// 内置 serial、udp、tcp、pipe、replay 五种：
+//
+//	serial:///dev/ttyUSB0?baud=115200
+//	udp://192.168.1.5:8899
+//	tcp://host:port?timeout=5s
+//	pipe://name
+//	replay:///path/to/session.rec?strict=1&speed=1&raw=1
+func RegisterTransport(scheme string, opener TransportOpener) {
+	internal.RegisterTransport(scheme, opener)
+}
+
+// OpenTransport 按地址打开传输层
+func OpenTransport(address string) (Transport, error) {
+	return internal.OpenTransport(address)
+}
+
+// ListenPipe 监听进程内管道，pipe://name 的连接由 Accept 返回
+func ListenPipe(name string) (net.Listener, error) {
+	return internal.ListenPipe(name)
+}
+
+// LinkStats 链路统计：发送、应答、重发、超时与无对应请求的应答数
+type LinkStats = internal.LinkStats
+
+// LinkStats 返回当前连接的链路统计
+func (dobot *Dobot) LinkStats() LinkStats {
+	return dobot.conn.Stats()
+}
@@ -50,15 +50,15 @@ type HOMECmd struct {
 
 // ParallelOutputCmd 并行输出命令结构
 type ParallelOutputCmd struct {
-	Ratio   uint8
-	Address uint16
-	Level   uint8
+	Ratio   uint8  `range:"0,100"`
+	Address uint16 `range:"1,20"`
+	Level   uint8  `range:"0,1"`
 }
 
 // AutoLevelingCmd 自动调平命令
 type AutoLevelingCmd struct {
-	ControlFlag uint8
-	Precision   float32
+	ControlFlag uint8   `range:"0,1"`
+	Precision   float32 `range:"0.02,0.5"`
 }
 
 // HHTTrigMode 手持示教触发模式
@@ -86,32 +86,32 @@ const (
 
 // JOGJointParams JOG关节参数
 type JOGJointParams struct {
-	Velocity     [4]float32
-	Acceleration [4]float32
+	Velocity     [4]float32 `range:"0,500"`
+	Acceleration [4]float32 `range:"0,1000"`
 }
 
 // JOGCoordinateParams JOG坐标参数
 type JOGCoordinateParams struct {
-	Velocity     [4]float32
-	Acceleration [4]float32
+	Velocity     [4]float32 `range:"0,500"`
+	Acceleration [4]float32 `range:"0,1000"`
 }
 
 // JOGLParams JOGL参数
 type JOGLParams struct {
-	Velocity     float32
-	Acceleration float32
+	Velocity     float32 `range:"0,500"`
+	Acceleration float32 `range:"0,1000"`
 }
 
 // JOGCommonParams JOG通用参数
 type JOGCommonParams struct {
-	VelocityRatio     float32
-	AccelerationRatio float32
+	VelocityRatio     float32 `range:"0,100"`
+	AccelerationRatio float32 `range:"0,100"`
 }
 
 // JOGCmd JOG命令
 type JOGCmd struct {
-	IsJoint uint8
-	Cmd     uint8
+	IsJoint uint8 `range:"0,1"`
+	Cmd     uint8 `range:"0,10"`
 }
 
 const (
@@ -130,41 +130,41 @@ const (
 
 // PTPJointParams PTP关节参数
 type PTPJointParams struct {
-	Velocity     [4]float32
-	Acceleration [4]float32
+	Velocity     [4]float32 `range:"0,500"`
+	Acceleration [4]float32 `range:"0,1000"`
 }
 
 // PTPCoordinateParams PTP坐标参数
 type PTPCoordinateParams struct {
-	XYZVelocity     float32
-	RVelocity       float32
-	XYZAcceleration float32
-	RAcceleration   float32
+	XYZVelocity     float32 `range:"0,500"`
+	RVelocity       float32 `range:"0,500"`
+	XYZAcceleration float32 `range:"0,1000"`
+	RAcceleration   float32 `range:"0,1000"`
 }
 
 // PTPLParams PTPL参数
 type PTPLParams struct {
-	Velocity     float32
-	Acceleration float32
+	Velocity     float32 `range:"0,500"`
+	Acceleration float32 `range:"0,1000"`
 }
 
 // PTPJumpParams PTP跳跃参数
 type PTPJumpParams struct {
-	JumpHeight float32
+	JumpHeight float32 `range:"0,200"`
 	ZLimit     float32
 }
 
 // PTPJump2Params PTP跳跃2参数
 type PTPJump2Params struct {
-	StartJumpHeight float32
-	EndJumpHeight   float32
+	StartJumpHeight float32 `range:"0,200"`
+	EndJumpHeight   float32 `range:"0,200"`
 	ZLimit          float32
 }
 
 // PTPCommonParams PTP通用参数
 type PTPCommonParams struct {
-	VelocityRatio     float32
-	AccelerationRatio float32
+	VelocityRatio     float32 `range:"0,100"`
+	AccelerationRatio float32 `range:"0,100"`
 }
 
 // PTPMode PTP运动模式
@@ -226,10 +226,10 @@ const (
 
 // CPParams CP参数
 type CPParams struct {
-	PlanAcc       float32
-	JuncitionVel  float32
-	AccOrPeriod   float32
-	RealTimeTrack uint8
+	PlanAcc       float32 `range:"0,1000"`
+	JuncitionVel  float32 `range:"0,500"`
+	AccOrPeriod   float32 `range:"0,1000"`
+	RealTimeTrack uint8   `range:"0,1"`
 }
 
 // CPMode CP模式
@@ -246,27 +246,27 @@ type CPCmd struct {
 	X        float32
 	Y        float32
 	Z        float32
-	Velocity float32
+	Velocity float32 `range:"0,"`
 }
 
 // CPCommonParams CP通用参数
 type CPCommonParams struct {
-	VelocityRatio     float32
-	AccelerationRatio float32
+	VelocityRatio     float32 `range:"0,100"`
+	AccelerationRatio float32 `range:"0,100"`
 }
 
 // ARCParams ARC参数
 type ARCParams struct {
-	XYZVelocity     float32
-	RVelocity       float32
-	XYZAcceleration float32
-	RAcceleration   float32
+	XYZVelocity     float32 `range:"0,500"`
+	RVelocity       float32 `range:"0,500"`
+	XYZAcceleration float32 `range:"0,1000"`
+	RAcceleration   float32 `range:"0,1000"`
 }
 
 // ARCCommonParams ARC通用参数
 type ARCCommonParams struct {
-	VelocityRatio     float32
-	AccelerationRatio float32
+	VelocityRatio     float32 `range:"0,100"`
+	AccelerationRatio float32 `range:"0,100"`
 }
 
 // ARCCmd ARC命令
@@ -299,7 +299,7 @@ type CircleCmd struct {
 		Z float32
 		R float32
 	}
-	Count uint32
+	Count uint32 `range:"1,"`
 }
 
 // WAITCmd 等待命令
@@ -335,8 +335,8 @@ const (
 
 // TRIGCmd 触发命令
 type TRIGCmd struct {
-	Address   uint8
-	Mode      uint8
+	Address   uint8 `range:"1,20"`
+	Mode      uint8 `range:"0,1"`
 	Condition uint8
 	Threshold float32
 }
@@ -354,21 +354,21 @@ const (
 
 // IOMultiplexing IO复用
 type IOMultiplexing struct {
-	Address   uint8
-	Multiplex uint8
+	Address   uint8 `range:"1,20"`
+	Multiplex uint8 `range:"0,4"`
 }
 
 // IODO IO数字输出
 type IODO struct {
-	Address uint8
-	Level   uint8
+	Address uint8 `range:"1,20"`
+	Level   uint8 `range:"0,1"`
 }
 
 // IOPWM IO PWM输出
 type IOPWM struct {
-	Address   uint8
-	Frequency float32
-	DutyCycle float32
+	Address   uint8   `range:"1,20"`
+	Frequency float32 `range:"10,1000000"`
+	DutyCycle float32 `range:"0,100"`
 }
 
 // IODI IO数字输入
@@ -385,14 +385,14 @@ type IOADC struct {
 
 // EMotor 扩展电机
 type EMotor struct {
-	Index     uint8
+	Index     uint8 `range:"0,1"`
 	IsEnabled bool
 	Speed     int32
 }
 
 // EMotorS 扩展步进电机
 type EMotorS struct {
-	Index     uint8
+	Index     uint8 `range:"0,1"`
 	IsEnabled bool
 	Speed     int32
 	Distance  uint32
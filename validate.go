@@ -0,0 +1,170 @@
+package godobot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidParams = errors.New("invalid params")
+
+// ParamError 参数不满足约束，可用 errors.Is(err, ErrInvalidParams) 判断
+type ParamError struct {
+	Param string // 出错的参数，如 PTPCommonParams.VelocityRatio
+	Value any    // 参数值
+	Rule  string // 违反的约束
+}
+
+func (err *ParamError) Error() string {
+	return fmt.Sprintf("invalid params: %s = %v, %s", err.Param, err.Value, err.Rule)
+}
+
+func (err *ParamError) Unwrap() error {
+	return ErrInvalidParams
+}
+
+// enum 枚举类型，Valid 判断取值是否已定义
+type enum interface {
+	Valid() bool
+}
+
+// crossChecker 需要跨字段检查的参数结构
+type crossChecker interface {
+	check() error
+}
+
+func (mode PTPMode) Valid() bool                   { return mode <= PTPJUMPMOVLXYZMode }
+func (mode CPMode) Valid() bool                    { return mode <= CPAbsoluteMode }
+func (mode TRIGMode) Valid() bool                  { return mode <= TRIGADCMode }
+func (mode HHTTrigMode) Valid() bool               { return mode <= TriggeredOnPeriodicInterval }
+func (orientation ArmOrientation) Valid() bool     { return orientation <= RightyArmOrientation }
+func (function IOFunction) Valid() bool            { return function <= IOFunctionADC }
+func (port ColorPort) Valid() bool                 { return port <= CL_PORT_GP5 }
+func (port InfraredPort) Valid() bool              { return port <= IF_PORT_GP5 }
+func (condition TRIGInputIOCondition) Valid() bool { return condition <= TRIGInputIONotEqual }
+func (condition TRIGADCCondition) Valid() bool     { return condition <= TRIGADCGT }
+
+// check 触发条件取值随触发模式不同
+func (cmd *TRIGCmd) check() error {
+	valid := TRIGADCCondition(cmd.Condition).Valid()
+	if TRIGMode(cmd.Mode) == TRIGInputIOMode {
+		valid = TRIGInputIOCondition(cmd.Condition).Valid()
+	}
+	if !valid {
+		return &ParamError{Param: "TRIGCmd.Condition", Value: cmd.Condition, Rule: fmt.Sprintf("not defined for mode %d", cmd.Mode)}
+	}
+	return nil
+}
+
+// SetParamValidation 开启或关闭参数检查，默认开启；实验固件的参数范围不同时关闭
+func (dobot *Dobot) SetParamValidation(enabled bool) {
+	dobot.unchecked = !enabled
+}
+
+// validate 按字段的 range 标签与枚举类型检查参数
+func (dobot *Dobot) validate(params any) error {
+	if dobot.unchecked {
+		return nil
+	}
+	value := reflect.ValueOf(params)
+	for value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	name := value.Type().Name()
+	if kind := value.Kind(); kind == reflect.Slice || kind == reflect.Array {
+		name = value.Type().Elem().Name()
+	}
+	if err := validateValue(name, value, ""); err != nil {
+		return err
+	}
+	if checker, ok := params.(crossChecker); ok {
+		return checker.check()
+	}
+	return nil
+}
+
+// validateValue 检查一个值，rule 为所属字段的 range 标签
+func validateValue(name string, value reflect.Value, rule string) error {
+	if value.CanInterface() {
+		if e, ok := value.Interface().(enum); ok && !e.Valid() {
+			return &ParamError{Param: name, Value: value.Interface(), Rule: "not a defined " + value.Type().Name()}
+		}
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if err := validateValue(name+"."+field.Name, value.Field(i), field.Tag.Get("range")); err != nil {
+				return err
+			}
+		}
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			if err := validateValue(fmt.Sprintf("%s[%d]", name, i), value.Index(i), rule); err != nil {
+				return err
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return validateRange(name, float64(value.Int()), value.Interface(), rule)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return validateRange(name, float64(value.Uint()), value.Interface(), rule)
+	case reflect.Float32, reflect.Float64:
+		return validateRange(name, value.Float(), value.Interface(), rule)
+	}
+	return nil
+}
+
+// validateArg 检查非结构体参数，rule 与 range 标签的写法相同
+func (dobot *Dobot) validateArg(name string, value float32, rule string) error {
+	if dobot.unchecked {
+		return nil
+	}
+	return validateRange(name, float64(value), value, rule)
+}
+
+// parseRange 解析 range 标签 "min,max"，省略的一端为 ±Inf
+func parseRange(rule string) (low, high float64, err error) {
+	lowText, highText, ok := strings.Cut(rule, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("godobot: malformed range tag %q", rule)
+	}
+	low, high = math.Inf(-1), math.Inf(1)
+	if lowText != "" {
+		if low, err = strconv.ParseFloat(lowText, 64); err != nil {
+			return 0, 0, fmt.Errorf("godobot: malformed range tag %q", rule)
+		}
+	}
+	if highText != "" {
+		if high, err = strconv.ParseFloat(highText, 64); err != nil {
+			return 0, 0, fmt.Errorf("godobot: malformed range tag %q", rule)
+		}
+	}
+	return low, high, nil
+}
+
+// validateRange 检查 range:"min,max" 约束，省略的一端不限；标签本身有误时返回错误而不发送
+func validateRange(name string, number float64, value any, rule string) error {
+	if rule == "" {
+		return nil
+	}
+	low, high, err := parseRange(rule)
+	if err != nil {
+		return fmt.Errorf("%w on %s", err, name)
+	}
+	if number >= low && number <= high { // NaN 不满足任何比较
+		return nil
+	}
+	lowText, highText, _ := strings.Cut(rule, ",")
+	switch {
+	case lowText == "":
+		rule = "want <= " + highText
+	case highText == "":
+		rule = "want >= " + lowText
+	default:
+		rule = fmt.Sprintf("out of range [%s, %s]", lowText, highText)
+	}
+	return &ParamError{Param: name, Value: value, Rule: rule}
+}
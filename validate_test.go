@@ -0,0 +1,111 @@
+package godobot
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestRangeTags 包内所有 range 标签都能解析，且下限不大于上限
+func TestRangeTags(t *testing.T) {
+	files := token.NewFileSet()
+	source := func(info fs.FileInfo) bool { return !strings.HasSuffix(info.Name(), "_test.go") }
+	packages, err := parser.ParseDir(files, ".", source, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := 0
+	for _, pkg := range packages {
+		ast.Inspect(pkg, func(node ast.Node) bool {
+			field, ok := node.(*ast.Field)
+			if !ok || field.Tag == nil {
+				return true
+			}
+			text, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				t.Fatalf("%v: %v", files.Position(field.Pos()), err)
+			}
+			rule, ok := reflect.StructTag(text).Lookup("range")
+			if !ok {
+				return true
+			}
+			tags++
+			low, high, err := parseRange(rule)
+			if err != nil || low > high {
+				t.Errorf("%v: range tag %q: %v", files.Position(field.Pos()), rule, err)
+			}
+			return true
+		})
+	}
+	if tags == 0 {
+		t.Fatal("no range tags found")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	nan := float32(math.NaN())
+	tests := []struct {
+		name   string
+		params any
+		param  string // 出错的参数，空表示通过
+	}{
+		{"valid", &PTPCommonParams{VelocityRatio: 50, AccelerationRatio: 100}, ""},
+		{"above max", &PTPCommonParams{VelocityRatio: 101}, "PTPCommonParams.VelocityRatio"},
+		{"below min", &CPCmd{Velocity: -1}, "CPCmd.Velocity"},
+		{"NaN", &CPCmd{Velocity: nan}, "CPCmd.Velocity"},
+		{"open upper bound", &CPCmd{Velocity: 1e6}, ""},
+		{"array element", &PTPJointParams{Velocity: [4]float32{0, 0, 501, 0}}, "PTPJointParams.Velocity[2]"},
+		{"undefined enum", &PTPCmd{PTPMode: PTPJUMPMOVLXYZMode + 1}, "PTPCmd.PTPMode"},
+		{"cross field", &TRIGCmd{Address: 1, Mode: uint8(TRIGInputIOMode), Condition: uint8(TRIGInputIONotEqual) + 1}, "TRIGCmd.Condition"},
+	}
+	dobot := NewDobot()
+	for _, test := range tests {
+		err := dobot.validate(test.params)
+		var paramErr *ParamError
+		switch {
+		case test.param == "" && err != nil:
+			t.Errorf("%s: validate = %v, want nil", test.name, err)
+		case test.param != "" && (!errors.As(err, &paramErr) || paramErr.Param != test.param || !errors.Is(err, ErrInvalidParams)):
+			t.Errorf("%s: validate = %v, want ParamError on %s", test.name, err, test.param)
+		}
+	}
+	dobot.SetParamValidation(false)
+	if err := dobot.validate(&CPCmd{Velocity: -1}); err != nil {
+		t.Errorf("validation disabled: %v", err)
+	}
+}
+
+// TestValidateMalformedTag 标签有误时返回错误而不是 panic
+func TestValidateMalformedTag(t *testing.T) {
+	for _, rule := range []string{"100", "a,1", "0,b"} {
+		if err := validateRange("Params.Field", 1, 1, rule); err == nil {
+			t.Errorf("rule %q accepted", rule)
+		}
+	}
+	var params struct {
+		Value float32 `range:"0-100"`
+	}
+	if err := NewDobot().validate(&params); err == nil {
+		t.Error("malformed tag accepted")
+	}
+}
+
+// TestSetCPLECmdPower 激光功率为百分比，超出 0..100 时不发送
+func TestSetCPLECmdPower(t *testing.T) {
+	dobot := NewDobot()
+	for _, power := range []float32{-1, 100.5, float32(math.NaN())} {
+		_, err := dobot.SetCPLECmd(context.Background(), uint8(CPAbsoluteMode), 200, 0, 0, power, true)
+		var paramErr *ParamError
+		if !errors.As(err, &paramErr) || paramErr.Param != "CPLECmd.Power" {
+			t.Errorf("power %g: error %v, want ParamError on CPLECmd.Power", power, err)
+		}
+	}
+}
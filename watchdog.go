@@ -0,0 +1,30 @@
+package godobot
+
+import "github.com/zdypro888/godobot/internal"
+
+// WatchdogPolicy 链路看门狗策略：队列执行期间调用方停止发送指令或链路劣化超过阈值时，
+// 停止设备队列执行并进入故障状态，拒绝运动指令直到 Rearm。调用方阻塞在 QueuedCommand.Wait 中时视为仍在工作
+type WatchdogPolicy = internal.WatchdogPolicy
+
+// SetWatchdog 设置链路看门狗，nil 关闭；可在连接前调用
+func (dobot *Dobot) SetWatchdog(policy *WatchdogPolicy) {
+	dobot.watchdog = policy
+	if dobot.conn != nil {
+		dobot.conn.SetWatchdog(policy)
+	}
+}
+
+// Feed 表明调用方仍在工作，长时间既不发指令也不在 Wait 中等待时定期调用以免触发看门狗
+func (dobot *Dobot) Feed() {
+	dobot.conn.Feed()
+}
+
+// Fault 看门狗触发的故障，未触发时返回 nil
+func (dobot *Dobot) Fault() *FaultError {
+	return dobot.conn.Fault()
+}
+
+// Rearm 清除看门狗故障，恢复接受运动指令，队列需重新 SetQueuedCmdStartExec 后执行
+func (dobot *Dobot) Rearm() {
+	dobot.conn.Rearm()
+}
@@ -0,0 +1,615 @@
+package godobot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/zdypro888/godobot/kinematics"
+	"github.com/zdypro888/godobot/protocol"
+)
+
+// 工作空间检查默认参数
+const (
+	DefaultWorkspaceStep = 2.0 // 路径插值步长（毫米）
+	jointStep            = 1.0 // 关节插补的插值步长（度）
+	jogProbeAngle        = 2.0 // 点动时试探的关节角度（度）
+	jogGuardInterval     = 20 * time.Millisecond
+	jogGuardLatency      = 100 * time.Millisecond // 发现越界到点动停止的最坏延迟：轮询间隔、往返与减速
+)
+
+var (
+	ErrOutOfWorkspace = errors.New("outside workspace")
+	ErrKeepOut        = errors.New("inside keep-out zone")
+	ErrDegenerateArc  = errors.New("arc points are collinear")
+)
+
+// Range 取值范围
+type Range struct {
+	Min float64
+	Max float64
+}
+
+func (r *Range) contains(value float64) bool {
+	return value >= r.Min && value <= r.Max
+}
+
+// Box 长方体禁入区（毫米），如夹具、文件托盘
+type Box struct {
+	Name string
+	Min  [3]float64
+	Max  [3]float64
+}
+
+func (box *Box) contains(position kinematics.Position) bool {
+	point := [3]float64{position.X, position.Y, position.Z}
+	for i := range point {
+		if point[i] < box.Min[i] || point[i] > box.Max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Workspace 软件工作空间：运动指令发送前沿插补路径检查关节限位、可达范围与禁入区
+type Workspace struct {
+	Model   *kinematics.Model // 关节限位、末端偏移与滑轨，nil 时按设备当前参数构造
+	Z       *Range            // 末端高度范围，Min 即桌面以上的最低高度，nil 不检查
+	Radius  *Range            // 末端距底座轴线的水平距离范围（圆柱形工作空间），nil 不检查
+	KeepOut []Box             // 禁入区
+	Step    float64           // 路径插值步长（毫米），0 使用 DefaultWorkspaceStep
+}
+
+// WorkspaceError 运动指令被工作空间拒绝，Err 为 ErrOutOfWorkspace、ErrKeepOut、
+// kinematics.ErrUnreachable、ErrDegenerateArc 或 *kinematics.LimitError
+type WorkspaceError struct {
+	Id    protocol.ProtocolId // 被拒绝的指令
+	Point kinematics.Position // 路径上第一个违规点
+	Zone  string              // 进入的禁入区名称
+	Err   error
+}
+
+func (err *WorkspaceError) Error() string {
+	message := fmt.Sprintf("workspace: protocol %d rejected at (%.2f, %.2f, %.2f): %v", err.Id, err.Point.X, err.Point.Y, err.Point.Z, err.Err)
+	if err.Zone != "" {
+		message += " " + err.Zone
+	}
+	return message
+}
+
+func (err *WorkspaceError) Unwrap() error {
+	return err.Err
+}
+
+// workspace 工作空间检查状态
+type workspace struct {
+	mutex   sync.Mutex
+	config  Workspace
+	model   *kinematics.Model  // 按设备参数构造的模型缓存
+	planned *kinematics.Joints // 已入队运动指令的终点，nil 表示以设备当前位姿为起点
+	jogStop context.CancelFunc // 取消正在进行的点动守护
+}
+
+// forget 设备位姿或运动学参数已改变，下次检查时重新读取
+func (space *workspace) forget() {
+	if space == nil {
+		return
+	}
+	space.mutex.Lock()
+	space.model = nil
+	space.planned = nil
+	space.mutex.Unlock()
+}
+
+// amendModel 运动学参数改变后更新检查用的模型。立即执行时重新读取设备；入队时参数在之前的队列运动之后才生效，
+// 之后入队的运动按修改后的模型检查，已入队运动的终点保留
+func (dobot *Dobot) amendModel(ctx context.Context, isQueued bool, change func(model *kinematics.Model)) {
+	space := dobot.workspace.Load()
+	if space == nil {
+		return
+	}
+	if !isQueued {
+		space.forget()
+		return
+	}
+	space.mutex.Lock()
+	defer space.mutex.Unlock()
+	if space.config.Model != nil {
+		return
+	}
+	model := space.model
+	if model == nil {
+		var err error
+		if model, err = dobot.KinematicsModel(ctx); err != nil {
+			space.planned = nil
+			return
+		}
+	}
+	amended := *model
+	change(&amended)
+	space.model = &amended
+}
+
+// stopJOG 取消点动守护，返回后守护不会再发送停止指令
+func (space *workspace) stopJOG() {
+	if space == nil {
+		return
+	}
+	space.mutex.Lock()
+	if space.jogStop != nil {
+		space.jogStop()
+		space.jogStop = nil
+	}
+	space.mutex.Unlock()
+}
+
+// SetWorkspace 设置软件工作空间，nil 关闭检查，可在指令发送期间调用。启用后队列运动指令按入队顺序检查，
+// 同一 Dobot 上的运动指令串行发送；点动期间持续检查前方路径，将要越界时自动停止点动
+func (dobot *Dobot) SetWorkspace(config *Workspace) {
+	var space *workspace
+	if config != nil {
+		space = &workspace{config: *config}
+	}
+	dobot.workspace.Swap(space).stopJOG()
+}
+
+// planner 沿运动路径逐点检查
+type planner struct {
+	space    *workspace
+	model    *kinematics.Model
+	id       protocol.ProtocolId
+	step     float64
+	position kinematics.Position
+	joints   kinematics.Joints
+}
+
+// check 检查路径上的一点
+func (plan *planner) check(position kinematics.Position, joints kinematics.Joints) error {
+	violation := func(err error, zone string) error {
+		return &WorkspaceError{Id: plan.id, Point: position, Zone: zone, Err: err}
+	}
+	if err := plan.model.Check(joints); err != nil {
+		return violation(err, "")
+	}
+	config := &plan.space.config
+	if config.Z != nil && !config.Z.contains(position.Z) {
+		return violation(ErrOutOfWorkspace, "")
+	}
+	if config.Radius != nil && !config.Radius.contains(math.Hypot(position.X, position.Y)) {
+		return violation(ErrOutOfWorkspace, "")
+	}
+	for i := range config.KeepOut {
+		if config.KeepOut[i].contains(position) {
+			return violation(ErrKeepOut, config.KeepOut[i].Name)
+		}
+	}
+	return nil
+}
+
+func lerp(from, to, ratio float64) float64 {
+	return from + (to-from)*ratio
+}
+
+func distance(from, to kinematics.Position) float64 {
+	return math.Sqrt((to.X-from.X)*(to.X-from.X) + (to.Y-from.Y)*(to.Y-from.Y) + (to.Z-from.Z)*(to.Z-from.Z))
+}
+
+// moveL 笛卡尔直线插补
+func (plan *planner) moveL(to kinematics.Position) error {
+	from := plan.position
+	count := max(int(math.Ceil(distance(from, to)/plan.step)), 1)
+	for i := 1; i <= count; i++ {
+		ratio := float64(i) / float64(count)
+		point := kinematics.Position{
+			X: lerp(from.X, to.X, ratio),
+			Y: lerp(from.Y, to.Y, ratio),
+			Z: lerp(from.Z, to.Z, ratio),
+			R: lerp(from.R, to.R, ratio),
+			L: lerp(from.L, to.L, ratio),
+		}
+		joints, err := plan.model.Inverse(point)
+		if err != nil {
+			return &WorkspaceError{Id: plan.id, Point: point, Err: err}
+		}
+		if err = plan.check(point, joints); err != nil {
+			return err
+		}
+		plan.joints = joints
+	}
+	plan.position = to
+	return nil
+}
+
+// moveJ 关节插补
+func (plan *planner) moveJ(to kinematics.Joints) error {
+	from := plan.joints
+	end := plan.model.Forward(to)
+	steps := distance(plan.position, end) / plan.step
+	for axis := range to.Angle {
+		steps = max(steps, math.Abs(to.Angle[axis]-from.Angle[axis])/jointStep)
+	}
+	count := max(int(math.Ceil(steps)), 1)
+	for i := 1; i <= count; i++ {
+		ratio := float64(i) / float64(count)
+		var joints kinematics.Joints
+		for axis := range joints.Angle {
+			joints.Angle[axis] = lerp(from.Angle[axis], to.Angle[axis], ratio)
+		}
+		joints.L = lerp(from.L, to.L, ratio)
+		if err := plan.check(plan.model.Forward(joints), joints); err != nil {
+			return err
+		}
+	}
+	plan.joints = to
+	plan.position = end
+	return nil
+}
+
+// moveJTo 关节插补到笛卡尔目标
+func (plan *planner) moveJTo(to kinematics.Position) error {
+	joints, err := plan.model.Inverse(to)
+	if err != nil {
+		return &WorkspaceError{Id: plan.id, Point: to, Err: err}
+	}
+	return plan.moveJ(joints)
+}
+
+// jump 门型运动：抬升到 top 高度，水平移动，再下降到目标
+func (plan *planner) jump(to kinematics.Position, params *PTPJumpParams, linear bool) error {
+	top := max(plan.position.Z, to.Z) + float64(params.JumpHeight)
+	if params.ZLimit > 0 {
+		top = max(min(top, float64(params.ZLimit)), plan.position.Z, to.Z)
+	}
+	up := plan.position
+	up.Z = top
+	if err := plan.moveL(up); err != nil {
+		return err
+	}
+	over := to
+	over.Z = top
+	if linear {
+		if err := plan.moveL(over); err != nil {
+			return err
+		}
+	} else if err := plan.moveJTo(over); err != nil {
+		return err
+	}
+	return plan.moveL(to)
+}
+
+// arc 经过 via 到 to 的圆弧，full 时走完整圆周
+func (plan *planner) arc(via, to kinematics.Position, full bool) error {
+	start := [3]float64{plan.position.X, plan.position.Y, plan.position.Z}
+	a := sub3([3]float64{via.X, via.Y, via.Z}, start)
+	b := sub3([3]float64{to.X, to.Y, to.Z}, start)
+	normal := cross3(a, b)
+	normalSquare := dot3(normal, normal)
+	if normalSquare < 1e-9 {
+		return &WorkspaceError{Id: plan.id, Point: to, Err: ErrDegenerateArc}
+	}
+	// 三点外接圆圆心
+	var offset [3]float64
+	left, right := cross3(b, normal), cross3(normal, a)
+	for i := range offset {
+		offset[i] = (dot3(a, a)*left[i] + dot3(b, b)*right[i]) / (2 * normalSquare)
+	}
+	center := add3(start, offset)
+	radius := math.Sqrt(dot3(offset, offset))
+	u := scale3(sub3(start, center), 1/radius)
+	v := scale3(cross3(normal, u), 1/math.Sqrt(normalSquare))
+	sweep := 2 * math.Pi
+	if !full {
+		end := sub3([3]float64{to.X, to.Y, to.Z}, center)
+		sweep = math.Atan2(dot3(end, v), dot3(end, u))
+		if sweep <= 0 {
+			sweep += 2 * math.Pi
+		}
+	}
+	from := plan.position
+	count := max(int(math.Ceil(radius*sweep/plan.step)), 1)
+	for i := 1; i <= count; i++ {
+		ratio := float64(i) / float64(count)
+		angle := sweep * ratio
+		p := add3(center, add3(scale3(u, radius*math.Cos(angle)), scale3(v, radius*math.Sin(angle))))
+		point := kinematics.Position{X: p[0], Y: p[1], Z: p[2], R: lerp(from.R, to.R, ratio), L: from.L}
+		joints, err := plan.model.Inverse(point)
+		if err != nil {
+			return &WorkspaceError{Id: plan.id, Point: point, Err: err}
+		}
+		if err = plan.check(point, joints); err != nil {
+			return err
+		}
+		plan.joints = joints
+		plan.position = point
+	}
+	return nil
+}
+
+func add3(a, b [3]float64) [3]float64 { return [3]float64{a[0] + b[0], a[1] + b[1], a[2] + b[2]} }
+func sub3(a, b [3]float64) [3]float64 { return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]} }
+func dot3(a, b [3]float64) float64    { return a[0]*b[0] + a[1]*b[1] + a[2]*b[2] }
+func scale3(a [3]float64, k float64) [3]float64 {
+	return [3]float64{a[0] * k, a[1] * k, a[2] * k}
+}
+func cross3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+// sendMotion 按工作空间检查运动路径后发送，未设置工作空间时直接发送
+func (dobot *Dobot) sendMotion(ctx context.Context, message *protocol.Message, route func(ctx context.Context, plan *planner) error) (*protocol.Message, error) {
+	space := dobot.workspace.Load()
+	if space == nil {
+		return dobot.conn.SendMessage(ctx, message)
+	}
+	space.mutex.Lock()
+	defer space.mutex.Unlock()
+	plan, err := dobot.planFrom(ctx, space, message)
+	if err != nil {
+		return nil, err
+	}
+	if err = route(ctx, plan); err != nil {
+		return nil, err
+	}
+	resp, err := dobot.conn.SendMessage(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	if message.IsQueued {
+		space.planned = &plan.joints
+	} else {
+		// 立即执行的运动改变了设备位姿，之后的队列指令从设备当前位姿开始
+		space.planned = nil
+	}
+	return resp, nil
+}
+
+// planFrom 以已入队运动的终点或设备当前位姿为起点
+func (dobot *Dobot) planFrom(ctx context.Context, space *workspace, message *protocol.Message) (*planner, error) {
+	model := space.config.Model
+	if model == nil {
+		if space.model == nil {
+			var err error
+			if space.model, err = dobot.KinematicsModel(ctx); err != nil {
+				return nil, err
+			}
+		}
+		model = space.model
+	}
+	step := space.config.Step
+	if step <= 0 {
+		step = DefaultWorkspaceStep
+	}
+	plan := &planner{space: space, model: model, id: message.Id, step: step}
+	if message.IsQueued && space.planned != nil {
+		plan.joints = *space.planned
+	} else {
+		pose, err := dobot.GetPose(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var l float32
+		if model.Rail {
+			if l, err = dobot.GetPoseL(ctx); err != nil {
+				return nil, err
+			}
+		}
+		plan.joints = pose.Joints(l)
+	}
+	plan.position = model.Forward(plan.joints)
+	return plan, nil
+}
+
+// routePTP PTP 各运动模式的路径
+func (dobot *Dobot) routePTP(mode PTPMode, x, y, z, r float32, l *float32) func(ctx context.Context, plan *planner) error {
+	return func(ctx context.Context, plan *planner) error {
+		target := kinematics.Position{X: float64(x), Y: float64(y), Z: float64(z), R: float64(r), L: plan.position.L}
+		if l != nil {
+			target.L = float64(*l)
+		}
+		angles := kinematics.Joints{Angle: [4]float64{float64(x), float64(y), float64(z), float64(r)}, L: target.L}
+		switch mode {
+		case PTPJUMPXYZMode, PTPJUMPANGLEMode, PTPJUMPMOVLXYZMode:
+			params, err := dobot.GetPTPJumpParams(ctx)
+			if err != nil {
+				return err
+			}
+			if mode == PTPJUMPANGLEMode {
+				target = plan.model.Forward(angles)
+			}
+			return plan.jump(target, params, mode == PTPJUMPMOVLXYZMode)
+		case PTPMOVJXYZMode:
+			return plan.moveJTo(target)
+		case PTPMOVLXYZMode:
+			return plan.moveL(target)
+		case PTPMOVJANGLEMode:
+			return plan.moveJ(angles)
+		case PTPMOVLANGLEMode:
+			return plan.moveL(plan.model.Forward(angles))
+		case PTPMOVJANGLEINCMode:
+			for axis := range angles.Angle {
+				angles.Angle[axis] += plan.joints.Angle[axis]
+			}
+			return plan.moveJ(angles)
+		case PTPMOVLXYZINCMode, PTPMOVJXYZINCMode:
+			target = plan.position
+			target.X += float64(x)
+			target.Y += float64(y)
+			target.Z += float64(z)
+			target.R += float64(r)
+			if l != nil {
+				target.L = float64(*l)
+			}
+			if mode == PTPMOVLXYZINCMode {
+				return plan.moveL(target)
+			}
+			return plan.moveJTo(target)
+		}
+		return &WorkspaceError{Id: plan.id, Point: target, Err: fmt.Errorf("unknown ptp mode %d", mode)}
+	}
+}
+
+// routeCP 连续轨迹的直线路径，relative 时坐标为增量
+func routeCP(relative bool, x, y, z float32) func(ctx context.Context, plan *planner) error {
+	return func(ctx context.Context, plan *planner) error {
+		target := plan.position
+		if relative {
+			target.X += float64(x)
+			target.Y += float64(y)
+			target.Z += float64(z)
+		} else {
+			target.X, target.Y, target.Z = float64(x), float64(y), float64(z)
+		}
+		return plan.moveL(target)
+	}
+}
+
+// routeArc 经过 via 到 to 的圆弧路径，full 时为整圆
+func routeArc(via, to [4]float32, full bool) func(ctx context.Context, plan *planner) error {
+	return func(ctx context.Context, plan *planner) error {
+		position := func(point [4]float32) kinematics.Position {
+			return kinematics.Position{X: float64(point[0]), Y: float64(point[1]), Z: float64(point[2]), R: float64(point[3]), L: plan.position.L}
+		}
+		return plan.arc(position(via), position(to), full)
+	}
+}
+
+// arcPoint ARCCmd 与 CircleCmd 中的点
+func arcPoint(point struct{ X, Y, Z, R float32 }) [4]float32 {
+	return [4]float32{point.X, point.Y, point.Z, point.R}
+}
+
+// routeHome 回零经过限位开关，路径无法检查，只以回零点作为之后队列运动的起点
+func (dobot *Dobot) routeHome(ctx context.Context, plan *planner) error {
+	params, err := dobot.GetHOMEParams(ctx)
+	if err != nil {
+		return err
+	}
+	home := kinematics.Position{X: float64(params.X), Y: float64(params.Y), Z: float64(params.Z), R: float64(params.R), L: plan.position.L}
+	joints, err := plan.model.Inverse(home)
+	if err != nil {
+		return &WorkspaceError{Id: plan.id, Point: home, Err: err}
+	}
+	plan.joints, plan.position = joints, home
+	return nil
+}
+
+// routeJOG 点动是持续运动，检查按下方向上 distance 毫米或 angle 度以内的路径，
+// 已在边界附近时拒绝继续向外点动；之后由 guardJOG 在点动期间持续检查
+func routeJOG(cmd *JOGCmd, distance, angle float64) func(ctx context.Context, plan *planner) error {
+	return func(ctx context.Context, plan *planner) error {
+		if cmd.Cmd == JogIdle {
+			return nil
+		}
+		axis := int(cmd.Cmd-1) / 2
+		sign := 1.0
+		if (cmd.Cmd-1)%2 == 1 {
+			sign = -1
+		}
+		if cmd.IsJoint != 0 {
+			joints := plan.joints
+			if axis < len(joints.Angle) {
+				joints.Angle[axis] += sign * angle
+			} else {
+				joints.L += sign * distance
+			}
+			return plan.moveJ(joints)
+		}
+		target := plan.position
+		switch axis {
+		case 0:
+			target.X += sign * distance
+		case 1:
+			target.Y += sign * distance
+		case 2:
+			target.Z += sign * distance
+		case 3:
+			target.R += sign * angle
+		default:
+			target.L += sign * distance
+		}
+		return plan.moveL(target)
+	}
+}
+
+// jogReach 点动在 jogGuardLatency 内移动的距离（毫米）与角度（度），不小于插值步长与 jogProbeAngle
+func (dobot *Dobot) jogReach(ctx context.Context, cmd *JOGCmd) (distance, angle float64, err error) {
+	common, err := dobot.GetJOGCommonParams(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	scale := float64(common.VelocityRatio) / 100 * jogGuardLatency.Seconds()
+	axis := int(cmd.Cmd-1) / 2
+	switch {
+	case axis == 4:
+		params, err := dobot.GetJOGLParams(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		distance = float64(params.Velocity) * scale
+	case cmd.IsJoint != 0:
+		params, err := dobot.GetJOGJointParams(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		angle = float64(params.Velocity[axis]) * scale
+	default:
+		params, err := dobot.GetJOGCoordinateParams(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		if axis == 3 {
+			angle = float64(params.Velocity[axis]) * scale
+		} else {
+			distance = float64(params.Velocity[axis]) * scale
+		}
+	}
+	return max(distance, DefaultWorkspaceStep), max(angle, jogProbeAngle), nil
+}
+
+// guardJOG 点动期间轮询位姿，前方 distance、angle 以内将要越界时发送 JogIdle 停止点动
+func (dobot *Dobot) guardJOG(space *workspace, cmd JOGCmd, distance, angle float64) {
+	ctx, cancel := context.WithCancel(context.Background())
+	space.mutex.Lock()
+	space.jogStop = cancel
+	space.mutex.Unlock()
+	conn := dobot.conn
+	go func() {
+		defer cancel()
+		ticker := time.NewTicker(jogGuardInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-conn.Done():
+				return
+			case <-ticker.C:
+			}
+			if !dobot.checkJOG(ctx, space, &cmd, distance, angle) {
+				return
+			}
+		}
+	}()
+}
+
+// checkJOG 检查一次点动前方的路径，将要越界或无法读取位姿时停止点动，返回是否继续守护
+func (dobot *Dobot) checkJOG(ctx context.Context, space *workspace, cmd *JOGCmd, distance, angle float64) bool {
+	space.mutex.Lock()
+	defer space.mutex.Unlock()
+	if ctx.Err() != nil {
+		return false
+	}
+	plan, err := dobot.planFrom(ctx, space, &protocol.Message{Id: protocol.ProtocolJOGCmd, RW: true})
+	if err == nil {
+		if err = routeJOG(cmd, distance, angle)(ctx, plan); err == nil {
+			return true
+		}
+	}
+	idle := &protocol.Message{Id: protocol.ProtocolJOGCmd, RW: true, Params: []byte{cmd.IsJoint, JogIdle}}
+	dobot.conn.SendMessage(context.Background(), idle)
+	space.planned = nil
+	return false
+}
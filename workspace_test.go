@@ -0,0 +1,96 @@
+package godobot_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zdypro888/godobot"
+	"github.com/zdypro888/godobot/simulator"
+)
+
+// connectSimulator 连接一个新的模拟器，测试结束时关闭
+func connectSimulator(t *testing.T, name string, scale float64) (*simulator.Simulator, *godobot.Dobot) {
+	t.Helper()
+	sim := simulator.New()
+	sim.SetTimeScale(scale)
+	if _, err := sim.Listen(name); err != nil {
+		t.Fatal(err)
+	}
+	dobot := godobot.NewDobot()
+	if err := dobot.Connect("pipe://" + name); err != nil {
+		sim.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		dobot.Close()
+		sim.Close()
+	})
+	return sim, dobot
+}
+
+// TestJOGWorkspace 点动不检查终点，守护应在越过 Z 下限前停止点动，并拒绝继续向外点动
+func TestJOGWorkspace(t *testing.T) {
+	sim, dobot := connectSimulator(t, "jog-workspace", 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	pose, err := dobot.GetPose(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	floor := float64(pose.Z) - 10
+	dobot.SetWorkspace(&godobot.Workspace{Z: &godobot.Range{Min: floor, Max: 300}})
+	down := &godobot.JOGCmd{Cmd: godobot.JogCNPressed}
+	if _, err := dobot.SetJOGCmd(ctx, down, false); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1500 * time.Millisecond) // 默认 30mm/s，不加守护时会越过下限约 35mm
+	stopped := sim.Pose()
+	if float64(stopped.Z) < floor {
+		t.Fatalf("jog stopped at Z %.2f, below workspace floor %.2f", stopped.Z, floor)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if pose := sim.Pose(); pose.Z != stopped.Z {
+		t.Errorf("jog still moving: Z %.2f -> %.2f", stopped.Z, pose.Z)
+	}
+	if _, err := dobot.SetJOGCmd(ctx, down, false); !errors.Is(err, godobot.ErrOutOfWorkspace) {
+		t.Errorf("jog towards floor: error %v, want ErrOutOfWorkspace", err)
+	}
+	if _, err := dobot.SetJOGCmd(ctx, &godobot.JOGCmd{Cmd: godobot.JogCPPressed}, false); err != nil {
+		t.Errorf("jog away from floor: %v", err)
+	}
+	if _, err := dobot.SetJOGCmd(ctx, &godobot.JOGCmd{Cmd: godobot.JogIdle}, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestQueuedModelChange 入队的末端参数与构型指令在之前的运动之后才生效，不应丢弃已入队运动的终点
+func TestQueuedModelChange(t *testing.T) {
+	_, dobot := connectSimulator(t, "queued-model-change", 100)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := dobot.SetQueuedCmdStopExec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	pose, err := dobot.GetPose(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dobot.SetWorkspace(&godobot.Workspace{Z: &godobot.Range{Min: float64(pose.Z) - 10, Max: 300}})
+	lower := &godobot.PTPCmd{PTPMode: godobot.PTPMOVLXYZMode, X: pose.X, Y: pose.Y, Z: pose.Z - 8, R: pose.R}
+	if _, err := dobot.SetPTPCmd(ctx, lower, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dobot.SetEndEffectorParams(ctx, &godobot.EndEffectorParams{}, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dobot.SetArmOrientation(ctx, godobot.LeftyArmOrientation, true); err != nil {
+		t.Fatal(err)
+	}
+	// 从已入队的终点再下降 5mm 越过下限；若终点被丢弃则会从当前位姿检查而通过
+	step := &godobot.PTPCmd{PTPMode: godobot.PTPMOVLXYZINCMode, Z: -5}
+	if _, err := dobot.SetPTPCmd(ctx, step, true); !errors.Is(err, godobot.ErrOutOfWorkspace) {
+		t.Errorf("queued step below floor: error %v, want ErrOutOfWorkspace", err)
+	}
+}